@@ -1,13 +1,23 @@
 package main
 
 import (
+	"context"
 	"github.com/jmoiron/sqlx"
+	"go-application-task/configs"
+	"go-application-task/internal/disbursement"
 	"go-application-task/internal/middleware"
+	"go-application-task/internal/models"
+	"go-application-task/internal/notification"
+	"go-application-task/internal/otp"
+	"go-application-task/internal/outbox"
 	"go-application-task/internal/routes"
+	"go-application-task/internal/stream"
+	"go-application-task/internal/webhook"
 	"log"
 	"net/http"
 
 	"go-application-task/pkg/db"
+	"go-application-task/pkg/storage"
 )
 
 // Helper function to close a database connection with proper error handling
@@ -28,7 +38,48 @@ func main() {
 	defer closeDatabaseConnection(db.WriteDB, "WriteDB")
 	defer closeDatabaseConnection(db.ReadDB, "ReadDB")
 
-	router := routes.SetupRoutes()
+	// Background workers stop when the application exits
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Domain events are published from the outbox to in-process subscribers and the configured sinks
+	outboxConfig := configs.GetOutboxConfig()
+	bus := outbox.NewBus()
+	bus.Subscribe(webhook.Subscriber(db.WriteDB), models.WebhookEvents...)
+	bus.Subscribe(notification.Subscriber(db.WriteDB), notification.TriggerEvents...)
+	broker := stream.NewBroker(configs.GetStreamConfig().LogSize)
+	bus.Subscribe(broker.Handler(), models.EventOrderCreated, models.EventOrderCancelled, models.EventOrderStatusChanged)
+	sinks := []outbox.Sink{bus}
+	if outboxConfig.LogEvents {
+		sinks = append(sinks, outbox.LogSink{})
+	}
+	if outboxConfig.HTTPSinkURL != "" {
+		sinks = append(sinks, outbox.HTTPSink{URL: outboxConfig.HTTPSinkURL, Client: &http.Client{Timeout: outboxConfig.Timeout}})
+	}
+	go outbox.NewDispatcher(db.WriteDB, outboxConfig, sinks...).Run(ctx)
+
+	go webhook.NewDispatcher(db.WriteDB, configs.GetWebhookConfig()).Run(ctx)
+
+	notificationConfig := configs.GetNotificationConfig()
+	smsProvider, err := notification.NewSMSProvider(notificationConfig)
+	if err != nil {
+		log.Fatalf("Failed to initialize SMS provider: %v", err)
+	}
+	go notification.NewWorker(db.WriteDB, smsProvider, notificationConfig).Run(ctx)
+
+	payoutConfig := configs.GetPayoutConfig()
+	payoutProviders, err := disbursement.NewProviders(payoutConfig)
+	if err != nil {
+		log.Fatalf("Failed to initialize payout providers: %v", err)
+	}
+	go disbursement.NewWorker(db.WriteDB, payoutProviders, payoutConfig).Run(ctx)
+
+	blobs, err := storage.NewBlobStore(configs.GetStorageConfig())
+	if err != nil {
+		log.Fatalf("Failed to initialize blob storage: %v", err)
+	}
+
+	router := routes.SetupRoutes(broker, blobs, otp.SMSNotifier{SMS: smsProvider})
 	routerWithCors := middleware.EnableCors(router)
 
 	log.Println("Server running on port 8080")
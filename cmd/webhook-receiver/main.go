@@ -0,0 +1,52 @@
+// Command webhook-receiver is a local stand-in for a merchant webhook endpoint.
+// It verifies the signature of every delivery, logs the payload and can fail a share
+// of requests on purpose to exercise the retry and dead-letter handling.
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+
+	"go-application-task/internal/webhook"
+)
+
+func main() {
+	addr := flag.String("addr", ":9090", "address to listen on")
+	secret := flag.String("secret", os.Getenv("WEBHOOK_SECRET"), "endpoint signing secret returned by POST /webhooks")
+	failRate := flag.Float64("fail-rate", 0, "share of requests answered with 500, between 0 and 1")
+	flag.Parse()
+
+	http.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		payload, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read body", http.StatusBadRequest)
+			return
+		}
+
+		event := r.Header.Get(webhook.HeaderEvent)
+		deliveryID := r.Header.Get(webhook.HeaderDeliveryID)
+		signature := r.Header.Get(webhook.HeaderSignature)
+
+		if *secret != "" && !webhook.Verify(*secret, payload, signature) {
+			log.Printf("Delivery %s (%s): invalid signature %q", deliveryID, event, signature)
+			http.Error(w, "Invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		if rand.Float64() < *failRate {
+			log.Printf("Delivery %s (%s): failing on purpose", deliveryID, event)
+			http.Error(w, "Simulated failure", http.StatusInternalServerError)
+			return
+		}
+
+		log.Printf("Delivery %s (%s): %s", deliveryID, event, payload)
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	log.Printf("Webhook receiver listening on %s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, nil))
+}
@@ -1,7 +1,12 @@
 package configs
 
 import (
+	"encoding/base64"
+	"log"
 	"os"
+	"strconv"
+	"strings"
+	"time"
 )
 
 type DBConfig struct {
@@ -33,3 +38,321 @@ func GetReadDBConfig() DBConfig {
 		DBName:   os.Getenv("READ_DB_NAME"),
 	}
 }
+
+// SeedUser holds the credentials of a user created on boot
+type SeedUser struct {
+	Email    string
+	Password string
+}
+
+// GetSeedOpsUser returns the development ops account to create on boot.
+// Nothing is seeded unless both SEED_OPS_EMAIL and SEED_OPS_PASSWORD are set.
+func GetSeedOpsUser() SeedUser {
+	return SeedUser{
+		Email:    os.Getenv("SEED_OPS_EMAIL"),
+		Password: os.Getenv("SEED_OPS_PASSWORD"),
+	}
+}
+
+// GetTrackingBaseURL returns the public tracking page URL that consignment IDs are appended to
+func GetTrackingBaseURL() string {
+	baseURL := os.Getenv("TRACKING_BASE_URL")
+	if baseURL == "" {
+		baseURL = "http://localhost:8180/track"
+	}
+	return strings.TrimSuffix(baseURL, "/")
+}
+
+// getEnvInt reads an integer environment variable, falling back to def when it is unset or invalid
+func getEnvInt(name string, def int) int {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using default %d", name, value, def)
+		return def
+	}
+	return n
+}
+
+// getEnvFloat reads a decimal environment variable, falling back to def when it is unset or invalid
+func getEnvFloat(name string, def float64) float64 {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	n, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("Invalid %s %q, using default %v", name, value, def)
+		return def
+	}
+	return n
+}
+
+// WebhookConfig controls how webhook deliveries are attempted and retried
+type WebhookConfig struct {
+	MaxAttempts  int
+	BaseBackoff  time.Duration
+	PollInterval time.Duration
+	Timeout      time.Duration
+}
+
+// GetWebhookConfig returns the webhook delivery settings
+func GetWebhookConfig() WebhookConfig {
+	return WebhookConfig{
+		MaxAttempts:  getEnvInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		BaseBackoff:  time.Second * time.Duration(getEnvInt("WEBHOOK_BASE_BACKOFF_SECOND", 30)),
+		PollInterval: time.Second * time.Duration(getEnvInt("WEBHOOK_POLL_INTERVAL_SECOND", 5)),
+		Timeout:      time.Second * time.Duration(getEnvInt("WEBHOOK_TIMEOUT_SECOND", 10)),
+	}
+}
+
+// OutboxConfig controls how outbox events are published
+type OutboxConfig struct {
+	PollInterval time.Duration
+	LogEvents    bool
+	HTTPSinkURL  string
+	Timeout      time.Duration
+}
+
+// GetOutboxConfig returns the outbox dispatcher settings
+func GetOutboxConfig() OutboxConfig {
+	return OutboxConfig{
+		PollInterval: time.Second * time.Duration(getEnvInt("OUTBOX_POLL_INTERVAL_SECOND", 1)),
+		LogEvents:    os.Getenv("OUTBOX_LOG_EVENTS") == "true",
+		HTTPSinkURL:  os.Getenv("OUTBOX_HTTP_SINK_URL"),
+		Timeout:      time.Second * time.Duration(getEnvInt("OUTBOX_HTTP_TIMEOUT_SECOND", 10)),
+	}
+}
+
+// NotificationConfig selects the SMS driver and controls how notifications are retried
+type NotificationConfig struct {
+	SMSDriver    string
+	SMSFilePath  string
+	MaxAttempts  int
+	PollInterval time.Duration
+}
+
+// GetNotificationConfig returns the notification settings
+func GetNotificationConfig() NotificationConfig {
+	config := NotificationConfig{
+		SMSDriver:    os.Getenv("SMS_DRIVER"),
+		SMSFilePath:  os.Getenv("SMS_FILE_PATH"),
+		MaxAttempts:  getEnvInt("NOTIFICATION_MAX_ATTEMPTS", 3),
+		PollInterval: time.Second * time.Duration(getEnvInt("NOTIFICATION_POLL_INTERVAL_SECOND", 2)),
+	}
+	if config.SMSDriver == "" {
+		config.SMSDriver = "console"
+	}
+	if config.SMSFilePath == "" {
+		config.SMSFilePath = "sms.log"
+	}
+	return config
+}
+
+// StreamConfig controls the order event stream
+type StreamConfig struct {
+	LogSize           int
+	HeartbeatInterval time.Duration
+}
+
+// GetStreamConfig returns the order event stream settings
+func GetStreamConfig() StreamConfig {
+	return StreamConfig{
+		LogSize:           getEnvInt("STREAM_LOG_SIZE", 1000),
+		HeartbeatInterval: time.Second * time.Duration(getEnvInt("STREAM_HEARTBEAT_SECOND", 15)),
+	}
+}
+
+// GetReturnChargePercent returns the share of the delivery fee charged to the merchant for returning a parcel
+func GetReturnChargePercent() float64 {
+	return getEnvFloat("RETURN_CHARGE_PERCENT", 50)
+}
+
+// GetCancellationWindow returns how long after creation a merchant may still cancel an order, later only ops can.
+// A window of zero lets merchants cancel pending orders at any time.
+func GetCancellationWindow() time.Duration {
+	return time.Minute * time.Duration(getEnvInt("ORDER_CANCELLATION_WINDOW_MINUTE", 60))
+}
+
+// GetVATPercent returns the VAT rate included in the service charges billed to merchants.
+// INVOICE_VAT_PERCENT is still read when VAT_PERCENT is not set.
+func GetVATPercent() float64 {
+	return getEnvFloat("VAT_PERCENT", getEnvFloat("INVOICE_VAT_PERCENT", 15))
+}
+
+// PricingConfig controls how the delivery fee grows with parcel weight and size, and the VAT included in the fees
+type PricingConfig struct {
+	VolumetricDivisor float64
+	BaseWeight        float64
+	ExtraKgFee        float64
+	VATPercent        float64
+}
+
+// GetPricingConfig returns the weight based pricing settings.
+// VolumetricDivisor turns length x width x height in cm into kg.
+func GetPricingConfig() PricingConfig {
+	config := PricingConfig{
+		VolumetricDivisor: getEnvFloat("VOLUMETRIC_DIVISOR", 5000),
+		BaseWeight:        getEnvFloat("PRICING_BASE_WEIGHT_KG", 1),
+		ExtraKgFee:        getEnvFloat("PRICING_EXTRA_KG_FEE", 15),
+		VATPercent:        GetVATPercent(),
+	}
+	if config.VolumetricDivisor <= 0 {
+		config.VolumetricDivisor = 5000
+	}
+	return config
+}
+
+// PickupConfig controls when stores may ask for their parcels to be picked up.
+// Times of day are offsets from midnight in Location.
+type PickupConfig struct {
+	Location     *time.Location
+	OpensAt      time.Duration
+	ClosesAt     time.Duration
+	Cutoff       time.Duration
+	MinWindow    time.Duration
+	MaxDaysAhead int
+}
+
+// getEnvClock reads an HH:MM environment variable as an offset from midnight, falling back to def
+func getEnvClock(name, def string) time.Duration {
+	value := os.Getenv(name)
+	if value == "" {
+		value = def
+	}
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		log.Printf("Invalid %s %q, using default %s", name, value, def)
+		t, _ = time.Parse("15:04", def)
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+}
+
+// GetPickupConfig returns the store operating hours and pickup cut-off settings.
+// Same day pickups must be requested before the cut-off time.
+func GetPickupConfig() PickupConfig {
+	name := os.Getenv("PICKUP_TIMEZONE")
+	if name == "" {
+		name = "Asia/Dhaka"
+	}
+	location, err := time.LoadLocation(name)
+	if err != nil {
+		log.Printf("Invalid PICKUP_TIMEZONE %q, using UTC+6", name)
+		location = time.FixedZone("UTC+6", 6*60*60)
+	}
+	return PickupConfig{
+		Location:     location,
+		OpensAt:      getEnvClock("PICKUP_OPENS_AT", "09:00"),
+		ClosesAt:     getEnvClock("PICKUP_CLOSES_AT", "20:00"),
+		Cutoff:       getEnvClock("PICKUP_CUTOFF", "14:00"),
+		MinWindow:    time.Minute * time.Duration(getEnvInt("PICKUP_MIN_WINDOW_MINUTE", 60)),
+		MaxDaysAhead: getEnvInt("PICKUP_MAX_DAYS_AHEAD", 7),
+	}
+}
+
+// StorageConfig selects where uploaded files such as proof of delivery images are kept
+type StorageConfig struct {
+	Driver    string
+	LocalPath string
+}
+
+// GetStorageConfig returns the blob storage settings
+func GetStorageConfig() StorageConfig {
+	config := StorageConfig{
+		Driver:    os.Getenv("STORAGE_DRIVER"),
+		LocalPath: os.Getenv("STORAGE_LOCAL_PATH"),
+	}
+	if config.Driver == "" {
+		config.Driver = "local"
+	}
+	if config.LocalPath == "" {
+		config.LocalPath = "storage"
+	}
+	return config
+}
+
+// DeliveryOTPConfig controls when a one-time code is required on delivery and how wrong codes are limited
+type DeliveryOTPConfig struct {
+	Threshold   float64
+	Length      int
+	MaxAttempts int
+	Lockout     time.Duration
+}
+
+// GetDeliveryOTPConfig returns the delivery OTP settings.
+// Parcels with an amount to collect above Threshold always need a code.
+func GetDeliveryOTPConfig() DeliveryOTPConfig {
+	config := DeliveryOTPConfig{
+		Threshold:   getEnvFloat("DELIVERY_OTP_THRESHOLD", 5000),
+		Length:      getEnvInt("DELIVERY_OTP_LENGTH", 6),
+		MaxAttempts: getEnvInt("DELIVERY_OTP_MAX_ATTEMPTS", 5),
+		Lockout:     time.Minute * time.Duration(getEnvInt("DELIVERY_OTP_LOCKOUT_MINUTE", 15)),
+	}
+	if config.Length < 4 || config.Length > 10 {
+		config.Length = 6
+	}
+	return config
+}
+
+// InvoiceConfig holds the issuer details and VAT rate printed on merchant invoices
+type InvoiceConfig struct {
+	CompanyName     string
+	CompanyAddress  string
+	VATRegistration string
+	VATPercent      float64
+}
+
+// GetInvoiceConfig returns the invoice settings. Fees are VAT inclusive at VATPercent, see GetVATPercent.
+func GetInvoiceConfig() InvoiceConfig {
+	config := InvoiceConfig{
+		CompanyName:     os.Getenv("INVOICE_COMPANY_NAME"),
+		CompanyAddress:  os.Getenv("INVOICE_COMPANY_ADDRESS"),
+		VATRegistration: os.Getenv("INVOICE_VAT_REGISTRATION"),
+		VATPercent:      GetVATPercent(),
+	}
+	if config.CompanyName == "" {
+		config.CompanyName = "Courier Service"
+	}
+	return config
+}
+
+// PayoutConfig selects the payout provider drivers and controls how payouts are retried.
+// EncryptionKey is nil when PAYOUT_ENCRYPTION_KEY is not a base64 encoded 32 byte key.
+type PayoutConfig struct {
+	BankDriver     string
+	WalletDriver   string
+	SimulatorAsync bool
+	EncryptionKey  []byte
+	CallbackSecret string
+	MaxAttempts    int
+	BaseBackoff    time.Duration
+	PollInterval   time.Duration
+	Timeout        time.Duration
+}
+
+// GetPayoutConfig returns the payout disbursement settings
+func GetPayoutConfig() PayoutConfig {
+	config := PayoutConfig{
+		BankDriver:     os.Getenv("PAYOUT_BANK_DRIVER"),
+		WalletDriver:   os.Getenv("PAYOUT_WALLET_DRIVER"),
+		SimulatorAsync: os.Getenv("PAYOUT_SIMULATOR_ASYNC") == "true",
+		CallbackSecret: os.Getenv("PAYOUT_CALLBACK_SECRET"),
+		MaxAttempts:    getEnvInt("PAYOUT_MAX_ATTEMPTS", 5),
+		BaseBackoff:    time.Second * time.Duration(getEnvInt("PAYOUT_BASE_BACKOFF_SECOND", 60)),
+		PollInterval:   time.Second * time.Duration(getEnvInt("PAYOUT_POLL_INTERVAL_SECOND", 10)),
+		Timeout:        time.Second * time.Duration(getEnvInt("PAYOUT_TIMEOUT_SECOND", 30)),
+	}
+	if config.BankDriver == "" {
+		config.BankDriver = "simulator"
+	}
+	if config.WalletDriver == "" {
+		config.WalletDriver = "simulator"
+	}
+	if key, err := base64.StdEncoding.DecodeString(os.Getenv("PAYOUT_ENCRYPTION_KEY")); err == nil && len(key) == 32 {
+		config.EncryptionKey = key
+	}
+	return config
+}
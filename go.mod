@@ -10,4 +10,8 @@ require (
 	golang.org/x/crypto v0.29.0
 )
 
-require github.com/rs/cors v1.11.1
+require (
+	github.com/boombuler/barcode v1.1.0
+	github.com/go-pdf/fpdf v0.9.0
+	github.com/rs/cors v1.11.1
+)
@@ -0,0 +1,88 @@
+package disbursement
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"go-application-task/internal/ledger"
+	"go-application-task/internal/models"
+)
+
+// referencePrefix starts the reference every payout is sent to providers under
+const referencePrefix = "PO"
+
+// Reference returns the reference a payout is sent to providers under
+func Reference(payoutID int) string {
+	return fmt.Sprintf("%s%08d", referencePrefix, payoutID)
+}
+
+// ParseReference returns the payout ID of a reference made by Reference
+func ParseReference(reference string) (int, bool) {
+	if !strings.HasPrefix(reference, referencePrefix) {
+		return 0, false
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(reference, referencePrefix))
+	return id, err == nil && id > 0
+}
+
+// RecordAttempt logs an attempt to send a payout or a status update received for it
+func RecordAttempt(tx *sqlx.Tx, payoutID, attempt int, status, providerReference, errMessage string) error {
+	_, err := tx.Exec(`
+		INSERT INTO payout_attempts (payout_id, attempt, status, provider_reference, error)
+		VALUES ($1, $2, $3, NULLIF($4, ''), NULLIF($5, ''))
+	`, payoutID, attempt, status, providerReference, errMessage)
+	return err
+}
+
+// Succeed marks a payout paid and moves its amount out of payouts_payable. The payout must be locked by tx.
+func Succeed(tx *sqlx.Tx, payout *models.Payout, providerReference string) error {
+	err := tx.Get(payout, `
+		UPDATE payouts
+		SET status = $2, provider_reference = COALESCE(NULLIF($3, ''), provider_reference), last_error = NULL, paid_at = NOW(), updated_at = NOW()
+		WHERE id = $1
+		RETURNING *
+	`, payout.ID, models.PayoutStatusSucceeded, providerReference)
+	if err != nil {
+		return err
+	}
+	return ledger.Post(tx, ledger.Posting{
+		EntryType: models.EntryPayoutPaid,
+		UserID:    payout.UserID,
+		PayoutID:  &payout.ID,
+		Debit:     models.AccountPayoutsPayable,
+		Credit:    models.AccountCash,
+		Amount:    payout.Amount,
+	})
+}
+
+// Hold parks a payout whose outcome is unknown for ops to review. Its amount stays in payouts_payable,
+// so it is not paid again until it is known to have failed. The payout must be locked by tx.
+func Hold(tx *sqlx.Tx, payout *models.Payout, reason string) error {
+	return tx.Get(payout, `
+		UPDATE payouts SET status = $2, last_error = $3, updated_at = NOW()
+		WHERE id = $1
+		RETURNING *
+	`, payout.ID, models.PayoutStatusNeedsReview, reason)
+}
+
+// Fail marks a payout failed and gives its amount back to the merchant's balance, so the next payout run
+// includes it again. The payout must be locked by tx.
+func Fail(tx *sqlx.Tx, payout *models.Payout, reason string) error {
+	err := tx.Get(payout, `
+		UPDATE payouts SET status = $2, last_error = $3, updated_at = NOW()
+		WHERE id = $1
+		RETURNING *
+	`, payout.ID, models.PayoutStatusFailed, reason)
+	if err != nil {
+		return err
+	}
+	return ledger.Post(tx, ledger.Posting{
+		EntryType: models.EntryPayoutReversal,
+		UserID:    payout.UserID,
+		Debit:     models.AccountPayoutsPayable,
+		Credit:    models.AccountMerchantPayable,
+		Amount:    payout.Amount,
+	})
+}
@@ -0,0 +1,99 @@
+package disbursement
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+
+	"go-application-task/configs"
+	"go-application-task/internal/models"
+)
+
+// Transfer is a payout to send to a merchant's account.
+// Reference is unique per payout so a provider can recognise a retried transfer.
+type Transfer struct {
+	Reference      string
+	Method         string
+	Amount         float64
+	AccountName    string
+	AccountNumber  string
+	BankName       string
+	BranchName     string
+	RoutingNumber  string
+	WalletProvider string
+}
+
+// Result is what a provider reports after accepting a transfer.
+// Status is initiated while the provider is still processing it, the outcome then arrives through the callback.
+type Result struct {
+	Status            string
+	ProviderReference string
+	Error             string
+}
+
+// PayoutProvider sends money to merchant accounts.
+// An error means the transfer may or may not have reached the provider and is retried with the same reference,
+// until the payout runs out of attempts and is held for review; a Result with status failed is a final decline.
+type PayoutProvider interface {
+	Disburse(ctx context.Context, t Transfer) (Result, error)
+}
+
+// NewProviders returns the provider for each payout method as selected in config
+func NewProviders(config configs.PayoutConfig) (map[string]PayoutProvider, error) {
+	simulator := NewSimulator(config.SimulatorAsync)
+	drivers := map[string]string{
+		models.PayoutMethodBankTransfer: config.BankDriver,
+		models.PayoutMethodMobileWallet: config.WalletDriver,
+	}
+	providers := make(map[string]PayoutProvider, len(drivers))
+	for method, driver := range drivers {
+		switch driver {
+		case "simulator":
+			providers[method] = simulator
+		default:
+			return nil, fmt.Errorf("unknown %s payout driver %q", method, driver)
+		}
+	}
+	return providers, nil
+}
+
+// Simulator is a stand-in provider that logs transfers instead of moving money.
+// Accounts ending in 0000 are declined and accounts ending in 9999 fail with a temporary error, to exercise failures and retries.
+// In async mode transfers stay initiated until the outcome is posted to the callback endpoint.
+type Simulator struct {
+	async bool
+	mu    sync.Mutex
+	seen  map[string]Result
+}
+
+// NewSimulator creates a simulator provider
+func NewSimulator(async bool) *Simulator {
+	return &Simulator{async: async, seen: make(map[string]Result)}
+}
+
+func (s *Simulator) Disburse(ctx context.Context, t Transfer) (Result, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// A retried reference gets the original outcome, like a real provider's idempotency
+	if result, ok := s.seen[t.Reference]; ok {
+		return result, nil
+	}
+	if strings.HasSuffix(t.AccountNumber, "9999") {
+		return Result{}, fmt.Errorf("simulated provider timeout for %s", t.Reference)
+	}
+
+	result := Result{Status: models.PayoutStatusSucceeded, ProviderReference: fmt.Sprintf("sim-%d", time.Now().UnixNano())}
+	switch {
+	case strings.HasSuffix(t.AccountNumber, "0000"):
+		result.Status, result.Error = models.PayoutStatusFailed, "account closed"
+	case s.async:
+		result.Status = models.PayoutStatusInitiated
+	}
+	s.seen[t.Reference] = result
+	log.Printf("Simulated %s payout %s of %.2f to %s: %s", t.Method, t.Reference, t.Amount, t.AccountName, result.Status)
+	return result, nil
+}
@@ -0,0 +1,213 @@
+package disbursement
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go-application-task/configs"
+	"go-application-task/internal/models"
+	"go-application-task/pkg/secret"
+)
+
+// batchSize is how many due payouts are claimed per poll
+const batchSize = 20
+
+// leaseDuration keeps a claimed payout from being picked up again while it is being sent
+const leaseDuration = 5 * time.Minute
+
+// maxBackoff caps the delay between attempts
+const maxBackoff = time.Hour
+
+// Worker sends pending payouts through the provider for each merchant's payout method and retries failures
+type Worker struct {
+	db        *sqlx.DB
+	providers map[string]PayoutProvider
+	config    configs.PayoutConfig
+}
+
+// NewWorker creates a worker sending payouts from db through providers
+func NewWorker(db *sqlx.DB, providers map[string]PayoutProvider, config configs.PayoutConfig) *Worker {
+	return &Worker{db: db, providers: providers, config: config}
+}
+
+// Run polls for due payouts until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.config.PollInterval)
+	defer ticker.Stop()
+
+	log.Println("Payout worker started")
+	for {
+		for {
+			n, err := w.disburseDue(ctx)
+			if err != nil {
+				log.Printf("Payout worker error: %v", err)
+			}
+			// Keep draining while full batches are being claimed
+			if err != nil || n < batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Payout worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// duePayout is a claimed payout with the merchant's payout method
+type duePayout struct {
+	models.Payout
+	PayoutMethod models.PayoutMethod `db:"m"`
+}
+
+// disburseDue claims a batch of due payouts of merchants with a payout method, sends them and returns how many were claimed
+func (w *Worker) disburseDue(ctx context.Context) (int, error) {
+	var payouts []duePayout
+	err := w.db.SelectContext(ctx, &payouts, `
+		WITH due AS (
+			SELECT p.id FROM payouts p
+			WHERE p.status = $1 AND p.next_attempt_at <= NOW()
+			  AND EXISTS (SELECT 1 FROM payout_methods m WHERE m.user_id = p.user_id)
+			ORDER BY p.id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		), claimed AS (
+			UPDATE payouts p
+			SET next_attempt_at = NOW() + $3 * INTERVAL '1 second'
+			FROM due
+			WHERE p.id = due.id
+			RETURNING p.*
+		)
+		SELECT claimed.*,
+		       m.user_id AS "m.user_id", m.method AS "m.method", m.account_name AS "m.account_name",
+		       m.account_number_encrypted AS "m.account_number_encrypted", m.account_number_last4 AS "m.account_number_last4",
+		       m.bank_name AS "m.bank_name", m.branch_name AS "m.branch_name", m.routing_number AS "m.routing_number",
+		       m.wallet_provider AS "m.wallet_provider", m.updated_at AS "m.updated_at"
+		FROM claimed
+		JOIN payout_methods m ON m.user_id = claimed.user_id
+		ORDER BY claimed.id
+	`, models.PayoutStatusPending, batchSize, int(leaseDuration.Seconds()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim payouts: %w", err)
+	}
+
+	for _, payout := range payouts {
+		w.disburse(ctx, payout)
+	}
+	return len(payouts), nil
+}
+
+// disburse sends a single payout and records the outcome
+func (w *Worker) disburse(ctx context.Context, due duePayout) {
+	method := due.PayoutMethod
+	var result Result
+	accountNumber, err := secret.Open(w.config.EncryptionKey, method.AccountNumberEncrypted)
+	provider, ok := w.providers[method.Method]
+	if err == nil && !ok {
+		err = fmt.Errorf("no provider for payout method %s", method.Method)
+	}
+	if err == nil {
+		transfer := Transfer{
+			Reference:     Reference(due.ID),
+			Method:        method.Method,
+			Amount:        due.Amount,
+			AccountName:   method.AccountName,
+			AccountNumber: accountNumber,
+		}
+		if method.BankName != nil {
+			transfer.BankName = *method.BankName
+		}
+		if method.BranchName != nil {
+			transfer.BranchName = *method.BranchName
+		}
+		if method.RoutingNumber != nil {
+			transfer.RoutingNumber = *method.RoutingNumber
+		}
+		if method.WalletProvider != nil {
+			transfer.WalletProvider = *method.WalletProvider
+		}
+
+		sendCtx, cancel := context.WithTimeout(ctx, w.config.Timeout)
+		result, err = provider.Disburse(sendCtx, transfer)
+		cancel()
+	}
+
+	if err := w.record(due.ID, method.Method, result, err); err != nil {
+		log.Printf("Failed to record payout %d: %v", due.ID, err)
+	}
+}
+
+// record stores the outcome of sending a payout. A status update that arrived through the callback
+// while the payout was being sent takes precedence.
+func (w *Worker) record(payoutID int, method string, result Result, sendErr error) error {
+	tx, err := w.db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	var payout models.Payout
+	if err := tx.Get(&payout, `SELECT * FROM payouts WHERE id = $1 FOR UPDATE`, payoutID); err != nil {
+		return err
+	}
+	attempt := payout.Attempts + 1
+
+	status, errMessage := result.Status, result.Error
+	if sendErr != nil {
+		status, errMessage = models.PayoutStatusPending, sendErr.Error()
+		log.Printf("Failed to send payout %d (attempt %d): %v", payoutID, attempt, sendErr)
+	}
+	if err := RecordAttempt(tx, payoutID, attempt, status, result.ProviderReference, errMessage); err != nil {
+		return err
+	}
+	if payout.Status != models.PayoutStatusPending {
+		return tx.Commit()
+	}
+
+	_, err = tx.Exec(`
+		UPDATE payouts SET attempts = $2, method = $3, provider_reference = NULLIF($4, ''), updated_at = NOW()
+		WHERE id = $1
+	`, payoutID, attempt, method, result.ProviderReference)
+	if err != nil {
+		return err
+	}
+	payout.Attempts = attempt
+
+	switch {
+	case sendErr != nil && attempt >= w.config.MaxAttempts:
+		// The transfer may have reached the provider, so it is not reversed until someone checks
+		log.Printf("Payout %d needs review after %d attempts", payoutID, attempt)
+		err = Hold(tx, &payout, errMessage)
+	case sendErr != nil:
+		_, err = tx.Exec(`UPDATE payouts SET last_error = $2, next_attempt_at = $3 WHERE id = $1`, payoutID, errMessage, time.Now().Add(w.backoff(attempt)))
+	case status == models.PayoutStatusSucceeded:
+		err = Succeed(tx, &payout, result.ProviderReference)
+	case status == models.PayoutStatusFailed:
+		log.Printf("Payout %d declined by provider: %s", payoutID, errMessage)
+		err = Fail(tx, &payout, errMessage)
+	default:
+		_, err = tx.Exec(`UPDATE payouts SET status = $2 WHERE id = $1`, payoutID, models.PayoutStatusInitiated)
+	}
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// backoff returns the delay before the next attempt, doubling with every failed attempt
+func (w *Worker) backoff(attempt int) time.Duration {
+	delay := w.config.BaseBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
@@ -1,70 +1,277 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"github.com/gorilla/mux"
 	"github.com/jmoiron/sqlx"
+	"go-application-task/configs"
 	"go-application-task/internal/models"
+	"go-application-task/internal/promotion"
+	"go-application-task/pkg/utils"
+	"io"
 	"log"
 	"net/http"
-	"os"
+	"strings"
 )
 
-// CancelOrderHandler handles the cancellation of an order
+// maxBulkCancel caps how many orders can be cancelled in one request
+const maxBulkCancel = 100
+
+// maxCancellationNoteLength caps the free text given with a cancellation
+const maxCancellationNoteLength = 1000
+
+// Reasons an order cannot be cancelled by the caller
+var (
+	errOrderCancelled           = errors.New("order already cancelled")
+	errCancelNotPending         = errors.New("order is no longer pending")
+	errCancellationWindowClosed = errors.New("cancellation window has passed")
+)
+
+// cancelRequest is the reason given for cancelling orders
+type cancelRequest struct {
+	ReasonCode string `json:"reason_code"`
+	Note       string `json:"note"`
+}
+
+// decodeCancelRequest reads the cancellation reason from the JSON body, or from the reason_code and note
+// query parameters when there is no body
+func decodeCancelRequest(r *http.Request) (cancelRequest, error) {
+	var req cancelRequest
+	err := json.NewDecoder(r.Body).Decode(&req)
+	if err == io.EOF {
+		req.ReasonCode, req.Note = r.URL.Query().Get("reason_code"), r.URL.Query().Get("note")
+		err = nil
+	}
+	req.ReasonCode, req.Note = strings.TrimSpace(req.ReasonCode), strings.TrimSpace(req.Note)
+	return req, err
+}
+
+// validate checks the reason code is one of the active cancellation reasons
+func (req *cancelRequest) validate(db sqlx.Queryer) (map[string][]string, error) {
+	errs := make(map[string][]string)
+	if req.ReasonCode == "" {
+		errs["reason_code"] = append(errs["reason_code"], "The reason code field is required.")
+	} else {
+		var active bool
+		err := sqlx.Get(db, &active, `SELECT EXISTS(SELECT 1 FROM cancellation_reasons WHERE code = $1 AND active)`, req.ReasonCode)
+		if err != nil {
+			return nil, err
+		}
+		if !active {
+			errs["reason_code"] = append(errs["reason_code"], "The selected reason code is invalid.")
+		}
+	}
+	if len(req.Note) > maxCancellationNoteLength {
+		errs["note"] = append(errs["note"], fmt.Sprintf("The note may not be greater than %d characters.", maxCancellationNoteLength))
+	}
+	return errs, nil
+}
+
+// cancelOrder cancels one order for user and records the reason.
+// Merchants may only cancel their own pending orders within the cancellation window, ops may cancel any order
+// the status rules allow. A promotion the order used is given back.
+// sql.ErrNoRows is returned when the order does not exist or is not the merchant's.
+func cancelOrder(db *sqlx.DB, consignmentID string, user models.User, req cancelRequest) (models.OrderCancellation, error) {
+	var cancellation models.OrderCancellation
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return cancellation, err
+	}
+	defer tx.Rollback()
+
+	var order struct {
+		models.Order
+		WithinWindow bool `db:"within_window"`
+	}
+	err = tx.Get(&order, `
+		SELECT `+orderColumns+`, ($3 <= 0 OR created_at > NOW() - $3 * INTERVAL '1 second') AS within_window
+		FROM orders
+		WHERE consignment_id = $1 AND ($2 = 0 OR user_id = $2)
+		FOR UPDATE
+	`, consignmentID, ownerScope(user), int(configs.GetCancellationWindow().Seconds()))
+	if err != nil {
+		return cancellation, err
+	}
+
+	if order.OrderStatus == models.OrderStatusCancelled {
+		return cancellation, errOrderCancelled
+	}
+	if user.Role != models.RoleOps {
+		if order.OrderStatus != models.OrderStatusPending {
+			return cancellation, errCancelNotPending
+		}
+		if !order.WithinWindow {
+			return cancellation, errCancellationWindowClosed
+		}
+	}
+
+	if err := changeOrderStatus(tx, &order.Order, models.OrderStatusCancelled); err != nil {
+		return cancellation, err
+	}
+	if order.PromotionID != nil {
+		if err := promotion.Release(tx, consignmentID); err != nil {
+			return cancellation, err
+		}
+	}
+
+	var note *string
+	if req.Note != "" {
+		note = &req.Note
+	}
+	err = tx.Get(&cancellation, `
+		INSERT INTO order_cancellations (consignment_id, reason_code, note, cancelled_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING *
+	`, consignmentID, req.ReasonCode, note, user.ID)
+	if err == nil {
+		err = tx.Commit()
+	}
+	return cancellation, err
+}
+
+// cancelFailure turns an error from cancelOrder into the status code and message reported to the caller
+func cancelFailure(consignmentID string, err error) (int, string) {
+	var invalid errInvalidTransition
+	switch {
+	case err == sql.ErrNoRows:
+		return http.StatusNotFound, "Order not found"
+	case err == errOrderCancelled:
+		return http.StatusConflict, "Order already cancelled"
+	case err == errCancelNotPending:
+		return http.StatusConflict, "Please contact cx to cancel order"
+	case err == errCancellationWindowClosed:
+		return http.StatusConflict, "The cancellation window has passed, please contact cx to cancel order"
+	case errors.As(err, &invalid):
+		return http.StatusConflict, fmt.Sprintf("Order in status %s cannot be cancelled", invalid.From)
+	}
+	log.Printf("Failed to cancel order %s: %v", consignmentID, err)
+	return http.StatusInternalServerError, "Failed to cancel order"
+}
+
+// CancelOrderHandler cancels an order with a reason code from the managed list and an optional note.
+// The order is taken from the path on DELETE /orders/{consignment_id}, or from the consignment_id query
+// parameter on the older POST /cancel-order.
 func CancelOrderHandler(db *sqlx.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		// Extract consignment_id from query parameters
-		consignmentID := r.URL.Query().Get("consignment_id")
+		consignmentID := mux.Vars(r)["consignment_id"]
+		if consignmentID == "" {
+			consignmentID = r.URL.Query().Get("consignment_id")
+		}
 		if consignmentID == "" {
 			http.Error(w, "Consignment ID is required", http.StatusBadRequest)
 			return
 		}
+		if !utils.ValidateConsignmentID(consignmentID) {
+			http.Error(w, "Invalid consignment ID", http.StatusBadRequest)
+			return
+		}
+
+		req, err := decodeCancelRequest(r)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		user, ok := requireRole(w, r, db, models.RoleMerchant, models.RoleOps)
+		if !ok {
+			return
+		}
 
-		// Extract userID from token
-		userID, err := GetUserIDFromToken(r, os.Getenv("JWT_SECRET"), db)
+		errs, err := req.validate(db)
 		if err != nil {
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			log.Printf("Cancellation reason retrieval error: %v", err)
+			http.Error(w, "Failed to cancel order", http.StatusInternalServerError)
+			return
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
 			return
 		}
 
-		// Check if the order exists and retrieve it
-		var order models.Order
-		query := `SELECT order_status, user_id FROM orders WHERE consignment_id = $1 AND user_id = $2`
-		err = db.Get(&order, query, consignmentID, userID)
+		cancellation, err := cancelOrder(db, consignmentID, user, req)
 		if err != nil {
-			log.Printf("Order retrieval error: %v", err)
-			http.Error(w, "Failed to fetch order", http.StatusInternalServerError)
+			code, message := cancelFailure(consignmentID, err)
+			http.Error(w, message, code)
 			return
 		}
 
-		// Check if order status is not "Cancelled"
-		if order.OrderStatus == "cancelled" {
-			http.Error(w, "Order already cancelled", http.StatusConflict)
+		writeResponse(w, http.StatusOK, fmt.Sprintf("Order with consignment ID %s successfully cancelled.", consignmentID), cancellation)
+	}
+}
+
+// cancelOutcome is the result of cancelling one order in a bulk cancel
+type cancelOutcome struct {
+	ConsignmentID string                    `json:"consignment_id"`
+	Cancelled     bool                      `json:"cancelled"`
+	Code          int                       `json:"code"`
+	Error         string                    `json:"error,omitempty"`
+	Cancellation  *models.OrderCancellation `json:"cancellation,omitempty"`
+}
+
+// BulkCancelOrdersHandler cancels up to 100 orders with the same reason.
+// Each order is cancelled on its own, so one that cannot be cancelled does not hold back the others;
+// the outcome of each is reported in request order.
+func BulkCancelOrdersHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ConsignmentIDs []string `json:"consignment_ids"`
+			cancelRequest
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
 			return
 		}
+		req.ReasonCode, req.Note = strings.TrimSpace(req.ReasonCode), strings.TrimSpace(req.Note)
 
-		// Check if order status is "Completed"
-		if order.OrderStatus != "pending" {
-			http.Error(w, "Please contact cx to cancel order", http.StatusConflict)
+		user, ok := requireRole(w, r, db, models.RoleMerchant, models.RoleOps)
+		if !ok {
 			return
 		}
 
-		// Update the order status to "Cancelled"
-		updateQuery := `UPDATE orders SET order_status = 'cancelled' WHERE consignment_id = $1 AND user_id = $2`
-		_, err = db.Exec(updateQuery, consignmentID, userID)
+		errs, err := req.validate(db)
 		if err != nil {
-			log.Printf("Failed to update order status: %v", err)
-			http.Error(w, "Failed to cancel order", http.StatusInternalServerError)
+			log.Printf("Cancellation reason retrieval error: %v", err)
+			http.Error(w, "Failed to cancel orders", http.StatusInternalServerError)
+			return
+		}
+		if len(req.ConsignmentIDs) == 0 {
+			errs["consignment_ids"] = append(errs["consignment_ids"], "At least one consignment ID is required.")
+		} else if len(req.ConsignmentIDs) > maxBulkCancel {
+			errs["consignment_ids"] = append(errs["consignment_ids"], fmt.Sprintf("At most %d orders can be cancelled at once.", maxBulkCancel))
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
 			return
 		}
 
-		// Send success response
-		response := Response{
-			Message: fmt.Sprintf("Order with consignment ID %s successfully cancelled.", consignmentID),
-			Type:    "success",
-			Code:    200,
+		outcomes := make([]cancelOutcome, 0, len(req.ConsignmentIDs))
+		cancelled := 0
+		for _, consignmentID := range req.ConsignmentIDs {
+			outcome := cancelOutcome{ConsignmentID: consignmentID}
+			if !utils.ValidateConsignmentID(consignmentID) {
+				outcome.Code, outcome.Error = http.StatusBadRequest, "Invalid consignment ID"
+				outcomes = append(outcomes, outcome)
+				continue
+			}
+
+			cancellation, err := cancelOrder(db, consignmentID, user, req.cancelRequest)
+			if err != nil {
+				outcome.Code, outcome.Error = cancelFailure(consignmentID, err)
+			} else {
+				outcome.Cancelled, outcome.Code, outcome.Cancellation = true, http.StatusOK, &cancellation
+				cancelled++
+			}
+			outcomes = append(outcomes, outcome)
 		}
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(response)
+
+		writeResponse(w, http.StatusOK, fmt.Sprintf("%d of %d orders cancelled.", cancelled, len(outcomes)), struct {
+			Cancelled int             `json:"cancelled"`
+			Failed    int             `json:"failed"`
+			Results   []cancelOutcome `json:"results"`
+		}{cancelled, len(outcomes) - cancelled, outcomes})
 	}
 }
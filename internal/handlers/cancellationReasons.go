@@ -0,0 +1,133 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"go-application-task/internal/models"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// reasonCodePattern is the format of cancellation reason codes
+var reasonCodePattern = regexp.MustCompile(`^[a-z][a-z0-9_]{1,49}$`)
+
+// ListCancellationReasonsHandler lists the reasons orders may be cancelled for.
+// Merchants get the active reasons, ops get all of them unless active=true.
+func ListCancellationReasonsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := requireRole(w, r, db, models.RoleMerchant, models.RoleOps)
+		if !ok {
+			return
+		}
+		activeOnly := user.Role != models.RoleOps || r.URL.Query().Get("active") == "true"
+
+		reasons := []models.CancellationReason{}
+		err := db.Select(&reasons, `SELECT * FROM cancellation_reasons WHERE NOT $1 OR active ORDER BY id`, activeOnly)
+		if err != nil {
+			log.Printf("Failed to fetch cancellation reasons: %v", err)
+			http.Error(w, "Failed to fetch cancellation reasons", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Cancellation reasons successfully fetched.", reasons)
+	}
+}
+
+// CreateCancellationReasonHandler adds a reason orders may be cancelled for (ops only)
+func CreateCancellationReasonHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Code  string `json:"code"`
+			Label string `json:"label"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := requireRole(w, r, db, models.RoleOps); !ok {
+			return
+		}
+
+		errs := make(map[string][]string)
+		if !reasonCodePattern.MatchString(req.Code) {
+			errs["code"] = append(errs["code"], "The code must be 2 to 50 lowercase letters, digits or underscores, starting with a letter.")
+		}
+		req.Label = strings.TrimSpace(req.Label)
+		if req.Label == "" {
+			errs["label"] = append(errs["label"], "The label field is required.")
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+
+		var reason models.CancellationReason
+		err := db.Get(&reason, `INSERT INTO cancellation_reasons (code, label) VALUES ($1, $2) RETURNING *`, req.Code, req.Label)
+		if isUniqueViolation(err, "cancellation_reasons_code_key") {
+			writeValidationErrors(w, map[string][]string{"code": {"The code has already been taken."}})
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to create cancellation reason: %v", err)
+			http.Error(w, "Failed to create cancellation reason", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusCreated, "Cancellation reason created successfully.", reason)
+	}
+}
+
+// UpdateCancellationReasonHandler renames a cancellation reason or switches it on or off (ops only).
+// The code is kept so past cancellations still point at it.
+func UpdateCancellationReasonHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reasonID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+		var req struct {
+			Label  *string `json:"label"`
+			Active *bool   `json:"active"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := requireRole(w, r, db, models.RoleOps); !ok {
+			return
+		}
+
+		if req.Label != nil {
+			*req.Label = strings.TrimSpace(*req.Label)
+			if *req.Label == "" {
+				writeValidationErrors(w, map[string][]string{"label": {"The label field is required."}})
+				return
+			}
+		}
+
+		var reason models.CancellationReason
+		err := db.Get(&reason, `
+			UPDATE cancellation_reasons
+			SET label = COALESCE($2, label), active = COALESCE($3, active), updated_at = NOW()
+			WHERE id = $1
+			RETURNING *
+		`, reasonID, req.Label, req.Active)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Cancellation reason not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to update cancellation reason: %v", err)
+			http.Error(w, "Failed to update cancellation reason", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Cancellation reason updated successfully.", reason)
+	}
+}
@@ -0,0 +1,226 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"go-application-task/configs"
+	"go-application-task/internal/models"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// businessDay parses a YYYY-MM-DD business date in the operations time zone, defaulting to today.
+// It returns the date and the start and end of that day.
+func businessDay(date string) (string, time.Time, time.Time, error) {
+	location := configs.GetPickupConfig().Location
+	now := time.Now().In(location)
+	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+	if date != "" {
+		day, err := time.ParseInLocation("2006-01-02", date, location)
+		if err != nil {
+			return "", start, start, err
+		}
+		if day.After(start) {
+			return "", start, start, fmt.Errorf("%s is in the future", date)
+		}
+		start = day
+	}
+	return start.Format("2006-01-02"), start, start.AddDate(0, 0, 1), nil
+}
+
+// reconcileRiders compares each rider's deposits at a hub for a business day with the COD they collected that day.
+// It covers riders based at the hub and anyone else who deposited there, or only riderID when it is not 0.
+func reconcileRiders(db sqlx.Queryer, hub models.Hub, date string, start, end time.Time, riderID int) ([]models.CashReconciliation, error) {
+	reconciliations := []models.CashReconciliation{}
+	err := sqlx.Select(db, &reconciliations, `
+		SELECT r.id AS rider_id, r.name AS rider_name,
+		       COALESCE(c.cod_orders, 0) AS cod_orders,
+		       COALESCE(c.expected, 0) AS expected,
+		       COALESCE(d.deposited, 0) AS deposited,
+		       COALESCE(d.deposited, 0) - COALESCE(c.expected, 0) AS difference
+		FROM riders r
+		LEFT JOIN (
+			SELECT o.rider_id, COUNT(*) AS cod_orders, SUM(e.debit) AS expected
+			FROM ledger_transactions t
+			JOIN ledger_entries e ON e.transaction_id = t.id AND e.account = 'cod_receivable'
+			JOIN orders o ON o.consignment_id = t.consignment_id
+			WHERE t.entry_type = 'cod_collected' AND t.created_at >= $2 AND t.created_at < $3
+			GROUP BY o.rider_id
+		) c ON c.rider_id = r.id
+		LEFT JOIN (
+			SELECT rider_id, SUM(amount) AS deposited
+			FROM rider_cash_deposits
+			WHERE hub_id = $4 AND business_date = $5::date
+			GROUP BY rider_id
+		) d ON d.rider_id = r.id
+		WHERE ((r.home_hub = $1 AND (r.active OR c.rider_id IS NOT NULL)) OR d.rider_id IS NOT NULL)
+		  AND ($6 = 0 OR r.id = $6)
+		ORDER BY r.name, r.id
+	`, hub.Code, start.UTC(), end.UTC(), hub.ID, date, riderID)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range reconciliations {
+		reconciliation := &reconciliations[i]
+		reconciliation.Difference = math.Round(reconciliation.Difference*100) / 100
+		switch {
+		case reconciliation.Difference < 0:
+			reconciliation.Status = models.CashStatusShort
+		case reconciliation.Difference > 0:
+			reconciliation.Status = models.CashStatusOver
+		default:
+			reconciliation.Status = models.CashStatusBalanced
+		}
+	}
+	return reconciliations, nil
+}
+
+// CreateCashDepositHandler records cash a rider based at the hub handed over to the cashier (cashiers and ops only).
+// The response reconciles all of the rider's deposits for the day against the COD they collected.
+func CreateCashDepositHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hubID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+		var req struct {
+			RiderID      int     `json:"rider_id"`
+			Amount       float64 `json:"amount"`
+			BusinessDate string  `json:"business_date"`
+			Note         *string `json:"note"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		user, ok := requireRole(w, r, db, models.RoleCashier, models.RoleOps)
+		if !ok {
+			return
+		}
+
+		hub, err := getHub(db, hubID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Hub not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Hub retrieval error: %v", err)
+			http.Error(w, "Failed to fetch hub", http.StatusInternalServerError)
+			return
+		}
+
+		errs := make(map[string][]string)
+		date, start, end, err := businessDay(req.BusinessDate)
+		if err != nil {
+			errs["business_date"] = append(errs["business_date"], "The business date must be a past or current date in YYYY-MM-DD format.")
+		}
+		if req.Amount <= 0 {
+			errs["amount"] = append(errs["amount"], "The amount must be greater than 0.")
+		}
+		if req.RiderID == 0 {
+			errs["rider_id"] = append(errs["rider_id"], "The rider field is required.")
+		} else if rider, err := getRider(db, req.RiderID); err == sql.ErrNoRows {
+			errs["rider_id"] = append(errs["rider_id"], "The rider was not found.")
+		} else if err != nil {
+			log.Printf("Rider retrieval error: %v", err)
+			http.Error(w, "Failed to fetch rider", http.StatusInternalServerError)
+			return
+		} else if rider.HomeHub != hub.Code {
+			errs["rider_id"] = append(errs["rider_id"], fmt.Sprintf("The rider is based at %s, not %s.", rider.HomeHub, hub.Code))
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+
+		var deposit models.CashDeposit
+		err = db.Get(&deposit, `
+			INSERT INTO rider_cash_deposits (rider_id, hub_id, business_date, amount, note, recorded_by)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id, rider_id, hub_id, to_char(business_date, 'YYYY-MM-DD') AS business_date, amount, note, recorded_by, created_at
+		`, req.RiderID, hub.ID, date, math.Round(req.Amount*100)/100, req.Note, user.ID)
+		if err != nil {
+			log.Printf("Failed to record cash deposit: %v", err)
+			http.Error(w, "Failed to record cash deposit", http.StatusInternalServerError)
+			return
+		}
+
+		var reconciliation *models.CashReconciliation
+		reconciliations, err := reconcileRiders(db, hub, date, start, end, req.RiderID)
+		if err != nil {
+			log.Printf("Failed to reconcile rider cash: %v", err)
+			http.Error(w, "Failed to reconcile rider cash", http.StatusInternalServerError)
+			return
+		}
+		if len(reconciliations) > 0 {
+			reconciliation = &reconciliations[0]
+			if reconciliation.Status != models.CashStatusBalanced {
+				log.Printf("Rider %d is %s by %.2f at %s on %s", req.RiderID, reconciliation.Status, math.Abs(reconciliation.Difference), hub.Code, date)
+			}
+		}
+
+		writeResponse(w, http.StatusCreated, "Cash deposit recorded successfully.", map[string]interface{}{
+			"deposit":        deposit,
+			"reconciliation": reconciliation,
+		})
+	}
+}
+
+// CashReconciliationHandler reports each rider's deposits against the COD they collected for a hub and business date,
+// given as the date query parameter and defaulting to today (cashiers and ops only)
+func CashReconciliationHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hubID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+		if _, ok := requireRole(w, r, db, models.RoleCashier, models.RoleOps); !ok {
+			return
+		}
+
+		date, start, end, err := businessDay(r.URL.Query().Get("date"))
+		if err != nil {
+			http.Error(w, "invalid date, expected a past or current YYYY-MM-DD", http.StatusBadRequest)
+			return
+		}
+
+		hub, err := getHub(db, hubID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Hub not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Hub retrieval error: %v", err)
+			http.Error(w, "Failed to fetch hub", http.StatusInternalServerError)
+			return
+		}
+
+		reconciliations, err := reconcileRiders(db, hub, date, start, end, 0)
+		if err != nil {
+			log.Printf("Failed to reconcile rider cash: %v", err)
+			http.Error(w, "Failed to reconcile rider cash", http.StatusInternalServerError)
+			return
+		}
+
+		report := models.CashReconciliationReport{Hub: hub, BusinessDate: date, Reconciliations: reconciliations}
+		for _, reconciliation := range reconciliations {
+			report.Expected += reconciliation.Expected
+			report.Deposited += reconciliation.Deposited
+			switch reconciliation.Status {
+			case models.CashStatusShort:
+				report.Shortages++
+			case models.CashStatusOver:
+				report.Overages++
+			}
+		}
+		report.Expected = math.Round(report.Expected*100) / 100
+		report.Deposited = math.Round(report.Deposited*100) / 100
+		report.Difference = math.Round((report.Deposited-report.Expected)*100) / 100
+
+		writeResponse(w, http.StatusOK, "Cash reconciliation successfully fetched.", report)
+	}
+}
@@ -0,0 +1,451 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"go-application-task/internal/ledger"
+	"go-application-task/internal/models"
+	"go-application-task/pkg/storage"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// maxClaimUploadSize caps the size of a claim upload, all photos included
+const maxClaimUploadSize = 20 << 20
+
+// maxClaimPhotos is the most photos a claim may be filed with
+const maxClaimPhotos = 5
+
+// claimableStatuses are the order statuses in which the parcel has been in the courier's hands
+var claimableStatuses = []string{
+	models.OrderStatusPicked,
+	models.OrderStatusOutForDelivery,
+	models.OrderStatusDeliveryFailed,
+	models.OrderStatusCompleted,
+	models.OrderStatusPartiallyDelivered,
+	models.OrderStatusReturnInitiated,
+	models.OrderStatusReturned,
+}
+
+// claimTransitions lists the statuses a claim may move to from each status
+var claimTransitions = map[string][]string{
+	models.ClaimStatusOpen:          {models.ClaimStatusInvestigating, models.ClaimStatusRejected},
+	models.ClaimStatusInvestigating: {models.ClaimStatusApproved, models.ClaimStatusRejected},
+}
+
+// claimEvidenceURL is where a claim photo can be downloaded
+func claimEvidenceURL(claimID, evidenceID int) string {
+	return fmt.Sprintf("/claims/%d/evidence/%d", claimID, evidenceID)
+}
+
+// compensationCap returns the most a claim on an order may be compensated with: the declared value of its items,
+// or the amount to collect when no items were declared
+func compensationCap(order models.Order) float64 {
+	if order.DeclaredValue != nil {
+		return *order.DeclaredValue
+	}
+	return order.AmountToCollect
+}
+
+// getClaim fetches a claim with its evidence and comments, scoped to userID unless it is 0
+func getClaim(db sqlx.Queryer, claimID, userID int) (models.Claim, error) {
+	var claim models.Claim
+	err := sqlx.Get(db, &claim, `SELECT * FROM claims WHERE id = $1 AND ($2 = 0 OR user_id = $2)`, claimID, userID)
+	if err != nil {
+		return claim, err
+	}
+	claim.Evidence = []models.ClaimEvidence{}
+	err = sqlx.Select(db, &claim.Evidence, `SELECT * FROM claim_evidence WHERE claim_id = $1 ORDER BY id`, claimID)
+	if err != nil {
+		return claim, err
+	}
+	for i := range claim.Evidence {
+		claim.Evidence[i].URL = claimEvidenceURL(claimID, claim.Evidence[i].ID)
+	}
+	claim.Comments = []models.ClaimComment{}
+	err = sqlx.Select(db, &claim.Comments, `SELECT * FROM claim_comments WHERE claim_id = $1 ORDER BY id`, claimID)
+	return claim, err
+}
+
+// CreateClaimHandler files a claim for one of the caller's parcels that was lost or damaged.
+// It takes a multipart form with consignment_id, claim_type, description, an optional requested_amount
+// and up to 5 JPEG or PNG photos under photos.
+func CreateClaimHandler(db *sqlx.DB, store storage.BlobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := requireRole(w, r, db, models.RoleMerchant)
+		if !ok {
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxClaimUploadSize)
+		if err := r.ParseMultipartForm(maxClaimUploadSize); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		consignmentID := strings.TrimSpace(r.FormValue("consignment_id"))
+		claimType := r.FormValue("claim_type")
+		description := strings.TrimSpace(r.FormValue("description"))
+		photos := r.MultipartForm.File["photos"]
+
+		errs := make(map[string][]string)
+		if consignmentID == "" {
+			errs["consignment_id"] = append(errs["consignment_id"], "The consignment field is required.")
+		}
+		if claimType != models.ClaimTypeLost && claimType != models.ClaimTypeDamaged {
+			errs["claim_type"] = append(errs["claim_type"], "The claim type must be lost or damaged.")
+		}
+		if description == "" {
+			errs["description"] = append(errs["description"], "The description field is required.")
+		}
+		var requestedAmount *float64
+		if value := r.FormValue("requested_amount"); value != "" {
+			amount, err := strconv.ParseFloat(value, 64)
+			if err != nil || amount <= 0 {
+				errs["requested_amount"] = append(errs["requested_amount"], "The requested amount must be greater than 0.")
+			}
+			amount = roundMoney(amount)
+			requestedAmount = &amount
+		}
+		if claimType == models.ClaimTypeDamaged && len(photos) == 0 {
+			errs["photos"] = append(errs["photos"], "Photos of the damage are required.")
+		}
+		if len(photos) > maxClaimPhotos {
+			errs["photos"] = append(errs["photos"], fmt.Sprintf("A claim may have at most %d photos.", maxClaimPhotos))
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+
+		order, err := getUserOrder(db, consignmentID, user.ID)
+		if err == sql.ErrNoRows {
+			writeValidationErrors(w, map[string][]string{"consignment_id": {"The consignment was not found."}})
+			return
+		}
+		if err != nil {
+			log.Printf("Order retrieval error: %v", err)
+			http.Error(w, "Failed to fetch order", http.StatusInternalServerError)
+			return
+		}
+		claimable := false
+		for _, status := range claimableStatuses {
+			claimable = claimable || order.OrderStatus == status
+		}
+		if !claimable {
+			http.Error(w, fmt.Sprintf("A claim cannot be filed for an order in status %s", order.OrderStatus), http.StatusConflict)
+			return
+		}
+		if requestedAmount != nil && *requestedAmount > compensationCap(order) {
+			writeValidationErrors(w, map[string][]string{"requested_amount": {fmt.Sprintf("The requested amount may not exceed the parcel's value of %.2f.", compensationCap(order))}})
+			return
+		}
+
+		// Store the photos before the claim so a failed upload leaves no claim without its evidence
+		var keys []string
+		for i, header := range photos {
+			file, err := header.Open()
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+				return
+			}
+			key, err := storeImage(r.Context(), store, file, fmt.Sprintf("claims/%s/photo", consignmentID))
+			file.Close()
+			if err == errUnsupportedType {
+				field := fmt.Sprintf("photos.%d", i)
+				writeValidationErrors(w, map[string][]string{field: {"The photo must be a JPEG or PNG image."}})
+				return
+			}
+			if err != nil {
+				log.Printf("Failed to store claim photo: %v", err)
+				http.Error(w, "Failed to store claim evidence", http.StatusInternalServerError)
+				return
+			}
+			keys = append(keys, key)
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			log.Printf("Failed to start transaction: %v", err)
+			http.Error(w, "Failed to file claim", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		var claimID int
+		err = tx.Get(&claimID, `
+			INSERT INTO claims (consignment_id, user_id, claim_type, description, requested_amount)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING id
+		`, consignmentID, user.ID, claimType, description, requestedAmount)
+		if isUniqueViolation(err, "idx_claims_consignment_id") {
+			http.Error(w, "A claim has already been filed for this consignment", http.StatusConflict)
+			return
+		}
+		for _, key := range keys {
+			if err != nil {
+				break
+			}
+			_, err = tx.Exec(`INSERT INTO claim_evidence (claim_id, file_key) VALUES ($1, $2)`, claimID, key)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		var claim models.Claim
+		if err == nil {
+			claim, err = getClaim(db, claimID, user.ID)
+		}
+		if err != nil {
+			log.Printf("Failed to file claim: %v", err)
+			http.Error(w, "Failed to file claim", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusCreated, "Claim filed successfully.", claim)
+	}
+}
+
+// ListClaimsHandler lists the caller's claims, newest first, optionally filtered by status.
+// Ops see every merchant's claims and may filter by user_id.
+func ListClaimsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := requireRole(w, r, db, models.RoleMerchant, models.RoleOps)
+		if !ok {
+			return
+		}
+		userID := listScope(r, user)
+		status := r.URL.Query().Get("status")
+
+		page, perPage, offset := parsePagination(r)
+		claims := []models.Claim{}
+		err := db.Select(&claims, `
+			SELECT * FROM claims
+			WHERE ($1 = 0 OR user_id = $1) AND ($2 = '' OR status = $2)
+			ORDER BY id DESC
+			LIMIT $3 OFFSET $4
+		`, userID, status, perPage, offset)
+		if err != nil {
+			log.Printf("Failed to fetch claims: %v", err)
+			http.Error(w, "Failed to fetch claims", http.StatusInternalServerError)
+			return
+		}
+
+		var total int
+		err = db.Get(&total, `SELECT COUNT(*) FROM claims WHERE ($1 = 0 OR user_id = $1) AND ($2 = '' OR status = $2)`, userID, status)
+		if err != nil {
+			log.Printf("Error counting claims: %v", err)
+			http.Error(w, "Failed to calculate pagination", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Claims successfully fetched.", newPaginatedResponse(claims, len(claims), total, page, perPage))
+	}
+}
+
+// GetClaimHandler returns a claim with its evidence and comments
+func GetClaimHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claimID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+		user, ok := requireRole(w, r, db, models.RoleMerchant, models.RoleOps)
+		if !ok {
+			return
+		}
+
+		claim, err := getClaim(db, claimID, ownerScope(user))
+		if err == sql.ErrNoRows {
+			http.Error(w, "Claim not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Claim retrieval error: %v", err)
+			http.Error(w, "Failed to fetch claim", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Claim successfully fetched.", claim)
+	}
+}
+
+// ClaimEvidenceFileHandler serves a claim photo to the claim's merchant or to ops
+func ClaimEvidenceFileHandler(db *sqlx.DB, store storage.BlobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claimID, _ := strconv.Atoi(mux.Vars(r)["id"])
+		evidenceID, _ := strconv.Atoi(mux.Vars(r)["evidence_id"])
+
+		user, ok := requireRole(w, r, db, models.RoleMerchant, models.RoleOps)
+		if !ok {
+			return
+		}
+
+		var key string
+		err := db.Get(&key, `
+			SELECT e.file_key
+			FROM claim_evidence e
+			JOIN claims c ON c.id = e.claim_id
+			WHERE e.id = $1 AND e.claim_id = $2 AND ($3 = 0 OR c.user_id = $3)
+		`, evidenceID, claimID, ownerScope(user))
+		if err == sql.ErrNoRows {
+			http.Error(w, "Evidence not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Claim evidence retrieval error: %v", err)
+			http.Error(w, "Failed to fetch evidence", http.StatusInternalServerError)
+			return
+		}
+
+		serveFile(w, r, store, key, "Evidence not found")
+	}
+}
+
+// AddClaimCommentHandler leaves a note on a claim for the merchant (ops only)
+func AddClaimCommentHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claimID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+		var req struct {
+			Body string `json:"body"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if strings.TrimSpace(req.Body) == "" {
+			writeValidationErrors(w, map[string][]string{"body": {"The body field is required."}})
+			return
+		}
+
+		user, ok := requireRole(w, r, db, models.RoleOps)
+		if !ok {
+			return
+		}
+
+		var comment models.ClaimComment
+		err := db.Get(&comment, `
+			INSERT INTO claim_comments (claim_id, user_id, body)
+			SELECT id, $2, $3 FROM claims WHERE id = $1
+			RETURNING *
+		`, claimID, user.ID, strings.TrimSpace(req.Body))
+		if err == sql.ErrNoRows {
+			http.Error(w, "Claim not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to add claim comment: %v", err)
+			http.Error(w, "Failed to add comment", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusCreated, "Comment added successfully.", comment)
+	}
+}
+
+// UpdateClaimStatusHandler moves a claim through investigation to a decision (ops only).
+// Approving takes a compensation_amount of at most the parcel's value, which is credited to the merchant's balance
+// and paid out with their next payout. An optional comment is left on the claim.
+func UpdateClaimStatusHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claimID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+		var req struct {
+			Status             string   `json:"status"`
+			CompensationAmount *float64 `json:"compensation_amount"`
+			Comment            string   `json:"comment"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		user, ok := requireRole(w, r, db, models.RoleOps)
+		if !ok {
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			log.Printf("Failed to start transaction: %v", err)
+			http.Error(w, "Failed to update claim", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		var claim models.Claim
+		err = tx.Get(&claim, `SELECT * FROM claims WHERE id = $1 FOR UPDATE`, claimID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Claim not found", http.StatusNotFound)
+			return
+		}
+		var order models.Order
+		if err == nil {
+			err = tx.Get(&order, `SELECT `+orderColumns+` FROM orders WHERE consignment_id = $1`, claim.ConsignmentID)
+		}
+		if err != nil {
+			log.Printf("Claim retrieval error: %v", err)
+			http.Error(w, "Failed to update claim", http.StatusInternalServerError)
+			return
+		}
+
+		allowed := false
+		for _, next := range claimTransitions[claim.Status] {
+			allowed = allowed || next == req.Status
+		}
+		if !allowed {
+			http.Error(w, fmt.Sprintf("Claim cannot move from %s to %s", claim.Status, req.Status), http.StatusConflict)
+			return
+		}
+
+		var compensation *float64
+		if req.Status == models.ClaimStatusApproved {
+			limit := compensationCap(order)
+			if req.CompensationAmount == nil || *req.CompensationAmount <= 0 || roundMoney(*req.CompensationAmount) > roundMoney(limit) {
+				writeValidationErrors(w, map[string][]string{"compensation_amount": {fmt.Sprintf("The compensation must be greater than 0 and at most the parcel's value of %.2f.", limit)}})
+				return
+			}
+			amount := roundMoney(*req.CompensationAmount)
+			compensation = &amount
+		}
+
+		decided := req.Status == models.ClaimStatusApproved || req.Status == models.ClaimStatusRejected
+		err = tx.Get(&claim, `
+			UPDATE claims
+			SET status = $2, compensation_amount = $3,
+			    decided_by = CASE WHEN $4 THEN $5::int END, decided_at = CASE WHEN $4 THEN NOW() END, updated_at = NOW()
+			WHERE id = $1
+			RETURNING *
+		`, claim.ID, req.Status, compensation, decided, user.ID)
+		if err == nil && compensation != nil {
+			err = ledger.Post(tx, ledger.Posting{
+				EntryType:     models.EntryClaimCompensation,
+				UserID:        claim.UserID,
+				ConsignmentID: &claim.ConsignmentID,
+				Debit:         models.AccountClaimsExpense,
+				Credit:        models.AccountMerchantPayable,
+				Amount:        *compensation,
+			})
+		}
+		if err == nil && strings.TrimSpace(req.Comment) != "" {
+			_, err = tx.Exec(`INSERT INTO claim_comments (claim_id, user_id, body) VALUES ($1, $2, $3)`, claim.ID, user.ID, strings.TrimSpace(req.Comment))
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err == nil {
+			claim, err = getClaim(db, claim.ID, 0)
+		}
+		if err != nil {
+			log.Printf("Failed to update claim: %v", err)
+			http.Error(w, "Failed to update claim", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, fmt.Sprintf("Claim %s.", claim.Status), claim)
+	}
+}
@@ -3,12 +3,19 @@ package handlers
 import (
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"github.com/dgrijalva/jwt-go"
 	"github.com/jmoiron/sqlx"
+	"go-application-task/configs"
 	"go-application-task/internal/models"
+	"go-application-task/internal/otp"
+	"go-application-task/internal/outbox"
+	"go-application-task/internal/pricing"
+	"go-application-task/internal/promotion"
 	"go-application-task/pkg/db"
 	"go-application-task/pkg/utils"
+	"log"
 	"net/http"
 	"os"
 	"regexp"
@@ -23,21 +30,23 @@ const (
 	ValidDeliveryType  = 48
 	ValidItemType      = 2
 	ValidItemQuantity  = 1
-	ValidItemWeight    = 0.5
 )
 
-// GetUserIDFromToken extracts the user ID from the JWT token using the email
-func GetUserIDFromToken(r *http.Request, jwtSecret string, db *sqlx.DB) (int, error) {
+// consignmentIDAttempts is how many consignment IDs are tried before giving up on a unique violation
+const consignmentIDAttempts = 5
+
+// GetUserFromToken extracts the user from the JWT token using the email
+func GetUserFromToken(r *http.Request, jwtSecret string, db *sqlx.DB) (models.User, error) {
 	// Get token from Authorization header (strip Bearer prefix)
 	authHeader := r.Header.Get("Authorization")
 	if authHeader == "" {
-		return 0, fmt.Errorf("authorization token is missing")
+		return models.User{}, fmt.Errorf("authorization token is missing")
 	}
 
 	// Strip Bearer prefix
 	tokenString := strings.TrimPrefix(authHeader, "Bearer ")
 	if tokenString == "" {
-		return 0, fmt.Errorf("authorization token format is incorrect")
+		return models.User{}, fmt.Errorf("authorization token format is incorrect")
 	}
 
 	// Parse the token and extract claims
@@ -47,34 +56,60 @@ func GetUserIDFromToken(r *http.Request, jwtSecret string, db *sqlx.DB) (int, er
 	})
 
 	if err != nil || !token.Valid {
-		return 0, fmt.Errorf("invalid token: %v", err)
+		return models.User{}, fmt.Errorf("invalid token: %v", err)
 	}
 
 	// Extract claims from the token
 	claims, ok := token.Claims.(jwt.MapClaims)
 	if !ok {
-		return 0, fmt.Errorf("unable to parse claims")
+		return models.User{}, fmt.Errorf("unable to parse claims")
 	}
 
 	// Extract email from claims
 	email, ok := claims["email"].(string)
 	if !ok {
-		return 0, fmt.Errorf("email not found in token")
+		return models.User{}, fmt.Errorf("email not found in token")
 	}
 
 	// Query the database to get the user_id for the given email
 	var user models.User
-	query := "SELECT * FROM users WHERE email = $1"
-	err = db.QueryRow(query, email).Scan(&user.ID, &user.Email, &user.Password)
+	query := "SELECT id, email, password, role FROM users WHERE email = $1"
+	err = db.QueryRow(query, email).Scan(&user.ID, &user.Email, &user.Password, &user.Role)
 	if err == sql.ErrNoRows {
-		return 0, fmt.Errorf("no user found with email %s", email)
+		return models.User{}, fmt.Errorf("no user found with email %s", email)
 	}
 	if err != nil {
-		return 0, fmt.Errorf("unable to retrieve user for email %s: %v", email, err)
+		return models.User{}, fmt.Errorf("unable to retrieve user for email %s: %v", email, err)
+	}
+	return user, nil
+}
+
+// GetUserIDFromToken extracts the user ID from the JWT token using the email
+func GetUserIDFromToken(r *http.Request, jwtSecret string, db *sqlx.DB) (int, error) {
+	user, err := GetUserFromToken(r, jwtSecret, db)
+	if err != nil {
+		return 0, err
 	}
 	return user.ID, nil
 }
 
+// requireRole authenticates the caller and checks they have one of the given roles.
+// It writes the error response and returns false when they do not.
+func requireRole(w http.ResponseWriter, r *http.Request, db *sqlx.DB, roles ...string) (models.User, bool) {
+	user, err := GetUserFromToken(r, os.Getenv("JWT_SECRET"), db)
+	if err != nil {
+		http.Error(w, "Unauthorized", http.StatusUnauthorized)
+		return user, false
+	}
+	for _, role := range roles {
+		if user.Role == role {
+			return user, true
+		}
+	}
+	http.Error(w, "Forbidden", http.StatusForbidden)
+	return user, false
+}
+
 // ValidateOrderFields validates if the provided fields match the expected hardcoded values and if required fields are missing.
 func ValidateOrderFields(order *models.Order) map[string][]string {
 	errors := make(map[string][]string)
@@ -129,24 +164,136 @@ func ValidateOrderFields(order *models.Order) map[string][]string {
 		errors["item_type"] = append(errors["item_type"], "Invalid item type selected")
 	}
 
-	// Validate item_quantity
-	if order.ItemQuantity == 0 {
-		errors["item_quantity"] = append(errors["item_quantity"], "The item quantity field is required.")
-	} else if order.ItemQuantity != ValidItemQuantity {
-		errors["item_quantity"] = append(errors["item_quantity"], "Invalid item quantity selected")
+	// Validate item_quantity, it is the total of the items when items are given
+	if len(order.Items) == 0 {
+		if order.ItemQuantity == 0 {
+			errors["item_quantity"] = append(errors["item_quantity"], "The item quantity field is required.")
+		} else if order.ItemQuantity != ValidItemQuantity {
+			errors["item_quantity"] = append(errors["item_quantity"], "Invalid item quantity selected")
+		}
 	}
 
-	// Validate item_weight
-	if order.ItemWeight == 0 {
-		errors["item_weight"] = append(errors["item_weight"], "The item weight field is required.")
-	} else if order.ItemWeight != ValidItemWeight {
-		errors["item_weight"] = append(errors["item_weight"], "Invalid item weight selected")
+	// Validate item_weight and dimensions
+	for field, messages := range validateParcel(order) {
+		errors[field] = append(errors[field], messages...)
 	}
 
 	// Validate amount_to_collect
 	if order.AmountToCollect == 0 {
 		errors["amount_to_collect"] = append(errors["amount_to_collect"], "The amount to collect field is required.")
 	}
+
+	// Validate items
+	for i, item := range order.Items {
+		field := fmt.Sprintf("items.%d", i)
+		if item.Name == "" {
+			errors[field+".name"] = append(errors[field+".name"], "The item name field is required.")
+		}
+		if item.Quantity < 1 {
+			errors[field+".quantity"] = append(errors[field+".quantity"], "The item quantity must be at least 1.")
+		}
+		if item.UnitPrice < 0 {
+			errors[field+".unit_price"] = append(errors[field+".unit_price"], "The unit price cannot be negative.")
+		}
+		if item.Weight < 0 {
+			errors[field+".weight"] = append(errors[field+".weight"], "The item weight cannot be negative.")
+		}
+	}
+
+	// Validate check_amount_to_collect
+	if order.CheckAmountToCollect && order.DeclaredValue == nil {
+		errors["check_amount_to_collect"] = append(errors["check_amount_to_collect"], "Items are required to check the amount to collect.")
+	}
+
+	// Validate order_type
+	switch order.OrderType {
+	case "", models.OrderTypeRegular:
+		if order.ParentConsignmentID != nil {
+			errors["parent_consignment_id"] = append(errors["parent_consignment_id"], "Only exchange orders can have a parent consignment.")
+		}
+	case models.OrderTypeExchange:
+		if order.ParentConsignmentID == nil || *order.ParentConsignmentID == "" {
+			errors["parent_consignment_id"] = append(errors["parent_consignment_id"], "The parent consignment field is required for exchange orders.")
+		}
+	default:
+		errors["order_type"] = append(errors["order_type"], "Invalid order type selected")
+	}
+	return errors
+}
+
+// validateParcel checks the weight and the optional dimensions the delivery fee is worked out from
+func validateParcel(order *models.Order) map[string][]string {
+	errors := make(map[string][]string)
+
+	if order.ItemWeight == 0 && len(order.Items) == 0 {
+		errors["item_weight"] = append(errors["item_weight"], "The item weight field is required.")
+	} else if order.ItemWeight <= 0 {
+		errors["item_weight"] = append(errors["item_weight"], "The item weight must be greater than 0.")
+	}
+
+	// Dimensions are optional but must be given together
+	dimensions := map[string]float64{"length": order.Length, "width": order.Width, "height": order.Height}
+	given := 0
+	for field, value := range dimensions {
+		if value < 0 {
+			errors[field] = append(errors[field], fmt.Sprintf("The %s cannot be negative.", field))
+		}
+		if value > 0 {
+			given++
+		}
+	}
+	if given > 0 && given < len(dimensions) {
+		for field, value := range dimensions {
+			if value == 0 {
+				errors[field] = append(errors[field], fmt.Sprintf("The %s field is required when any dimension is given.", field))
+			}
+		}
+	}
+	return errors
+}
+
+// quoteOrder prices an order with the surcharges currently in effect and records the charges on the order
+func quoteOrder(db sqlx.Queryer, order *models.Order) (pricing.Quote, error) {
+	surcharges, err := effectiveSurcharges(db)
+	if err != nil {
+		return pricing.Quote{}, err
+	}
+	quote := pricing.Calculate(pricing.Parcel{
+		InsideCity:      order.RecipientCity == ValidRecipientCity,
+		Weight:          order.ItemWeight,
+		Length:          order.Length,
+		Width:           order.Width,
+		Height:          order.Height,
+		AmountToCollect: order.AmountToCollect,
+	}, configs.GetPricingConfig(), surcharges)
+	setOrderFees(order, quote)
+	return quote, nil
+}
+
+// setOrderFees records a quote's charges, the weights it was charged on and the full breakdown on the order
+func setOrderFees(order *models.Order, quote pricing.Quote) {
+	order.VolumetricWeight = quote.VolumetricWeight
+	order.ChargeableWeight = quote.ChargeableWeight
+	order.DeliveryFee = quote.DeliveryFee
+	order.DeliveryFeeDiscount = quote.Discount
+	order.Surcharge = quote.Surcharge
+	order.CODFee = quote.CODFee
+	order.VAT = quote.VAT
+	// A quote only holds numbers and strings, so it always marshals
+	order.FeeBreakdown, _ = json.Marshal(quote)
+}
+
+// validateAmountToCollect checks that the amount to collect covers the declared value of the items
+// and does not exceed it by more than the delivery fee passed on to the recipient
+func validateAmountToCollect(order *models.Order) map[string][]string {
+	errors := make(map[string][]string)
+	if !order.CheckAmountToCollect || order.DeclaredValue == nil {
+		return errors
+	}
+	min, max := *order.DeclaredValue, *order.DeclaredValue+order.DeliveryFee
+	if order.AmountToCollect < min || order.AmountToCollect > max {
+		errors["amount_to_collect"] = append(errors["amount_to_collect"], fmt.Sprintf("The amount to collect must be between %.2f and %.2f for the declared value of the items.", min, max))
+	}
 	return errors
 }
 
@@ -158,95 +305,269 @@ func validatePhone(phone string) bool {
 	return re.MatchString(phone)
 }
 
-// CreateOrderHandler handles the creation of a new order
-func CreateOrderHandler(w http.ResponseWriter, r *http.Request) {
-	var order models.Order
-	if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
-		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
-		return
+// insertOrder writes a new order row, its initial status history entry and the order.created event in one transaction.
+// When otpCode is set its hash is stored as the order's delivery code. An applied promotion is redeemed, failing with
+// promotion.ErrUnavailable when it ran out meanwhile. An exchange also gets a return consignment for the old item,
+// which the rider collects when handing over the replacement.
+func insertOrder(order *models.Order, otpCode string) error {
+	tx, err := db.WriteDB.Beginx()
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
 
-	jwtSecret := os.Getenv("JWT_SECRET")
+	if taken, err := consignmentIDTaken(tx, order.ConsignmentID); err != nil || taken {
+		if taken {
+			err = errConsignmentIDTaken
+		}
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO orders (store_id, recipient_name, recipient_phone, recipient_address, recipient_city, recipient_zone, recipient_area, delivery_type, item_type, item_quantity, item_weight, amount_to_collect, order_status, consignment_id, delivery_fee, cod_fee, user_id, order_type, parent_consignment_id, declared_value, length, width, height, volumetric_weight, chargeable_weight, promotion_id, promo_code, delivery_fee_discount, surcharge, vat, fee_breakdown) 
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, $30, $31)
+	`, order.StoreID, order.RecipientName, order.RecipientPhone, order.RecipientAddress, order.RecipientCity, order.RecipientZone, order.RecipientArea, order.DeliveryType, order.ItemType, order.ItemQuantity, order.ItemWeight, order.AmountToCollect, order.OrderStatus, order.ConsignmentID, order.DeliveryFee, order.CODFee, order.UserID, order.OrderType, order.ParentConsignmentID, order.DeclaredValue, order.Length, order.Width, order.Height, order.VolumetricWeight, order.ChargeableWeight, order.PromotionID, order.PromoCode, order.DeliveryFeeDiscount, order.Surcharge, order.VAT, order.FeeBreakdown)
+	if err != nil {
+		return err
+	}
+
+	if order.PromotionID != nil {
+		if err := promotion.Redeem(tx, *order.PromotionID, promotionOrder(order), order.ConsignmentID, order.DeliveryFeeDiscount); err != nil {
+			return err
+		}
+	}
 
-	// Run user ID fetch in a goroutine
-	userIDCh := make(chan int)
-	go func() {
-		userID, err := GetUserIDFromToken(r, jwtSecret, db.ReadDB)
+	for i := range order.Items {
+		item := &order.Items[i]
+		item.ConsignmentID = order.ConsignmentID
+		err = tx.Get(&item.ID, `
+			INSERT INTO order_items (consignment_id, sku, name, quantity, unit_price, weight)
+			VALUES ($1, $2, $3, $4, $5, $6)
+			RETURNING id
+		`, item.ConsignmentID, item.SKU, item.Name, item.Quantity, item.UnitPrice, item.Weight)
 		if err != nil {
-			userIDCh <- -1 // signal error with a special value
-		} else {
-			userIDCh <- userID
+			return err
 		}
-	}()
+	}
 
-	// Validate required and hardcoded fields
-	errors := ValidateOrderFields(&order)
-	if len(errors) > 0 {
-		w.WriteHeader(http.StatusUnprocessableEntity)
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"message": "Please fix the given errors",
-			"type":    "error",
-			"code":    422,
-			"errors":  errors,
-		})
-		return
+	order.ExchangeReturn = nil
+	if order.OrderType == models.OrderTypeExchange {
+		exchangeReturn := models.OrderReturn{
+			ConsignmentID: order.ConsignmentID,
+			Reason:        fmt.Sprintf("Exchange of %s", *order.ParentConsignmentID),
+			InitiatedBy:   &order.UserID,
+		}
+		if err := insertReturn(tx, &exchangeReturn, order.RecipientCity); err != nil {
+			return err
+		}
+		order.ExchangeReturn = &exchangeReturn
 	}
 
-	// Validate the recipient phone number
-	if !validatePhone(order.RecipientPhone) {
-		http.Error(w, "Invalid phone number", http.StatusBadRequest)
-		return
+	if otpCode != "" {
+		if err := issueDeliveryOTP(tx, order.ConsignmentID, otpCode); err != nil {
+			return err
+		}
 	}
 
-	// Retrieve user ID from goroutine
-	userID := <-userIDCh
-	if userID == -1 {
-		http.Error(w, "Authentication error", http.StatusUnauthorized)
-		return
+	if err := recordStatusChange(tx, order.ConsignmentID, order.OrderStatus); err != nil {
+		return err
 	}
 
-	// Generate consignment_id
-	consignmentID, err := utils.GenerateConsignmentID("DA")
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to generate consignment ID: %v", err), http.StatusInternalServerError)
-		return
+	event := orderEvent{ConsignmentID: order.ConsignmentID, MerchantOrderID: order.MerchantOrderID, OrderStatus: order.OrderStatus}
+	if err := outbox.Write(tx, order.ConsignmentID, models.EventOrderCreated, order.UserID, event); err != nil {
+		return err
 	}
+	return tx.Commit()
+}
 
-	order.ConsignmentID = consignmentID
-	order.OrderStatus = "pending"
-	order.UserID = userID
+// errConsignmentIDTaken is returned when a generated consignment ID is already used by an order or a return
+var errConsignmentIDTaken = errors.New("consignment ID already taken")
+
+// consignmentIDTaken reports whether an order or a return already goes by the consignment ID.
+// Orders and returns are tracked and labelled by the same IDs, so a new ID must be free in both tables.
+func consignmentIDTaken(q sqlx.Queryer, consignmentID string) (bool, error) {
+	var taken bool
+	err := sqlx.Get(q, &taken, `
+		SELECT EXISTS(SELECT 1 FROM orders WHERE consignment_id = $1)
+		    OR EXISTS(SELECT 1 FROM order_returns WHERE return_consignment_id = $1)
+	`, consignmentID)
+	return taken, err
+}
 
-	// Calculate delivery fee
-	deliveryFee := 60
-	if order.RecipientCity != ValidRecipientCity {
-		deliveryFee = 100
-	}
-	order.DeliveryFee = float64(deliveryFee)
-	codFee := 0.01 * order.AmountToCollect
-	order.CODFee = codFee
+// isConsignmentIDCollision reports whether err is a unique violation on the consignment ID
+func isConsignmentIDCollision(err error) bool {
+	return err == errConsignmentIDTaken || db.IsUniqueViolation(err, "orders_consignment_id_key") || db.IsUniqueViolation(err, "idx_consignment_id")
+}
 
-	// Insert order into the database
-	_, err = db.WriteDB.Exec(`
-		INSERT INTO orders (store_id, recipient_name, recipient_phone, recipient_address, recipient_city, recipient_zone, recipient_area, delivery_type, item_type, item_quantity, item_weight, amount_to_collect, order_status, consignment_id, delivery_fee, cod_fee, user_id) 
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17)
-	`, order.StoreID, order.RecipientName, order.RecipientPhone, order.RecipientAddress, order.RecipientCity, order.RecipientZone, order.RecipientArea, order.DeliveryType, order.ItemType, order.ItemQuantity, order.ItemWeight, order.AmountToCollect, order.OrderStatus, order.ConsignmentID, order.DeliveryFee, order.CODFee, order.UserID)
+// CreateOrderHandler handles the creation of a new order.
+// High-value parcels and parcels of opted-in stores get a delivery code, which is sent to the recipient through notifier.
+func CreateOrderHandler(notifier otp.Notifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var order models.Order
+		if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
 
-	if err != nil {
-		http.Error(w, fmt.Sprintf("Failed to create order: %v", err), http.StatusInternalServerError)
-		return
-	}
-
-	// Respond with success
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]interface{}{
-		"message": "Order Created Successfully",
-		"type":    "success",
-		"code":    200,
-		"data": map[string]interface{}{
-			"consignment_id":    consignmentID,
-			"merchant_order_id": order.MerchantOrderID,
-			"order_status":      order.OrderStatus,
-			"delivery_fee":      deliveryFee,
-		},
-	})
+		jwtSecret := os.Getenv("JWT_SECRET")
+
+		// Run user ID fetch in a goroutine
+		userIDCh := make(chan int)
+		go func() {
+			userID, err := GetUserIDFromToken(r, jwtSecret, db.ReadDB)
+			if err != nil {
+				userIDCh <- -1 // signal error with a special value
+			} else {
+				userIDCh <- userID
+			}
+		}()
+
+		// Totals are derived from the items, overriding anything sent alongside them.
+		// The declared value caps claim compensation, so one sent without items is not trusted.
+		order.DeclaredValue = nil
+		order.ApplyItemTotals()
+
+		// Validate required and hardcoded fields
+		errors := ValidateOrderFields(&order)
+		if len(errors) > 0 {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"message": "Please fix the given errors",
+				"type":    "error",
+				"code":    422,
+				"errors":  errors,
+			})
+			return
+		}
+
+		// Validate the recipient phone number
+		if !validatePhone(order.RecipientPhone) {
+			http.Error(w, "Invalid phone number", http.StatusBadRequest)
+			return
+		}
+
+		// Retrieve user ID from goroutine
+		userID := <-userIDCh
+		if userID == -1 {
+			http.Error(w, "Authentication error", http.StatusUnauthorized)
+			return
+		}
+
+		order.OrderStatus = "pending"
+		order.UserID = userID
+		if order.OrderType == "" {
+			order.OrderType = models.OrderTypeRegular
+		}
+
+		// An exchange replaces an item of one of the merchant's delivered orders
+		if order.OrderType == models.OrderTypeExchange {
+			parent, err := getUserOrder(db.ReadDB, *order.ParentConsignmentID, userID)
+			if err == sql.ErrNoRows {
+				writeValidationErrors(w, map[string][]string{"parent_consignment_id": {"The parent consignment was not found."}})
+				return
+			}
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Failed to fetch parent consignment: %v", err), http.StatusInternalServerError)
+				return
+			}
+			if parent.OrderStatus != models.OrderStatusCompleted && parent.OrderStatus != models.OrderStatusPartiallyDelivered {
+				writeValidationErrors(w, map[string][]string{"parent_consignment_id": {"Only delivered orders can be exchanged."}})
+				return
+			}
+		}
+
+		// Calculate delivery fee on the greater of actual and volumetric weight with surcharges, less any promotion
+		quote, err := quoteOrder(db.ReadDB, &order)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to calculate delivery fee: %v", err), http.StatusInternalServerError)
+			return
+		}
+		errors, err = applyPromotion(db.ReadDB, &order, &quote)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to apply promotion: %v", err), http.StatusInternalServerError)
+			return
+		}
+		if len(errors) > 0 {
+			writeValidationErrors(w, errors)
+			return
+		}
+
+		if errors := validateAmountToCollect(&order); len(errors) > 0 {
+			writeValidationErrors(w, errors)
+			return
+		}
+
+		// High-value parcels and opted-in stores need a delivery code from the recipient
+		otpRequired, err := deliveryOTPRequired(db.ReadDB, &order)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to check delivery settings: %v", err), http.StatusInternalServerError)
+			return
+		}
+		var otpCode string
+		if otpRequired {
+			otpCode, err = otp.Generate(configs.GetDeliveryOTPConfig().Length)
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Failed to generate delivery code: %v", err), http.StatusInternalServerError)
+				return
+			}
+		}
+
+		// Generate consignment_id and insert, retrying when the generated ID collides with an existing one
+		for attempt := 1; attempt <= consignmentIDAttempts; attempt++ {
+			order.ConsignmentID, err = utils.GenerateConsignmentID(utils.CityCode(order.RecipientCity))
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Failed to generate consignment ID: %v", err), http.StatusInternalServerError)
+				return
+			}
+
+			err = insertOrder(&order, otpCode)
+			if !isConsignmentIDCollision(err) {
+				break
+			}
+			log.Printf("Consignment ID %s already exists, retrying (attempt %d)", order.ConsignmentID, attempt)
+		}
+
+		if err == promotion.ErrUnavailable {
+			writeValidationErrors(w, map[string][]string{"promo_code": {"The promotion is no longer available."}})
+			return
+		}
+		if err != nil {
+			http.Error(w, fmt.Sprintf("Failed to create order: %v", err), http.StatusInternalServerError)
+			return
+		}
+		consignmentID := order.ConsignmentID
+		if otpRequired {
+			sendDeliveryOTP(notifier, order.RecipientPhone, consignmentID, otpCode)
+		}
+
+		// Respond with success
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"message": "Order Created Successfully",
+			"type":    "success",
+			"code":    200,
+			"data": map[string]interface{}{
+				"consignment_id":        consignmentID,
+				"merchant_order_id":     order.MerchantOrderID,
+				"order_status":          order.OrderStatus,
+				"delivery_fee":          quote.DeliveryFee,
+				"delivery_fee_discount": quote.Discount,
+				"promo_code":            order.PromoCode,
+				"surcharge":             quote.Surcharge,
+				"cod_fee":               quote.CODFee,
+				"vat":                   quote.VAT,
+				"total_fee":             quote.TotalFee,
+				"fee_breakdown":         quote,
+				"chargeable_weight":     quote.ChargeableWeight,
+				"volumetric_weight":     quote.VolumetricWeight,
+				"order_type":            order.OrderType,
+				"exchange_return":       order.ExchangeReturn,
+				"item_quantity":         order.ItemQuantity,
+				"item_weight":           order.ItemWeight,
+				"declared_value":        order.DeclaredValue,
+				"items":                 order.Items,
+				"delivery_otp_required": otpRequired,
+			},
+		})
+	}
 }
@@ -0,0 +1,260 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"go-application-task/configs"
+	"go-application-task/internal/models"
+	"go-application-task/internal/otp"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// otpNotifyTimeout bounds how long sending a code to the recipient may take
+const otpNotifyTimeout = 30 * time.Second
+
+// deliveryOTPRequired reports whether an order needs a delivery code, either because of its value
+// or because its store has opted in
+func deliveryOTPRequired(db sqlx.Queryer, order *models.Order) (bool, error) {
+	if order.AmountToCollect > configs.GetDeliveryOTPConfig().Threshold {
+		return true, nil
+	}
+	var enabled bool
+	err := sqlx.Get(db, &enabled, `SELECT otp_enabled FROM store_delivery_settings WHERE user_id = $1 AND store_id = $2`, order.UserID, order.StoreID)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	return enabled, err
+}
+
+// issueDeliveryOTP stores the hash of code for an order, replacing any earlier code and clearing failed attempts
+func issueDeliveryOTP(exec sqlx.Execer, consignmentID, code string) error {
+	hash, err := otp.Hash(code)
+	if err != nil {
+		return err
+	}
+	_, err = exec.Exec(`
+		INSERT INTO delivery_otps (consignment_id, code_hash)
+		VALUES ($1, $2)
+		ON CONFLICT (consignment_id) DO UPDATE
+		SET code_hash = EXCLUDED.code_hash, failed_attempts = 0, updated_at = NOW()
+	`, consignmentID, hash)
+	return err
+}
+
+// sendDeliveryOTP sends a code to the recipient in the background so the request is not held up by the SMS provider
+func sendDeliveryOTP(notifier otp.Notifier, phone, consignmentID, code string) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), otpNotifyTimeout)
+		defer cancel()
+		if err := notifier.Notify(ctx, phone, consignmentID, code); err != nil {
+			log.Printf("Failed to send delivery OTP for %s: %v", consignmentID, err)
+		}
+	}()
+}
+
+// errWrongOTP is returned by verifyDeliveryOTP when the code does not match
+type errWrongOTP struct {
+	AttemptsLeft int
+}
+
+func (e errWrongOTP) Error() string {
+	return fmt.Sprintf("wrong delivery code, %d attempts left", e.AttemptsLeft)
+}
+
+// errOTPLocked is returned by verifyDeliveryOTP while too many wrong codes have been entered
+type errOTPLocked struct {
+	Until time.Time
+}
+
+func (e errOTPLocked) Error() string {
+	return fmt.Sprintf("too many wrong delivery codes, try again after %s", e.Until.Format(time.RFC3339))
+}
+
+// verifyDeliveryOTP checks code against the order's delivery code, if it has one, and logs the attempt.
+// Wrong codes are counted and lock the order for the configured lockout once the limit is reached.
+// On errWrongOTP and errOTPLocked the caller must still commit tx so the attempt is recorded.
+func verifyDeliveryOTP(tx *sqlx.Tx, consignmentID string, riderID int, code string) error {
+	// The lock is compared with the database clock, which also set it
+	var deliveryOTP struct {
+		models.DeliveryOTP
+		Now time.Time `db:"now"`
+	}
+	err := tx.Get(&deliveryOTP, `
+		SELECT *, LOCALTIMESTAMP AS now
+		FROM delivery_otps WHERE consignment_id = $1 FOR UPDATE
+	`, consignmentID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	cfg := configs.GetDeliveryOTPConfig()
+	limiter := otp.Limiter{MaxAttempts: cfg.MaxAttempts, Lockout: cfg.Lockout}
+	if limiter.Locked(deliveryOTP.LockedUntil, deliveryOTP.Now) {
+		return errOTPLocked{Until: *deliveryOTP.LockedUntil}
+	}
+
+	success := code != "" && otp.Matches(deliveryOTP.CodeHash, code)
+	if _, err := tx.Exec(`INSERT INTO delivery_otp_attempts (consignment_id, rider_id, success) VALUES ($1, $2, $3)`, consignmentID, riderID, success); err != nil {
+		return err
+	}
+	if success {
+		_, err = tx.Exec(`UPDATE delivery_otps SET verified_at = NOW(), failed_attempts = 0, locked_until = NULL, updated_at = NOW() WHERE consignment_id = $1`, consignmentID)
+		return err
+	}
+
+	log.Printf("Wrong delivery OTP for %s entered by rider %d", consignmentID, riderID)
+	attemptsLeft, lockedUntil := limiter.Fail(deliveryOTP.FailedAttempts, deliveryOTP.Now)
+	if lockedUntil == nil {
+		_, err = tx.Exec(`UPDATE delivery_otps SET failed_attempts = failed_attempts + 1, updated_at = NOW() WHERE consignment_id = $1`, consignmentID)
+		if err != nil {
+			return err
+		}
+		return errWrongOTP{AttemptsLeft: attemptsLeft}
+	}
+
+	// Out of attempts: lock the code and give the rider a fresh set once the lockout ends
+	_, err = tx.Exec(`
+		UPDATE delivery_otps SET failed_attempts = 0, locked_until = $2, updated_at = NOW() WHERE consignment_id = $1
+	`, consignmentID, *lockedUntil)
+	if err != nil {
+		return err
+	}
+	log.Printf("Delivery OTP for %s locked until %s", consignmentID, lockedUntil.Format(time.RFC3339))
+	return errOTPLocked{Until: *lockedUntil}
+}
+
+// ResendDeliveryOTPHandler issues a new delivery code for one of the caller's orders and sends it to the recipient.
+// The previous code stops working.
+func ResendDeliveryOTPHandler(db *sqlx.DB, notifier otp.Notifier) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		consignmentID := mux.Vars(r)["consignment_id"]
+
+		// Extract userID from token
+		userID, err := GetUserIDFromToken(r, os.Getenv("JWT_SECRET"), db)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		order, err := getUserOrder(db, consignmentID, userID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Order not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Order retrieval error: %v", err)
+			http.Error(w, "Failed to fetch order", http.StatusInternalServerError)
+			return
+		}
+
+		var deliveryOTP models.DeliveryOTP
+		err = db.Get(&deliveryOTP, `SELECT * FROM delivery_otps WHERE consignment_id = $1`, consignmentID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "The order does not need a delivery code", http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Printf("Delivery OTP retrieval error: %v", err)
+			http.Error(w, "Failed to resend delivery code", http.StatusInternalServerError)
+			return
+		}
+		if deliveryOTP.VerifiedAt != nil {
+			http.Error(w, "The delivery code has already been used", http.StatusConflict)
+			return
+		}
+
+		code, err := otp.Generate(configs.GetDeliveryOTPConfig().Length)
+		if err == nil {
+			err = issueDeliveryOTP(db, consignmentID, code)
+		}
+		if err != nil {
+			log.Printf("Failed to issue delivery OTP: %v", err)
+			http.Error(w, "Failed to resend delivery code", http.StatusInternalServerError)
+			return
+		}
+		sendDeliveryOTP(notifier, order.RecipientPhone, consignmentID, code)
+
+		writeResponse(w, http.StatusOK, "Delivery code sent to the recipient.", nil)
+	}
+}
+
+// GetDeliverySettingsHandler returns a store's delivery OTP opt-in, defaulting to disabled
+func GetDeliverySettingsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		storeID, err := parseStoreID(r)
+		if err != nil {
+			http.Error(w, "Wrong Store selected", http.StatusNotFound)
+			return
+		}
+
+		// Extract userID from token
+		userID, err := GetUserIDFromToken(r, os.Getenv("JWT_SECRET"), db)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		settings := models.StoreDeliverySettings{UserID: userID, StoreID: storeID}
+		err = db.Get(&settings, `SELECT * FROM store_delivery_settings WHERE user_id = $1 AND store_id = $2`, userID, storeID)
+		if err != nil && err != sql.ErrNoRows {
+			log.Printf("Failed to fetch delivery settings: %v", err)
+			http.Error(w, "Failed to fetch delivery settings", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Delivery settings successfully fetched.", settings)
+	}
+}
+
+// UpdateDeliverySettingsHandler sets whether all of a store's parcels need a delivery code.
+// It applies to orders created afterwards.
+func UpdateDeliverySettingsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		storeID, err := parseStoreID(r)
+		if err != nil {
+			http.Error(w, "Wrong Store selected", http.StatusNotFound)
+			return
+		}
+
+		var req struct {
+			OTPEnabled bool `json:"otp_enabled"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		// Extract userID from token
+		userID, err := GetUserIDFromToken(r, os.Getenv("JWT_SECRET"), db)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var settings models.StoreDeliverySettings
+		err = db.Get(&settings, `
+			INSERT INTO store_delivery_settings (user_id, store_id, otp_enabled)
+			VALUES ($1, $2, $3)
+			ON CONFLICT (user_id, store_id) DO UPDATE
+			SET otp_enabled = EXCLUDED.otp_enabled, updated_at = NOW()
+			RETURNING *
+		`, userID, storeID, req.OTPEnabled)
+		if err != nil {
+			log.Printf("Failed to update delivery settings: %v", err)
+			http.Error(w, "Failed to update delivery settings", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Delivery settings updated successfully.", settings)
+	}
+}
@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"go-application-task/internal/models"
+	"go-application-task/pkg/export"
+	"log"
+	"net/http"
+	"os"
+	"time"
+)
+
+// orderExportRow is an order together with the time it reached each status
+type orderExportRow struct {
+	models.Order
+	CompletedAt sql.NullTime `db:"completed_at"`
+	CancelledAt sql.NullTime `db:"cancelled_at"`
+
+	ReturnConsignmentID sql.NullString  `db:"return_consignment_id"`
+	ReturnReason        sql.NullString  `db:"return_reason"`
+	ReturnStatus        sql.NullString  `db:"return_status"`
+	ReturnCharge        sql.NullFloat64 `db:"return_charge"`
+	ReturnedAt          sql.NullTime    `db:"returned_at"`
+}
+
+// orderExportColumns are the column headers of an order export, in the order values are written.
+// Every order column is exported except the fee breakdown, which is already split into its own columns,
+// and the promotion ID, which is exported as its promo code.
+var orderExportColumns = []string{
+	"consignment_id", "merchant_order_id", "store_id", "order_type", "parent_consignment_id",
+	"recipient_name", "recipient_phone", "recipient_address", "recipient_city", "recipient_zone", "recipient_area",
+	"delivery_type", "item_type", "item_quantity", "item_weight", "length", "width", "height", "volumetric_weight", "chargeable_weight", "item_description", "special_instruction",
+	"declared_value", "amount_to_collect", "collected_amount", "delivery_fee", "delivery_fee_discount", "promo_code", "surcharge", "cod_fee", "vat", "total_fee",
+	"order_status", "transfer_status", "transfer_status_name", "archive", "pickup_id", "rider_id", "assigned_at", "current_hub_id",
+	"created_at", "completed_at", "cancelled_at",
+	"return_consignment_id", "return_reason", "return_status", "return_charge", "returned_at",
+}
+
+// values returns the row values in the order of orderExportColumns
+func (row orderExportRow) values() []interface{} {
+	return []interface{}{
+		row.ConsignmentID, row.MerchantOrderID, row.StoreID, row.OrderType, row.ParentConsignmentID,
+		row.RecipientName, row.RecipientPhone, row.RecipientAddress, row.RecipientCity, row.RecipientZone, row.RecipientArea,
+		row.DeliveryType, row.ItemType, row.ItemQuantity, row.ItemWeight, row.Length, row.Width, row.Height, row.VolumetricWeight, row.ChargeableWeight, row.ItemDescription, row.SpecialInstruction,
+		row.DeclaredValue, row.AmountToCollect, row.CollectedAmount, row.DeliveryFee, row.DeliveryFeeDiscount, row.PromoCode, row.Surcharge, row.CODFee, row.VAT, row.DeliveryFee + row.Surcharge + row.CODFee,
+		row.OrderStatus, row.TransferStatus, models.TransferStatusNames[row.TransferStatus], row.Archive, row.PickupID, row.RiderID, row.AssignedAt, row.CurrentHubID,
+		row.OrderCreatedAt, nullTime(row.CompletedAt), nullTime(row.CancelledAt),
+		row.ReturnConsignmentID.String, row.ReturnReason.String, row.ReturnStatus.String, row.ReturnCharge.Float64, nullTime(row.ReturnedAt),
+	}
+}
+
+// nullTime converts a nullable timestamp to a value the export writers understand
+func nullTime(t sql.NullTime) interface{} {
+	if !t.Valid {
+		return nil
+	}
+	return t.Time
+}
+
+// statusTimeColumn selects the first time an order reached the given status
+func statusTimeColumn(status string) string {
+	return fmt.Sprintf(`(SELECT MIN(h.created_at) FROM order_status_history h WHERE h.consignment_id = orders.consignment_id AND h.status = '%s') AS %s_at`, status, status)
+}
+
+// ExportOrdersHandler streams the caller's orders as CSV or XLSX, accepting the same filters as order listing
+func ExportOrdersHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		format, err := export.GetFormat(r.URL.Query().Get("format"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Extract userID from token
+		userID, err := GetUserIDFromToken(r, os.Getenv("JWT_SECRET"), db)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		filter, err := parseOrderFilter(r, userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		where, args := filter.where()
+
+		query := `SELECT ` + orderColumns + `, ` + statusTimeColumn("completed") + `, ` + statusTimeColumn("cancelled") + `,
+				r.return_consignment_id, r.return_reason, r.return_status, r.return_charge, r.returned_at
+			FROM orders
+			LEFT JOIN LATERAL (
+				SELECT return_consignment_id, reason AS return_reason, status AS return_status, return_charge, returned_at
+				FROM order_returns WHERE order_returns.consignment_id = orders.consignment_id
+			) r ON TRUE
+			` + where + `
+			ORDER BY created_at DESC`
+
+		// Rows are read from the database and written to the client one at a time
+		rows, err := db.Queryx(query, args...)
+		if err != nil {
+			log.Printf("Database query error: %v", err)
+			http.Error(w, "Failed to export orders", http.StatusInternalServerError)
+			return
+		}
+		defer rows.Close()
+
+		filename := fmt.Sprintf("orders-%s.%s", time.Now().Format("20060102150405"), format.Extension)
+		w.Header().Set("Content-Type", format.ContentType)
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+		w.WriteHeader(http.StatusOK)
+
+		// Headers are already sent, so failures from here on can only be logged
+		writer := format.NewWriter(w)
+		if err := writer.WriteHeader(orderExportColumns); err != nil {
+			log.Printf("Failed to write export header: %v", err)
+			return
+		}
+		for rows.Next() {
+			var row orderExportRow
+			if err := rows.StructScan(&row); err != nil {
+				log.Printf("Row scan error: %v", err)
+				return
+			}
+			if err := writer.WriteRow(row.values()); err != nil {
+				log.Printf("Failed to write export row: %v", err)
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			log.Printf("Export rows error: %v", err)
+			return
+		}
+		if err := writer.Close(); err != nil {
+			log.Printf("Failed to finish export: %v", err)
+		}
+	}
+}
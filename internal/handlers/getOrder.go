@@ -1,7 +1,10 @@
 package handlers
 
 import (
+	"database/sql"
 	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
 	"github.com/jmoiron/sqlx"
 	"go-application-task/internal/models"
 	"log"
@@ -18,6 +21,27 @@ type Response struct {
 	Data    interface{} `json:"data"`
 }
 
+// writeResponse sends a success Response with the given status code
+func writeResponse(w http.ResponseWriter, code int, message string, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(code)
+	if err := json.NewEncoder(w).Encode(Response{Message: message, Type: "success", Code: code, Data: data}); err != nil {
+		log.Printf("Failed to encode response: %v", err)
+	}
+}
+
+// writeValidationErrors sends a 422 response listing the errors per field
+func writeValidationErrors(w http.ResponseWriter, errors map[string][]string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"message": "Please fix the given errors",
+		"type":    "error",
+		"code":    422,
+		"errors":  errors,
+	})
+}
+
 // PaginatedResponse struct to handle paginated data
 type PaginatedResponse struct {
 	Data        interface{} `json:"data"`
@@ -28,6 +52,156 @@ type PaginatedResponse struct {
 	LastPage    int         `json:"last_page"`
 }
 
+// parsePagination reads the page and limit query parameters and returns the page, page size and row offset
+func parsePagination(r *http.Request) (page, perPage, offset int) {
+	page, err := strconv.Atoi(r.URL.Query().Get("page"))
+	if err != nil || page < 1 {
+		page = 1 // default to page 1 if no valid page is provided
+	}
+	perPage, err = strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || perPage < 1 {
+		perPage = 10 // default to 10 per page
+	}
+
+	// Calculate offset for pagination
+	return page, perPage, (page - 1) * perPage
+}
+
+// newPaginatedResponse builds the pagination metadata for a page of count items out of total
+func newPaginatedResponse(data interface{}, count, total, page, perPage int) PaginatedResponse {
+	lastPage := (total / perPage)
+	if total%perPage > 0 {
+		lastPage++
+	}
+
+	return PaginatedResponse{
+		Data:        data,
+		Total:       total,
+		CurrentPage: page,
+		PerPage:     perPage,
+		TotalInPage: count,
+		LastPage:    lastPage,
+	}
+}
+
+// orderColumns lists the orders columns that map onto models.Order
+const orderColumns = `
+	store_id,
+	merchant_order_id,
+	recipient_name,
+	recipient_phone,
+	recipient_address,
+	recipient_city,
+	recipient_zone,
+	recipient_area,
+	delivery_type,
+	item_type,
+	transfer_status,
+	archive,
+	special_instruction,
+	item_quantity,
+	item_weight,
+	amount_to_collect,
+	item_description,
+	consignment_id,
+	order_status,
+	delivery_fee,
+	cod_fee,
+	user_id,
+	created_at,
+	order_type,
+	parent_consignment_id,
+	collected_amount,
+	declared_value,
+	length,
+	width,
+	height,
+	volumetric_weight,
+	chargeable_weight,
+	pickup_id,
+	rider_id,
+	assigned_at,
+	current_hub_id,
+	promotion_id,
+	promo_code,
+	delivery_fee_discount,
+	surcharge,
+	vat,
+	fee_breakdown`
+
+// getOrderItems fetches the items packed in an order
+func getOrderItems(db sqlx.Queryer, consignmentID string) ([]models.OrderItem, error) {
+	items := []models.OrderItem{}
+	err := sqlx.Select(db, &items, `SELECT * FROM order_items WHERE consignment_id = $1 ORDER BY id`, consignmentID)
+	return items, err
+}
+
+// getUserOrder fetches a single order by consignment ID, scoped to the given user
+func getUserOrder(db *sqlx.DB, consignmentID string, userID int) (models.Order, error) {
+	var order models.Order
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE consignment_id = $1 AND user_id = $2`
+	err := db.Get(&order, query, consignmentID, userID)
+	return order, err
+}
+
+// GetOrderHandler returns one of the caller's orders with its items, proof of delivery and cancellation reason
+func GetOrderHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		consignmentID := mux.Vars(r)["consignment_id"]
+
+		// Extract userID from token
+		userID, err := GetUserIDFromToken(r, os.Getenv("JWT_SECRET"), db)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		order, err := getUserOrder(db, consignmentID, userID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Order not found", http.StatusNotFound)
+			return
+		}
+		if err == nil {
+			order.Items, err = getOrderItems(db, consignmentID)
+		}
+		if err != nil {
+			log.Printf("Order retrieval error: %v", err)
+			http.Error(w, "Failed to fetch order", http.StatusInternalServerError)
+			return
+		}
+		order.TransferStatusName = models.TransferStatusNames[order.TransferStatus]
+
+		var proofOfDelivery *models.ProofOfDelivery
+		pod, err := getProofOfDelivery(db, consignmentID)
+		if err == nil {
+			proofOfDelivery = &pod
+		} else if err != sql.ErrNoRows {
+			log.Printf("Proof of delivery retrieval error: %v", err)
+			http.Error(w, "Failed to fetch order", http.StatusInternalServerError)
+			return
+		}
+
+		var cancellation *models.OrderCancellation
+		if order.OrderStatus == models.OrderStatusCancelled {
+			var c models.OrderCancellation
+			err = db.Get(&c, `SELECT * FROM order_cancellations WHERE consignment_id = $1`, consignmentID)
+			if err == nil {
+				cancellation = &c
+			} else if err != sql.ErrNoRows {
+				log.Printf("Cancellation retrieval error: %v", err)
+				http.Error(w, "Failed to fetch order", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		writeResponse(w, http.StatusOK, "Order successfully fetched.", struct {
+			models.Order
+			ProofOfDelivery *models.ProofOfDelivery   `json:"proof_of_delivery"`
+			Cancellation    *models.OrderCancellation `json:"cancellation,omitempty"`
+		}{order, proofOfDelivery, cancellation})
+	}
+}
+
 // ListOrdersHandler handles the fetching of orders with pagination
 func ListOrdersHandler(db *sqlx.DB) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
@@ -38,53 +212,27 @@ func ListOrdersHandler(db *sqlx.DB) http.HandlerFunc {
 			return
 		}
 
-		// Get pagination parameters from query params
-		page, err := strconv.Atoi(r.URL.Query().Get("page"))
-		if err != nil || page < 1 {
-			page = 1 // default to page 1 if no valid page is provided
-		}
-		perPage, err := strconv.Atoi(r.URL.Query().Get("limit"))
-		if err != nil || perPage < 1 {
-			perPage = 10 // default to 10 per page
+		filter, err := parseOrderFilter(r, userID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
 		}
+		where, args := filter.where()
+
+		// Get pagination parameters from query params
+		page, perPage, offset := parsePagination(r)
 
-		// Calculate offset for pagination
-		offset := (page - 1) * perPage
-
-		// Build SQL query with user ID filter and pagination
-		query := `
-			SELECT 
-				store_id, 
-				merchant_order_id, 
-				recipient_name, 
-				recipient_phone, 
-				recipient_address, 
-				recipient_city, 
-				recipient_zone, 
-				recipient_area, 
-				delivery_type, 
-				item_type, 
-				transfer_status, 
-				archive, 
-				special_instruction, 
-				item_quantity, 
-				item_weight, 
-				amount_to_collect, 
-				item_description, 
-				consignment_id, 
-				order_status, 
-				delivery_fee, 
-				cod_fee, 
-				user_id, 
-				created_at
+		// Build SQL query with the filters and pagination
+		query := fmt.Sprintf(`
+			SELECT `+orderColumns+`
 			FROM orders
-			WHERE user_id=$1 AND transfer_status = 1 AND archive = 0
+			%s
 			ORDER BY created_at DESC
-			LIMIT $2 OFFSET $3
-		`
+			LIMIT $%d OFFSET $%d
+		`, where, len(args)+1, len(args)+2)
 
 		// Execute query with pagination
-		rows, err := db.Queryx(query, userID, perPage, offset)
+		rows, err := db.Queryx(query, append(args, perPage, offset)...)
 		if err != nil {
 			log.Printf("Database query error: %v", err)
 			http.Error(w, "Failed to fetch orders", http.StatusInternalServerError)
@@ -101,35 +249,20 @@ func ListOrdersHandler(db *sqlx.DB) http.HandlerFunc {
 				http.Error(w, "Failed to process orders", http.StatusInternalServerError)
 				return
 			}
+			order.TransferStatusName = models.TransferStatusNames[order.TransferStatus]
 			orders = append(orders, order)
 		}
 
 		// Count total orders to calculate pagination metadata
 		var total int
-		err = db.Get(&total, `
-			SELECT COUNT(*) 
-			FROM orders
-			WHERE user_id=$1 AND transfer_status = 1 AND archive = 0
-		`, userID)
+		err = db.Get(&total, `SELECT COUNT(*) FROM orders `+where, args...)
 		if err != nil {
 			log.Printf("Error counting total orders: %v", err)
 			http.Error(w, "Failed to calculate pagination", http.StatusInternalServerError)
 			return
 		}
 
-		lastPage := (total / perPage)
-		if total%perPage > 0 {
-			lastPage++
-		}
-
-		paginatedResponse := PaginatedResponse{
-			Data:        orders,
-			Total:       total,
-			CurrentPage: page,
-			PerPage:     perPage,
-			TotalInPage: len(orders),
-			LastPage:    lastPage,
-		}
+		paginatedResponse := newPaginatedResponse(orders, len(orders), total, page, perPage)
 
 		response := Response{
 			Message: "Orders successfully fetched.",
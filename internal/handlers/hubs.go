@@ -0,0 +1,299 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go-application-task/internal/models"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// maxScanParcels is the most parcels a single scan or manifest may include
+const maxScanParcels = 500
+
+// getHub fetches a hub by ID
+func getHub(db sqlx.Queryer, hubID int) (models.Hub, error) {
+	var hub models.Hub
+	err := sqlx.Get(db, &hub, `SELECT * FROM hubs WHERE id = $1`, hubID)
+	return hub, err
+}
+
+// hubExists reports whether an active hub with the given code exists
+func hubExists(db sqlx.Queryer, code string) (bool, error) {
+	var exists bool
+	err := sqlx.Get(db, &exists, `SELECT EXISTS(SELECT 1 FROM hubs WHERE code = $1 AND active)`, code)
+	return exists, err
+}
+
+// lockScannedOrders locks the scanned orders and reports scanned consignment IDs that do not exist
+func lockScannedOrders(tx *sqlx.Tx, consignmentIDs []string) ([]models.Order, map[string][]string, error) {
+	var orders []models.Order
+	query := `SELECT ` + orderColumns + ` FROM orders WHERE consignment_id = ANY($1) ORDER BY consignment_id FOR UPDATE`
+	if err := tx.Select(&orders, query, pq.Array(consignmentIDs)); err != nil {
+		return nil, nil, err
+	}
+
+	errs := make(map[string][]string)
+	found := make(map[string]bool, len(orders))
+	for _, order := range orders {
+		found[order.ConsignmentID] = true
+	}
+	for _, consignmentID := range consignmentIDs {
+		if !found[consignmentID] {
+			field := "consignment_ids." + consignmentID
+			errs[field] = append(errs[field], "The order was not found.")
+		}
+	}
+	return orders, errs, nil
+}
+
+// recordHubScan moves a parcel to the given transfer status and hub and records the scan.
+// hubID is the scanning hub; the parcel is held by it unless it is leaving in transit.
+func recordHubScan(tx *sqlx.Tx, consignmentID string, hubID int, scanType string, manifestID *int, userID int) error {
+	transferStatus := models.TransferStatusAtHub
+	currentHubID := &hubID
+	if scanType == models.ScanTypeDispatch {
+		transferStatus, currentHubID = models.TransferStatusInTransit, nil
+	}
+
+	_, err := tx.Exec(`UPDATE orders SET transfer_status = $2, current_hub_id = $3 WHERE consignment_id = $1`, consignmentID, transferStatus, currentHubID)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(`
+		INSERT INTO hub_scans (consignment_id, hub_id, scan_type, manifest_id, scanned_by)
+		VALUES ($1, $2, $3, $4, $5)
+	`, consignmentID, hubID, scanType, manifestID, userID)
+	return err
+}
+
+// readScannedIDs decodes a list of scanned consignment IDs from the request body.
+// It writes the error response and returns false when the list is missing or too long.
+func readScannedIDs(w http.ResponseWriter, r *http.Request) ([]string, bool) {
+	var req struct {
+		ConsignmentIDs []string `json:"consignment_ids"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+		return nil, false
+	}
+	if len(req.ConsignmentIDs) == 0 {
+		writeValidationErrors(w, map[string][]string{"consignment_ids": {"At least one parcel is required."}})
+		return nil, false
+	}
+	if len(req.ConsignmentIDs) > maxScanParcels {
+		writeValidationErrors(w, map[string][]string{"consignment_ids": {fmt.Sprintf("At most %d parcels can be scanned at once.", maxScanParcels)}})
+		return nil, false
+	}
+	return req.ConsignmentIDs, true
+}
+
+// CreateHubHandler registers a sorting center or warehouse (ops only)
+func CreateHubHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Code    string  `json:"code"`
+			Name    string  `json:"name"`
+			HubType string  `json:"hub_type"`
+			ZoneID  int     `json:"zone_id"`
+			Address *string `json:"address"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := requireRole(w, r, db, models.RoleOps); !ok {
+			return
+		}
+
+		if req.HubType == "" {
+			req.HubType = models.HubTypeSortingCenter
+		}
+		errs := make(map[string][]string)
+		if req.Code == "" {
+			errs["code"] = append(errs["code"], "The code field is required.")
+		}
+		if req.Name == "" {
+			errs["name"] = append(errs["name"], "The name field is required.")
+		}
+		if req.HubType != models.HubTypeSortingCenter && req.HubType != models.HubTypeWarehouse {
+			errs["hub_type"] = append(errs["hub_type"], "The hub type must be sorting_center or warehouse.")
+		}
+		if req.ZoneID == 0 {
+			errs["zone_id"] = append(errs["zone_id"], "The zone field is required.")
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+
+		var hub models.Hub
+		err := db.Get(&hub, `
+			INSERT INTO hubs (code, name, hub_type, zone_id, address)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING *
+		`, req.Code, req.Name, req.HubType, req.ZoneID, req.Address)
+		if isUniqueViolation(err, "hubs_code_key") {
+			writeValidationErrors(w, map[string][]string{"code": {"The code has already been taken."}})
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to create hub: %v", err)
+			http.Error(w, "Failed to create hub", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusCreated, "Hub created successfully.", hub)
+	}
+}
+
+// ListHubsHandler lists hubs, optionally filtered by zone_id and hub_type (ops only)
+func ListHubsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := requireRole(w, r, db, models.RoleOps); !ok {
+			return
+		}
+
+		var zoneID int
+		if zone := r.URL.Query().Get("zone_id"); zone != "" {
+			id, err := strconv.Atoi(zone)
+			if err != nil {
+				http.Error(w, "invalid zone_id", http.StatusBadRequest)
+				return
+			}
+			zoneID = id
+		}
+		hubType := r.URL.Query().Get("hub_type")
+
+		hubs := []models.Hub{}
+		err := db.Select(&hubs, `
+			SELECT * FROM hubs
+			WHERE ($1 = 0 OR zone_id = $1) AND ($2 = '' OR hub_type = $2)
+			ORDER BY zone_id, code
+		`, zoneID, hubType)
+		if err != nil {
+			log.Printf("Failed to fetch hubs: %v", err)
+			http.Error(w, "Failed to fetch hubs", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Hubs successfully fetched.", hubs)
+	}
+}
+
+// HubParcelsHandler lists the parcels a hub currently holds (ops only)
+func HubParcelsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hubID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+		if _, ok := requireRole(w, r, db, models.RoleOps); !ok {
+			return
+		}
+
+		page, perPage, offset := parsePagination(r)
+		orders := []models.Order{}
+		err := db.Select(&orders, `
+			SELECT `+orderColumns+`
+			FROM orders
+			WHERE current_hub_id = $1 AND transfer_status = $2
+			ORDER BY consignment_id
+			LIMIT $3 OFFSET $4
+		`, hubID, models.TransferStatusAtHub, perPage, offset)
+		if err != nil {
+			log.Printf("Failed to fetch hub parcels: %v", err)
+			http.Error(w, "Failed to fetch parcels", http.StatusInternalServerError)
+			return
+		}
+		for i := range orders {
+			orders[i].TransferStatusName = models.TransferStatusNames[orders[i].TransferStatus]
+		}
+
+		var total int
+		err = db.Get(&total, `SELECT COUNT(*) FROM orders WHERE current_hub_id = $1 AND transfer_status = $2`, hubID, models.TransferStatusAtHub)
+		if err != nil {
+			log.Printf("Error counting hub parcels: %v", err)
+			http.Error(w, "Failed to calculate pagination", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Parcels successfully fetched.", newPaginatedResponse(orders, len(orders), total, page, perPage))
+	}
+}
+
+// HubInboundScanHandler records parcels collected from merchants arriving at a hub (ops only)
+func HubInboundScanHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		hubID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+		consignmentIDs, ok := readScannedIDs(w, r)
+		if !ok {
+			return
+		}
+
+		user, ok := requireRole(w, r, db, models.RoleOps)
+		if !ok {
+			return
+		}
+
+		hub, err := getHub(db, hubID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Hub not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Hub retrieval error: %v", err)
+			http.Error(w, "Failed to fetch hub", http.StatusInternalServerError)
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			log.Printf("Failed to start transaction: %v", err)
+			http.Error(w, "Failed to scan parcels", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		orders, errs, err := lockScannedOrders(tx, consignmentIDs)
+		if err != nil {
+			log.Printf("Order retrieval error: %v", err)
+			http.Error(w, "Failed to fetch orders", http.StatusInternalServerError)
+			return
+		}
+		for _, order := range orders {
+			if order.TransferStatus != models.TransferStatusAtMerchant {
+				field := "consignment_ids." + order.ConsignmentID
+				errs[field] = append(errs[field], fmt.Sprintf("The parcel is already %s.", models.TransferStatusNames[order.TransferStatus]))
+			}
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+
+		for _, order := range orders {
+			if err = recordHubScan(tx, order.ConsignmentID, hub.ID, models.ScanTypeInbound, nil, user.ID); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			log.Printf("Failed to scan parcels: %v", err)
+			http.Error(w, "Failed to scan parcels", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Parcels scanned successfully.", map[string]interface{}{
+			"hub":             hub,
+			"consignment_ids": consignmentIDs,
+		})
+	}
+}
@@ -0,0 +1,511 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"go-application-task/configs"
+	"go-application-task/internal/ledger"
+	"go-application-task/internal/models"
+	"go-application-task/pkg/invoice"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Number prefixes of issued documents
+const (
+	invoicePrefix    = "INV"
+	creditNotePrefix = "CN"
+)
+
+// roundMoney rounds an amount to whole poisha
+func roundMoney(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// nextDocumentNumber takes the next number for prefix in the current year, e.g. INV-2024-000042.
+// The sequence row stays locked until tx ends, so numbers are issued without gaps.
+func nextDocumentNumber(tx *sqlx.Tx, prefix string, issuedAt time.Time) (string, error) {
+	var number int
+	err := tx.Get(&number, `
+		INSERT INTO invoice_sequences (prefix, year, last_number)
+		VALUES ($1, $2, 1)
+		ON CONFLICT (prefix, year) DO UPDATE SET last_number = invoice_sequences.last_number + 1
+		RETURNING last_number
+	`, prefix, issuedAt.Year())
+	return fmt.Sprintf("%s-%d-%06d", prefix, issuedAt.Year(), number), err
+}
+
+// applyVAT splits each line's VAT inclusive charges into net and VAT and totals the document
+func applyVAT(doc *models.Invoice) {
+	doc.Subtotal, doc.VAT, doc.Total = 0, 0, 0
+	for i := range doc.Lines {
+		line := &doc.Lines[i]
+		line.Total = roundMoney(line.DeliveryFee + line.Surcharge + line.CODFee + line.ReturnCharge)
+		line.VAT = roundMoney(line.Total * doc.VATPercent / (100 + doc.VATPercent))
+		line.Net = roundMoney(line.Total - line.VAT)
+		doc.Subtotal += line.Net
+		doc.VAT += line.VAT
+		doc.Total += line.Total
+	}
+	doc.Subtotal, doc.VAT, doc.Total = roundMoney(doc.Subtotal), roundMoney(doc.VAT), roundMoney(doc.Total)
+}
+
+// insertInvoice numbers and stores an invoice or credit note with its lines
+func insertInvoice(tx *sqlx.Tx, doc *models.Invoice) error {
+	prefix := invoicePrefix
+	if doc.Kind == models.InvoiceKindCreditNote {
+		prefix = creditNotePrefix
+	}
+	doc.IssuedAt = time.Now()
+	number, err := nextDocumentNumber(tx, prefix, doc.IssuedAt)
+	if err != nil {
+		return err
+	}
+	doc.Number = number
+
+	lines := doc.Lines
+	err = tx.Get(doc, `
+		INSERT INTO invoices (number, kind, user_id, payout_id, original_invoice_id, period_start, period_end, vat_percent, subtotal, vat, total, reason, issued_by, issued_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING *
+	`, doc.Number, doc.Kind, doc.UserID, doc.PayoutID, doc.OriginalInvoiceID, doc.PeriodStart, doc.PeriodEnd, doc.VATPercent, doc.Subtotal, doc.VAT, doc.Total, doc.Reason, doc.IssuedBy, doc.IssuedAt)
+	if err != nil {
+		return err
+	}
+
+	doc.Lines = lines
+	for i := range doc.Lines {
+		line := &doc.Lines[i]
+		line.InvoiceID = doc.ID
+		err = tx.Get(&line.ID, `
+			INSERT INTO invoice_lines (invoice_id, consignment_id, delivery_fee, surcharge, cod_fee, return_charge, net, vat, total)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			RETURNING id
+		`, line.InvoiceID, line.ConsignmentID, line.DeliveryFee, line.Surcharge, line.CODFee, line.ReturnCharge, line.Net, line.VAT, line.Total)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getInvoice fetches an invoice with its lines, scoped to userID unless it is 0
+func getInvoice(db sqlx.Queryer, invoiceID, userID int) (models.Invoice, error) {
+	var doc models.Invoice
+	err := sqlx.Get(db, &doc, `SELECT * FROM invoices WHERE id = $1 AND ($2 = 0 OR user_id = $2)`, invoiceID, userID)
+	if err != nil {
+		return doc, err
+	}
+	doc.Lines = []models.InvoiceLine{}
+	err = sqlx.Select(db, &doc.Lines, `SELECT * FROM invoice_lines WHERE invoice_id = $1 ORDER BY id`, invoiceID)
+	return doc, err
+}
+
+// CreateInvoiceHandler issues the invoice for the fees of the orders settled in a payout (ops only)
+func CreateInvoiceHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			PayoutID int `json:"payout_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if req.PayoutID == 0 {
+			writeValidationErrors(w, map[string][]string{"payout_id": {"The payout field is required."}})
+			return
+		}
+
+		user, ok := requireRole(w, r, db, models.RoleOps)
+		if !ok {
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			log.Printf("Failed to start transaction: %v", err)
+			http.Error(w, "Failed to issue invoice", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		var payout models.Payout
+		err = tx.Get(&payout, `SELECT * FROM payouts WHERE id = $1 FOR UPDATE`, req.PayoutID)
+		if err == sql.ErrNoRows {
+			writeValidationErrors(w, map[string][]string{"payout_id": {"The payout was not found."}})
+			return
+		}
+		var invoiced bool
+		if err == nil {
+			err = tx.Get(&invoiced, `SELECT EXISTS(SELECT 1 FROM invoices WHERE payout_id = $1 AND kind = $2)`, payout.ID, models.InvoiceKindInvoice)
+		}
+		if err == nil && invoiced {
+			http.Error(w, "The payout has already been invoiced", http.StatusConflict)
+			return
+		}
+
+		doc := models.Invoice{
+			Kind:       models.InvoiceKindInvoice,
+			UserID:     payout.UserID,
+			PayoutID:   &payout.ID,
+			PeriodEnd:  payout.PeriodEnd,
+			VATPercent: configs.GetInvoiceConfig().VATPercent,
+			IssuedBy:   &user.ID,
+		}
+		var payoutLines []models.PayoutLine
+		if err == nil {
+			payoutLines, err = getPayoutLines(tx, payout.ID)
+		}
+		if err == nil {
+			err = tx.Get(&doc.PeriodStart, `SELECT COALESCE(MIN(created_at), $2) FROM ledger_transactions WHERE payout_id = $1`, payout.ID, payout.PeriodEnd)
+		}
+		if err != nil {
+			log.Printf("Payout retrieval error: %v", err)
+			http.Error(w, "Failed to issue invoice", http.StatusInternalServerError)
+			return
+		}
+
+		for _, payoutLine := range payoutLines {
+			if payoutLine.DeliveryFee == 0 && payoutLine.Surcharge == 0 && payoutLine.CODFee == 0 && payoutLine.ReturnCharge == 0 {
+				continue
+			}
+			doc.Lines = append(doc.Lines, models.InvoiceLine{
+				ConsignmentID: payoutLine.ConsignmentID,
+				DeliveryFee:   payoutLine.DeliveryFee,
+				Surcharge:     payoutLine.Surcharge,
+				CODFee:        payoutLine.CODFee,
+				ReturnCharge:  payoutLine.ReturnCharge,
+			})
+		}
+		if len(doc.Lines) == 0 {
+			http.Error(w, "The payout has no charges to invoice", http.StatusConflict)
+			return
+		}
+		applyVAT(&doc)
+
+		err = insertInvoice(tx, &doc)
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			log.Printf("Failed to issue invoice: %v", err)
+			http.Error(w, "Failed to issue invoice", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusCreated, "Invoice issued successfully.", doc)
+	}
+}
+
+// CreateCreditNoteHandler corrects an issued invoice by crediting charges back to the merchant (ops only).
+// Without lines every charge left on the invoice is credited. The credit is paid out with the merchant's next payout.
+func CreateCreditNoteHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		invoiceID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+		var req struct {
+			Reason string `json:"reason"`
+			Lines  []struct {
+				ConsignmentID string  `json:"consignment_id"`
+				DeliveryFee   float64 `json:"delivery_fee"`
+				Surcharge     float64 `json:"surcharge"`
+				CODFee        float64 `json:"cod_fee"`
+				ReturnCharge  float64 `json:"return_charge"`
+			} `json:"lines"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if req.Reason == "" {
+			writeValidationErrors(w, map[string][]string{"reason": {"The reason field is required."}})
+			return
+		}
+
+		user, ok := requireRole(w, r, db, models.RoleOps)
+		if !ok {
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			log.Printf("Failed to start transaction: %v", err)
+			http.Error(w, "Failed to issue credit note", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		// Lock the invoice so concurrent credit notes cannot credit more than was invoiced
+		var original models.Invoice
+		err = tx.Get(&original, `SELECT * FROM invoices WHERE id = $1 FOR UPDATE`, invoiceID)
+		if err == sql.ErrNoRows || (err == nil && original.Kind != models.InvoiceKindInvoice) {
+			http.Error(w, "Invoice not found", http.StatusNotFound)
+			return
+		}
+
+		// What is left to credit per consignment after earlier credit notes
+		var remaining []models.InvoiceLine
+		if err == nil {
+			err = tx.Select(&remaining, `
+				SELECT l.consignment_id,
+				       l.delivery_fee - COALESCE(c.delivery_fee, 0) AS delivery_fee,
+				       l.surcharge - COALESCE(c.surcharge, 0) AS surcharge,
+				       l.cod_fee - COALESCE(c.cod_fee, 0) AS cod_fee,
+				       l.return_charge - COALESCE(c.return_charge, 0) AS return_charge
+				FROM invoice_lines l
+				LEFT JOIN (
+					SELECT cl.consignment_id, SUM(cl.delivery_fee) AS delivery_fee, SUM(cl.surcharge) AS surcharge, SUM(cl.cod_fee) AS cod_fee, SUM(cl.return_charge) AS return_charge
+					FROM invoice_lines cl
+					JOIN invoices cn ON cn.id = cl.invoice_id
+					WHERE cn.original_invoice_id = $1
+					GROUP BY cl.consignment_id
+				) c ON c.consignment_id = l.consignment_id
+				WHERE l.invoice_id = $1
+				ORDER BY l.id
+			`, original.ID)
+		}
+		if err != nil {
+			log.Printf("Invoice retrieval error: %v", err)
+			http.Error(w, "Failed to issue credit note", http.StatusInternalServerError)
+			return
+		}
+
+		doc := models.Invoice{
+			Kind:              models.InvoiceKindCreditNote,
+			UserID:            original.UserID,
+			OriginalInvoiceID: &original.ID,
+			PeriodStart:       original.PeriodStart,
+			PeriodEnd:         original.PeriodEnd,
+			VATPercent:        original.VATPercent,
+			Reason:            &req.Reason,
+			IssuedBy:          &user.ID,
+		}
+		if len(req.Lines) == 0 {
+			for _, line := range remaining {
+				if line.DeliveryFee > 0 || line.Surcharge > 0 || line.CODFee > 0 || line.ReturnCharge > 0 {
+					doc.Lines = append(doc.Lines, line)
+				}
+			}
+		}
+
+		byID := make(map[string]models.InvoiceLine, len(remaining))
+		for _, line := range remaining {
+			byID[line.ConsignmentID] = line
+		}
+		errs := make(map[string][]string)
+		for i, line := range req.Lines {
+			field := fmt.Sprintf("lines.%d", i)
+			left, ok := byID[line.ConsignmentID]
+			if !ok {
+				errs[field+".consignment_id"] = append(errs[field+".consignment_id"], "The consignment is not on the invoice.")
+				continue
+			}
+			amounts := []struct {
+				name         string
+				value, limit float64
+			}{
+				{"delivery_fee", line.DeliveryFee, left.DeliveryFee},
+				{"surcharge", line.Surcharge, left.Surcharge},
+				{"cod_fee", line.CODFee, left.CODFee},
+				{"return_charge", line.ReturnCharge, left.ReturnCharge},
+			}
+			for _, amount := range amounts {
+				if amount.value < 0 || roundMoney(amount.value) > roundMoney(amount.limit) {
+					errs[field+"."+amount.name] = append(errs[field+"."+amount.name], fmt.Sprintf("The credit must be between 0 and %.2f.", amount.limit))
+				}
+			}
+			credited := models.InvoiceLine{
+				ConsignmentID: line.ConsignmentID,
+				DeliveryFee:   roundMoney(line.DeliveryFee),
+				Surcharge:     roundMoney(line.Surcharge),
+				CODFee:        roundMoney(line.CODFee),
+				ReturnCharge:  roundMoney(line.ReturnCharge),
+			}
+			doc.Lines = append(doc.Lines, credited)
+
+			// A consignment listed twice may not be credited more than is left in total
+			left.DeliveryFee -= credited.DeliveryFee
+			left.Surcharge -= credited.Surcharge
+			left.CODFee -= credited.CODFee
+			left.ReturnCharge -= credited.ReturnCharge
+			byID[line.ConsignmentID] = left
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+		applyVAT(&doc)
+		if doc.Total <= 0 {
+			http.Error(w, "There is nothing left to credit on the invoice", http.StatusConflict)
+			return
+		}
+
+		err = insertInvoice(tx, &doc)
+		if err == nil {
+			err = ledger.Post(tx, ledger.Posting{
+				EntryType: models.EntryFeeCredit,
+				UserID:    doc.UserID,
+				Debit:     models.AccountFeeRevenue,
+				Credit:    models.AccountMerchantPayable,
+				Amount:    doc.Total,
+			})
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			log.Printf("Failed to issue credit note: %v", err)
+			http.Error(w, "Failed to issue credit note", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusCreated, "Credit note issued successfully.", doc)
+	}
+}
+
+// ListInvoicesHandler lists the caller's invoices and credit notes, newest first.
+// Ops see every merchant's and may filter by user_id; anyone may filter by kind.
+func ListInvoicesHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := requireRole(w, r, db, models.RoleMerchant, models.RoleOps)
+		if !ok {
+			return
+		}
+		userID := listScope(r, user)
+		kind := r.URL.Query().Get("kind")
+
+		page, perPage, offset := parsePagination(r)
+		invoices := []models.Invoice{}
+		err := db.Select(&invoices, `
+			SELECT * FROM invoices
+			WHERE ($1 = 0 OR user_id = $1) AND ($2 = '' OR kind = $2)
+			ORDER BY id DESC
+			LIMIT $3 OFFSET $4
+		`, userID, kind, perPage, offset)
+		if err != nil {
+			log.Printf("Failed to fetch invoices: %v", err)
+			http.Error(w, "Failed to fetch invoices", http.StatusInternalServerError)
+			return
+		}
+
+		var total int
+		err = db.Get(&total, `SELECT COUNT(*) FROM invoices WHERE ($1 = 0 OR user_id = $1) AND ($2 = '' OR kind = $2)`, userID, kind)
+		if err != nil {
+			log.Printf("Error counting invoices: %v", err)
+			http.Error(w, "Failed to calculate pagination", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Invoices successfully fetched.", newPaginatedResponse(invoices, len(invoices), total, page, perPage))
+	}
+}
+
+// GetInvoiceHandler returns an invoice or credit note with its lines
+func GetInvoiceHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		invoiceID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+		user, ok := requireRole(w, r, db, models.RoleMerchant, models.RoleOps)
+		if !ok {
+			return
+		}
+
+		doc, err := getInvoice(db, invoiceID, ownerScope(user))
+		if err == sql.ErrNoRows {
+			http.Error(w, "Invoice not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Invoice retrieval error: %v", err)
+			http.Error(w, "Failed to fetch invoice", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Invoice successfully fetched.", doc)
+	}
+}
+
+// InvoicePDFHandler renders an invoice or credit note as PDF
+func InvoicePDFHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		invoiceID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+		user, ok := requireRole(w, r, db, models.RoleMerchant, models.RoleOps)
+		if !ok {
+			return
+		}
+
+		doc, err := getInvoice(db, invoiceID, ownerScope(user))
+		if err == sql.ErrNoRows {
+			http.Error(w, "Invoice not found", http.StatusNotFound)
+			return
+		}
+		var email, reference string
+		if err == nil {
+			err = db.Get(&email, `SELECT email FROM users WHERE id = $1`, doc.UserID)
+		}
+		if err == nil && doc.OriginalInvoiceID != nil {
+			err = db.Get(&reference, `SELECT number FROM invoices WHERE id = $1`, *doc.OriginalInvoiceID)
+		}
+		if err != nil {
+			log.Printf("Invoice retrieval error: %v", err)
+			http.Error(w, "Failed to fetch invoice", http.StatusInternalServerError)
+			return
+		}
+
+		cfg := configs.GetInvoiceConfig()
+		pdfDoc := invoice.Document{
+			Title:       "INVOICE",
+			Number:      doc.Number,
+			Reference:   reference,
+			IssuedAt:    doc.IssuedAt,
+			PeriodStart: doc.PeriodStart,
+			PeriodEnd:   doc.PeriodEnd,
+			Issuer:      invoice.Party{Name: cfg.CompanyName, Address: cfg.CompanyAddress, VATRegistration: cfg.VATRegistration},
+			Customer:    invoice.Party{Name: fmt.Sprintf("Merchant #%d", doc.UserID), Address: email},
+			VATPercent:  doc.VATPercent,
+			Subtotal:    doc.Subtotal,
+			VAT:         doc.VAT,
+			Total:       doc.Total,
+		}
+		if doc.Kind == models.InvoiceKindCreditNote {
+			pdfDoc.Title = "CREDIT NOTE"
+		}
+		if doc.Reason != nil {
+			pdfDoc.Reason = *doc.Reason
+		}
+		for _, line := range doc.Lines {
+			pdfDoc.Lines = append(pdfDoc.Lines, invoice.Line{
+				ConsignmentID: line.ConsignmentID,
+				DeliveryFee:   line.DeliveryFee,
+				Surcharge:     line.Surcharge,
+				CODFee:        line.CODFee,
+				ReturnCharge:  line.ReturnCharge,
+				Net:           line.Net,
+				VAT:           line.VAT,
+				Total:         line.Total,
+			})
+		}
+
+		// Render into a buffer first so that rendering errors can still produce an error response
+		var buf bytes.Buffer
+		if err := invoice.Render(&buf, pdfDoc); err != nil {
+			log.Printf("Invoice rendering error: %v", err)
+			http.Error(w, "Failed to render invoice", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "application/pdf")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", doc.Number+".pdf"))
+		w.WriteHeader(http.StatusOK)
+		if _, err := buf.WriteTo(w); err != nil {
+			log.Printf("Failed to write invoice: %v", err)
+		}
+	}
+}
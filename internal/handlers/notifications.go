@@ -0,0 +1,131 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"go-application-task/internal/models"
+	"go-application-task/internal/notification"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+// parseStoreID reads and validates the store_id path parameter
+func parseStoreID(r *http.Request) (int, error) {
+	storeID, err := strconv.Atoi(mux.Vars(r)["store_id"])
+	if err != nil || storeID != ValidStoreID {
+		return 0, fmt.Errorf("wrong store selected")
+	}
+	return storeID, nil
+}
+
+// GetNotificationSettingsHandler returns a store's notification opt-in, defaulting to disabled
+func GetNotificationSettingsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		storeID, err := parseStoreID(r)
+		if err != nil {
+			http.Error(w, "Wrong Store selected", http.StatusNotFound)
+			return
+		}
+
+		// Extract userID from token
+		userID, err := GetUserIDFromToken(r, os.Getenv("JWT_SECRET"), db)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		settings := models.StoreNotificationSettings{UserID: userID, StoreID: storeID, Language: models.LanguageEnglish}
+		err = db.Get(&settings, `SELECT * FROM store_notification_settings WHERE user_id = $1 AND store_id = $2`, userID, storeID)
+		if err != nil && err != sql.ErrNoRows {
+			log.Printf("Failed to fetch notification settings: %v", err)
+			http.Error(w, "Failed to fetch notification settings", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Notification settings successfully fetched.", settings)
+	}
+}
+
+// UpdateNotificationSettingsHandler sets whether a store's recipients get SMS notifications and in which language
+func UpdateNotificationSettingsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		storeID, err := parseStoreID(r)
+		if err != nil {
+			http.Error(w, "Wrong Store selected", http.StatusNotFound)
+			return
+		}
+
+		var req struct {
+			SMSEnabled bool   `json:"sms_enabled"`
+			Language   string `json:"language"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if req.Language == "" {
+			req.Language = models.LanguageEnglish
+		}
+		if !notification.IsLanguage(req.Language) {
+			writeValidationErrors(w, map[string][]string{"language": {"The language must be en or bn."}})
+			return
+		}
+
+		// Extract userID from token
+		userID, err := GetUserIDFromToken(r, os.Getenv("JWT_SECRET"), db)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var settings models.StoreNotificationSettings
+		err = db.Get(&settings, `
+			INSERT INTO store_notification_settings (user_id, store_id, sms_enabled, language)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (user_id, store_id) DO UPDATE
+			SET sms_enabled = EXCLUDED.sms_enabled, language = EXCLUDED.language, updated_at = NOW()
+			RETURNING *
+		`, userID, storeID, req.SMSEnabled, req.Language)
+		if err != nil {
+			log.Printf("Failed to update notification settings: %v", err)
+			http.Error(w, "Failed to update notification settings", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Notification settings updated successfully.", settings)
+	}
+}
+
+// ListOrderNotificationsHandler lists the messages sent to an order's recipient with their delivery status
+func ListOrderNotificationsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		consignmentID := mux.Vars(r)["consignment_id"]
+
+		// Extract userID from token
+		userID, err := GetUserIDFromToken(r, os.Getenv("JWT_SECRET"), db)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		notifications := []models.Notification{}
+		err = db.Select(&notifications, `
+			SELECT n.* FROM notifications n
+			JOIN orders o ON o.consignment_id = n.consignment_id
+			WHERE n.consignment_id = $1 AND o.user_id = $2
+			ORDER BY n.id
+		`, consignmentID, userID)
+		if err != nil {
+			log.Printf("Failed to fetch notifications: %v", err)
+			http.Error(w, "Failed to fetch notifications", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Notifications successfully fetched.", notifications)
+	}
+}
@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go-application-task/internal/models"
+	"go-application-task/pkg/storage"
+	"log"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// maxCommentUploadSize caps the size of a comment upload, all attachments included
+const maxCommentUploadSize = 10 << 20
+
+// maxCommentAttachments is the most files a single comment may carry
+const maxCommentAttachments = 5
+
+// attachmentTypes maps the accepted attachment content types to the extension they are stored with
+var attachmentTypes = map[string]string{
+	"image/jpeg":      ".jpg",
+	"image/png":       ".png",
+	"application/pdf": ".pdf",
+}
+
+// commentAttachmentURL is where a comment attachment can be downloaded
+func commentAttachmentURL(consignmentID string, commentID, attachmentID int) string {
+	return fmt.Sprintf("/orders/%s/comments/%d/attachments/%d", consignmentID, commentID, attachmentID)
+}
+
+// commentVisibilities returns the comment visibilities a user may see: everything for ops, merchant comments otherwise
+func commentVisibilities(user models.User) []string {
+	if user.Role == models.RoleOps {
+		return []string{models.CommentVisibilityMerchant, models.CommentVisibilityInternal}
+	}
+	return []string{models.CommentVisibilityMerchant}
+}
+
+// checkCommentOrder reports whether an order exists and its comment thread is open to the user.
+// It writes the error response and returns false when it is not.
+func checkCommentOrder(w http.ResponseWriter, db sqlx.Queryer, consignmentID string, user models.User) bool {
+	var exists bool
+	err := sqlx.Get(db, &exists, `SELECT EXISTS(SELECT 1 FROM orders WHERE consignment_id = $1 AND ($2 = 0 OR user_id = $2))`, consignmentID, ownerScope(user))
+	if err != nil {
+		log.Printf("Order retrieval error: %v", err)
+		http.Error(w, "Failed to fetch order", http.StatusInternalServerError)
+		return false
+	}
+	if !exists {
+		http.Error(w, "Order not found", http.StatusNotFound)
+		return false
+	}
+	return true
+}
+
+// loadCommentAttachments fills in the attachments of each comment
+func loadCommentAttachments(db sqlx.Queryer, comments []models.OrderComment) error {
+	ids := make([]int64, len(comments))
+	byID := make(map[int]*models.OrderComment, len(comments))
+	for i := range comments {
+		comments[i].Attachments = []models.CommentAttachment{}
+		ids[i] = int64(comments[i].ID)
+		byID[comments[i].ID] = &comments[i]
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var attachments []models.CommentAttachment
+	err := sqlx.Select(db, &attachments, `SELECT * FROM order_comment_attachments WHERE comment_id = ANY($1) ORDER BY id`, pq.Array(ids))
+	if err != nil {
+		return err
+	}
+	for _, attachment := range attachments {
+		comment := byID[attachment.CommentID]
+		attachment.URL = commentAttachmentURL(comment.ConsignmentID, comment.ID, attachment.ID)
+		comment.Attachments = append(comment.Attachments, attachment)
+	}
+	return nil
+}
+
+// CreateOrderCommentHandler adds a comment to an order's thread, by the order's merchant or by ops.
+// It takes JSON, or a multipart form when files are attached, with body, visibility and up to 5 JPEG, PNG or PDF
+// files under attachments. Merchants always comment visibly to both sides; ops comments are internal unless
+// visibility is merchant.
+func CreateOrderCommentHandler(db *sqlx.DB, store storage.BlobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		consignmentID := mux.Vars(r)["consignment_id"]
+
+		user, ok := requireRole(w, r, db, models.RoleMerchant, models.RoleOps)
+		if !ok {
+			return
+		}
+
+		var req struct {
+			Body       string `json:"body"`
+			Visibility string `json:"visibility"`
+		}
+		var files []*multipart.FileHeader
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+			r.Body = http.MaxBytesReader(w, r.Body, maxCommentUploadSize)
+			if err := r.ParseMultipartForm(maxCommentUploadSize); err != nil {
+				http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+				return
+			}
+			defer r.MultipartForm.RemoveAll()
+			req.Body, req.Visibility = r.FormValue("body"), r.FormValue("visibility")
+			files = r.MultipartForm.File["attachments"]
+		} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		errs := make(map[string][]string)
+		req.Body = strings.TrimSpace(req.Body)
+		if req.Body == "" {
+			errs["body"] = append(errs["body"], "The body field is required.")
+		}
+		switch {
+		case user.Role != models.RoleOps:
+			if req.Visibility == models.CommentVisibilityInternal {
+				errs["visibility"] = append(errs["visibility"], "Only ops can leave internal comments.")
+			}
+			req.Visibility = models.CommentVisibilityMerchant
+		case req.Visibility == "":
+			req.Visibility = models.CommentVisibilityInternal
+		case req.Visibility != models.CommentVisibilityMerchant && req.Visibility != models.CommentVisibilityInternal:
+			errs["visibility"] = append(errs["visibility"], "The visibility must be merchant or internal.")
+		}
+		if len(files) > maxCommentAttachments {
+			errs["attachments"] = append(errs["attachments"], fmt.Sprintf("A comment may have at most %d attachments.", maxCommentAttachments))
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+
+		if !checkCommentOrder(w, db, consignmentID, user) {
+			return
+		}
+
+		// Store the files before the comment so a failed upload leaves no comment without its attachments
+		attachments := make([]models.CommentAttachment, 0, len(files))
+		for i, header := range files {
+			file, err := header.Open()
+			if err != nil {
+				http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+				return
+			}
+			key, err := storeFile(r.Context(), store, file, fmt.Sprintf("comments/%s/attachment", consignmentID), attachmentTypes)
+			file.Close()
+			if err == errUnsupportedType {
+				field := fmt.Sprintf("attachments.%d", i)
+				writeValidationErrors(w, map[string][]string{field: {"The attachment must be a JPEG, PNG or PDF file."}})
+				return
+			}
+			if err != nil {
+				log.Printf("Failed to store comment attachment: %v", err)
+				http.Error(w, "Failed to store attachment", http.StatusInternalServerError)
+				return
+			}
+			name := path.Base(strings.ReplaceAll(header.Filename, "\\", "/"))
+			if len(name) > 255 {
+				name = name[len(name)-255:]
+			}
+			attachments = append(attachments, models.CommentAttachment{FileKey: key, FileName: name, Size: header.Size})
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			log.Printf("Failed to start transaction: %v", err)
+			http.Error(w, "Failed to add comment", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		comment := models.OrderComment{AuthorEmail: &user.Email}
+		err = tx.Get(&comment, `
+			INSERT INTO order_comments (consignment_id, user_id, author_role, visibility, body)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING *
+		`, consignmentID, user.ID, user.Role, req.Visibility, req.Body)
+		for i := range attachments {
+			if err != nil {
+				break
+			}
+			attachment := &attachments[i]
+			attachment.CommentID = comment.ID
+			err = tx.Get(attachment, `
+				INSERT INTO order_comment_attachments (comment_id, file_key, file_name, size)
+				VALUES ($1, $2, $3, $4)
+				RETURNING *
+			`, attachment.CommentID, attachment.FileKey, attachment.FileName, attachment.Size)
+			attachment.URL = commentAttachmentURL(consignmentID, comment.ID, attachment.ID)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			log.Printf("Failed to add comment: %v", err)
+			http.Error(w, "Failed to add comment", http.StatusInternalServerError)
+			return
+		}
+		comment.Attachments = attachments
+
+		writeResponse(w, http.StatusCreated, "Comment added successfully.", comment)
+	}
+}
+
+// ListOrderCommentsHandler lists an order's comment thread, oldest first.
+// Merchants see the comments visible to them, ops also see internal ones.
+func ListOrderCommentsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		consignmentID := mux.Vars(r)["consignment_id"]
+
+		user, ok := requireRole(w, r, db, models.RoleMerchant, models.RoleOps)
+		if !ok {
+			return
+		}
+		if !checkCommentOrder(w, db, consignmentID, user) {
+			return
+		}
+		visibilities := pq.Array(commentVisibilities(user))
+
+		page, perPage, offset := parsePagination(r)
+		comments := []models.OrderComment{}
+		err := db.Select(&comments, `
+			SELECT c.*, u.email AS author_email
+			FROM order_comments c
+			LEFT JOIN users u ON u.id = c.user_id
+			WHERE c.consignment_id = $1 AND c.visibility = ANY($2)
+			ORDER BY c.id
+			LIMIT $3 OFFSET $4
+		`, consignmentID, visibilities, perPage, offset)
+		if err == nil {
+			err = loadCommentAttachments(db, comments)
+		}
+		if err != nil {
+			log.Printf("Failed to fetch comments: %v", err)
+			http.Error(w, "Failed to fetch comments", http.StatusInternalServerError)
+			return
+		}
+
+		var total int
+		err = db.Get(&total, `SELECT COUNT(*) FROM order_comments WHERE consignment_id = $1 AND visibility = ANY($2)`, consignmentID, visibilities)
+		if err != nil {
+			log.Printf("Error counting comments: %v", err)
+			http.Error(w, "Failed to calculate pagination", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Comments successfully fetched.", newPaginatedResponse(comments, len(comments), total, page, perPage))
+	}
+}
+
+// CommentAttachmentFileHandler serves a comment attachment to those who can see the comment
+func CommentAttachmentFileHandler(db *sqlx.DB, store storage.BlobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		consignmentID := mux.Vars(r)["consignment_id"]
+		commentID, _ := strconv.Atoi(mux.Vars(r)["comment_id"])
+		attachmentID, _ := strconv.Atoi(mux.Vars(r)["attachment_id"])
+
+		user, ok := requireRole(w, r, db, models.RoleMerchant, models.RoleOps)
+		if !ok {
+			return
+		}
+
+		var attachment models.CommentAttachment
+		err := db.Get(&attachment, `
+			SELECT a.*
+			FROM order_comment_attachments a
+			JOIN order_comments c ON c.id = a.comment_id
+			JOIN orders o ON o.consignment_id = c.consignment_id
+			WHERE a.id = $1 AND a.comment_id = $2 AND c.consignment_id = $3
+			  AND c.visibility = ANY($4) AND ($5 = 0 OR o.user_id = $5)
+		`, attachmentID, commentID, consignmentID, pq.Array(commentVisibilities(user)), ownerScope(user))
+		if err == sql.ErrNoRows {
+			http.Error(w, "Attachment not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Attachment retrieval error: %v", err)
+			http.Error(w, "Failed to fetch attachment", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", attachment.FileName))
+		serveFile(w, r, store, attachment.FileKey, "Attachment not found")
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"fmt"
+	"go-application-task/internal/models"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// orderFilter holds the filters shared by order listing and export
+type orderFilter struct {
+	UserID        int
+	OrderStatus   string
+	StoreID       int
+	ConsignmentID string
+	From          *time.Time
+	To            *time.Time
+	Returns       string
+	// TransferStatus is 0 when parcels are listed wherever they are
+	TransferStatus int
+}
+
+// How returned parcels are treated by the order filter
+const (
+	returnsExclude = "exclude" // default, returned parcels are listed separately
+	returnsOnly    = "only"
+	returnsInclude = "include"
+)
+
+// parseOrderFilter reads the order filters from query parameters.
+// from and to are inclusive dates in YYYY-MM-DD format. Returned parcels are left out unless
+// returns=only or returns=include is given, or an order_status is asked for explicitly.
+func parseOrderFilter(r *http.Request, userID int) (orderFilter, error) {
+	q := r.URL.Query()
+	filter := orderFilter{
+		UserID:        userID,
+		OrderStatus:   q.Get("order_status"),
+		ConsignmentID: q.Get("consignment_id"),
+		Returns:       q.Get("returns"),
+	}
+
+	switch filter.Returns {
+	case "":
+		filter.Returns = returnsExclude
+	case returnsExclude, returnsOnly, returnsInclude:
+	default:
+		return filter, fmt.Errorf("invalid returns, expected exclude, only or include")
+	}
+
+	if transferStatus := q.Get("transfer_status"); transferStatus != "" {
+		for status, name := range models.TransferStatusNames {
+			if name == transferStatus || strconv.Itoa(status) == transferStatus {
+				filter.TransferStatus = status
+			}
+		}
+		if filter.TransferStatus == 0 {
+			return filter, fmt.Errorf("invalid transfer_status, expected at_merchant, at_hub, in_transit, with_rider or delivered")
+		}
+	}
+
+	if storeID := q.Get("store_id"); storeID != "" {
+		id, err := strconv.Atoi(storeID)
+		if err != nil {
+			return filter, fmt.Errorf("invalid store_id")
+		}
+		filter.StoreID = id
+	}
+
+	if from := q.Get("from"); from != "" {
+		t, err := time.Parse("2006-01-02", from)
+		if err != nil {
+			return filter, fmt.Errorf("invalid from date, expected YYYY-MM-DD")
+		}
+		filter.From = &t
+	}
+
+	if to := q.Get("to"); to != "" {
+		t, err := time.Parse("2006-01-02", to)
+		if err != nil {
+			return filter, fmt.Errorf("invalid to date, expected YYYY-MM-DD")
+		}
+		// Make the end date inclusive
+		t = t.AddDate(0, 0, 1)
+		filter.To = &t
+	}
+
+	return filter, nil
+}
+
+// where builds the SQL WHERE clause and its positional arguments for the filter
+func (f orderFilter) where() (string, []interface{}) {
+	conditions := []string{"user_id = $1", "archive = 0"}
+	args := []interface{}{f.UserID}
+
+	add := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	returnStatuses := fmt.Sprintf("('%s', '%s')", models.OrderStatusReturnInitiated, models.OrderStatusReturned)
+	if f.OrderStatus != "" {
+		add("order_status::text = $%d", f.OrderStatus)
+	} else if f.Returns == returnsExclude {
+		conditions = append(conditions, "order_status::text NOT IN "+returnStatuses)
+	} else if f.Returns == returnsOnly {
+		conditions = append(conditions, "order_status::text IN "+returnStatuses)
+	}
+	if f.TransferStatus != 0 {
+		add("transfer_status = $%d", f.TransferStatus)
+	}
+	if f.StoreID != 0 {
+		add("store_id = $%d", f.StoreID)
+	}
+	if f.ConsignmentID != "" {
+		add("consignment_id = $%d", f.ConsignmentID)
+	}
+	if f.From != nil {
+		add("created_at >= $%d", *f.From)
+	}
+	if f.To != nil {
+		add("created_at < $%d", *f.To)
+	}
+
+	return "WHERE " + strings.Join(conditions, " AND "), args
+}
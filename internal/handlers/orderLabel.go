@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go-application-task/configs"
+	"go-application-task/internal/models"
+	"go-application-task/pkg/label"
+	"go-application-task/pkg/utils"
+	"log"
+	"net/http"
+	"os"
+)
+
+// maxBulkLabels caps how many labels can be merged into a single PDF
+const maxBulkLabels = 200
+
+// buildLabel maps an order onto the data printed on its shipping label.
+// Stores have no phone number on record, so the sender is printed by name only.
+func buildLabel(order models.Order) label.Label {
+	l := label.Label{
+		ConsignmentID: order.ConsignmentID,
+		Sender: label.Party{
+			Name: fmt.Sprintf("Store #%d", order.StoreID),
+		},
+		Recipient: label.Party{
+			Name:    order.RecipientName,
+			Phone:   order.RecipientPhone,
+			Address: order.RecipientAddress,
+		},
+		CODAmount:   order.AmountToCollect,
+		RoutingCode: utils.RoutingCode(order.RecipientCity, order.RecipientZone, order.RecipientArea),
+		TrackingURL: configs.GetTrackingBaseURL() + "/" + order.ConsignmentID,
+		ItemWeight:  order.ItemWeight,
+	}
+	if order.MerchantOrderID != nil {
+		l.MerchantOrderID = *order.MerchantOrderID
+	}
+	if order.SpecialInstruction != nil {
+		l.Instruction = *order.SpecialInstruction
+	}
+	return l
+}
+
+// writeLabels renders labels into a buffer first so that rendering errors can still produce an error response
+func writeLabels(w http.ResponseWriter, tpl label.Template, labels []label.Label, filename string) {
+	var buf bytes.Buffer
+	if err := label.Render(&buf, tpl, labels); err != nil {
+		log.Printf("Label rendering error: %v", err)
+		http.Error(w, "Failed to render label", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/pdf")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	w.WriteHeader(http.StatusOK)
+	if _, err := buf.WriteTo(w); err != nil {
+		log.Printf("Failed to write label: %v", err)
+	}
+}
+
+// OrderLabelHandler renders the printable shipping label of a single order as PDF
+func OrderLabelHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		consignmentID := mux.Vars(r)["consignment_id"]
+		if !utils.ValidateConsignmentID(consignmentID) {
+			http.Error(w, "Invalid consignment ID", http.StatusBadRequest)
+			return
+		}
+
+		tpl, err := label.GetTemplate(r.URL.Query().Get("template"))
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Extract userID from token
+		userID, err := GetUserIDFromToken(r, os.Getenv("JWT_SECRET"), db)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		order, err := getUserOrder(db, consignmentID, userID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Order not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Order retrieval error: %v", err)
+			http.Error(w, "Failed to fetch order", http.StatusInternalServerError)
+			return
+		}
+
+		writeLabels(w, tpl, []label.Label{buildLabel(order)}, consignmentID+".pdf")
+	}
+}
+
+// BulkOrderLabelsHandler merges the shipping labels of many orders into one PDF, in the requested order
+func BulkOrderLabelsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ConsignmentIDs []string `json:"consignment_ids"`
+			Template       string   `json:"template"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if len(req.ConsignmentIDs) == 0 {
+			http.Error(w, "At least one consignment ID is required", http.StatusBadRequest)
+			return
+		}
+		if len(req.ConsignmentIDs) > maxBulkLabels {
+			http.Error(w, fmt.Sprintf("At most %d labels can be printed at once", maxBulkLabels), http.StatusBadRequest)
+			return
+		}
+
+		tpl, err := label.GetTemplate(req.Template)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		// Extract userID from token
+		userID, err := GetUserIDFromToken(r, os.Getenv("JWT_SECRET"), db)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var orders []models.Order
+		query := `SELECT ` + orderColumns + ` FROM orders WHERE consignment_id = ANY($1) AND user_id = $2`
+		if err := db.Select(&orders, query, pq.Array(req.ConsignmentIDs), userID); err != nil {
+			log.Printf("Order retrieval error: %v", err)
+			http.Error(w, "Failed to fetch orders", http.StatusInternalServerError)
+			return
+		}
+
+		byID := make(map[string]models.Order, len(orders))
+		for _, order := range orders {
+			byID[order.ConsignmentID] = order
+		}
+
+		labels := make([]label.Label, 0, len(req.ConsignmentIDs))
+		var missing []string
+		for _, consignmentID := range req.ConsignmentIDs {
+			order, ok := byID[consignmentID]
+			if !ok {
+				missing = append(missing, consignmentID)
+				continue
+			}
+			labels = append(labels, buildLabel(order))
+		}
+
+		if len(missing) > 0 {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusNotFound)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"message": "Some orders were not found",
+				"type":    "error",
+				"code":    404,
+				"errors":  map[string][]string{"consignment_ids": missing},
+			})
+			return
+		}
+
+		writeLabels(w, tpl, labels, "labels.pdf")
+	}
+}
@@ -0,0 +1,127 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"go-application-task/internal/models"
+	"go-application-task/internal/outbox"
+)
+
+// orderTransitions lists the statuses an order may move to from each status
+var orderTransitions = map[string][]string{
+	models.OrderStatusPending:         {models.OrderStatusPickupRequested, models.OrderStatusPicked, models.OrderStatusCompleted, models.OrderStatusPartiallyDelivered, models.OrderStatusCancelled, models.OrderStatusReturnInitiated},
+	models.OrderStatusPickupRequested: {models.OrderStatusPicked, models.OrderStatusCompleted, models.OrderStatusPartiallyDelivered, models.OrderStatusCancelled, models.OrderStatusReturnInitiated},
+	models.OrderStatusPicked:          {models.OrderStatusOutForDelivery, models.OrderStatusReturnInitiated},
+	models.OrderStatusOutForDelivery:  {models.OrderStatusCompleted, models.OrderStatusPartiallyDelivered, models.OrderStatusDeliveryFailed, models.OrderStatusReturnInitiated},
+	models.OrderStatusDeliveryFailed:  {models.OrderStatusOutForDelivery, models.OrderStatusReturnInitiated},
+	models.OrderStatusReturnInitiated: {models.OrderStatusReturned},
+}
+
+// statusTransferStatuses gives where a parcel is once it moves to one of these statuses, away from any hub
+var statusTransferStatuses = map[string]int{
+	models.OrderStatusOutForDelivery:     models.TransferStatusWithRider,
+	models.OrderStatusCompleted:          models.TransferStatusDelivered,
+	models.OrderStatusPartiallyDelivered: models.TransferStatusDelivered,
+	models.OrderStatusReturned:           models.TransferStatusAtMerchant,
+}
+
+// errInvalidTransition is returned when an order cannot move from its current status to the requested one
+type errInvalidTransition struct {
+	From, To string
+}
+
+func (e errInvalidTransition) Error() string {
+	return fmt.Sprintf("order cannot move from %s to %s", e.From, e.To)
+}
+
+// canTransition reports whether an order in status from may move to status to
+func canTransition(from, to string) bool {
+	for _, allowed := range orderTransitions[from] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// orderEvent is the data sent to merchants when an order is created or changes status
+type orderEvent struct {
+	ConsignmentID   string  `json:"consignment_id"`
+	MerchantOrderID *string `json:"merchant_order_id,omitempty"`
+	OrderStatus     string  `json:"order_status"`
+	PreviousStatus  string  `json:"previous_status,omitempty"`
+}
+
+// changeOrderStatus moves order to status inside tx, following the transition rules.
+// The status history entry and the outbox events are written in the same transaction.
+// order must hold the consignment ID, user ID and current status; its status is updated on success.
+func changeOrderStatus(tx *sqlx.Tx, order *models.Order, status string) error {
+	if !canTransition(order.OrderStatus, status) {
+		return errInvalidTransition{From: order.OrderStatus, To: status}
+	}
+
+	// The status condition guards against a concurrent change since the order was read
+	result, err := tx.Exec(`UPDATE orders SET order_status = $2 WHERE consignment_id = $1 AND order_status = $3`,
+		order.ConsignmentID, status, order.OrderStatus)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return fmt.Errorf("order %s was changed concurrently", order.ConsignmentID)
+	}
+
+	if err := recordStatusChange(tx, order.ConsignmentID, status); err != nil {
+		return err
+	}
+	if err := updateExchangeReturn(tx, order.ConsignmentID, status); err != nil {
+		return err
+	}
+	if transferStatus, ok := statusTransferStatuses[status]; ok {
+		_, err := tx.Exec(`UPDATE orders SET transfer_status = $2, current_hub_id = NULL WHERE consignment_id = $1`, order.ConsignmentID, transferStatus)
+		if err != nil {
+			return err
+		}
+	}
+
+	previousStatus := order.OrderStatus
+	order.OrderStatus = status
+	return publishStatusChange(tx, *order, previousStatus)
+}
+
+// updateExchangeReturn keeps the return leg of an exchange in step with the replacement's delivery.
+// The old item is collected when the replacement is delivered; if the exchange is cancelled nothing is collected.
+// Other orders have no return while they can be delivered or cancelled, so nothing matches for them.
+func updateExchangeReturn(exec sqlx.Execer, consignmentID, status string) error {
+	var err error
+	switch status {
+	case models.OrderStatusCompleted:
+		_, err = exec.Exec(`UPDATE order_returns SET status = $2, updated_at = NOW() WHERE consignment_id = $1 AND status = $3`,
+			consignmentID, models.ReturnStatusInTransit, models.ReturnStatusInitiated)
+	case models.OrderStatusCancelled:
+		_, err = exec.Exec(`DELETE FROM order_returns WHERE consignment_id = $1 AND status = $2`, consignmentID, models.ReturnStatusInitiated)
+	}
+	return err
+}
+
+// recordStatusChange appends an entry to the order status history
+func recordStatusChange(exec sqlx.Execer, consignmentID, status string) error {
+	_, err := exec.Exec(`INSERT INTO order_status_history (consignment_id, status) VALUES ($1, $2)`, consignmentID, status)
+	return err
+}
+
+// publishStatusChange writes order.status_changed and, for cancellations, order.cancelled to the outbox
+func publishStatusChange(exec sqlx.Execer, order models.Order, previousStatus string) error {
+	event := orderEvent{
+		ConsignmentID:   order.ConsignmentID,
+		MerchantOrderID: order.MerchantOrderID,
+		OrderStatus:     order.OrderStatus,
+		PreviousStatus:  previousStatus,
+	}
+	if err := outbox.Write(exec, order.ConsignmentID, models.EventOrderStatusChanged, order.UserID, event); err != nil {
+		return err
+	}
+	if order.OrderStatus == models.OrderStatusCancelled {
+		return outbox.Write(exec, order.ConsignmentID, models.EventOrderCancelled, order.UserID, event)
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+package handlers
+
+import (
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"go-application-task/configs"
+	"go-application-task/internal/stream"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// writeStreamEvent writes a single Server-Sent Event
+func writeStreamEvent(w http.ResponseWriter, event stream.Event) error {
+	_, err := fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.Seq, event.Type, event.Data)
+	return err
+}
+
+// OrderStreamHandler streams the caller's order events as Server-Sent Events.
+// Clients resume after a reconnect by sending the Last-Event-ID header; when the events in between are no
+// longer available a "reset" event tells the client to re-fetch its orders.
+func OrderStreamHandler(db *sqlx.DB, broker *stream.Broker) http.HandlerFunc {
+	config := configs.GetStreamConfig()
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		flusher, ok := w.(http.Flusher)
+		if !ok {
+			http.Error(w, "Streaming unsupported", http.StatusInternalServerError)
+			return
+		}
+
+		// Extract userID from token
+		userID, err := GetUserIDFromToken(r, os.Getenv("JWT_SECRET"), db)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		lastEventID := r.Header.Get("Last-Event-ID")
+		if lastEventID == "" {
+			lastEventID = r.URL.Query().Get("last_event_id")
+		}
+		lastSeq, _ := strconv.ParseUint(lastEventID, 10, 64)
+
+		sub, backlog, complete := broker.Subscribe(userID, lastSeq)
+		defer sub.Close()
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("Connection", "keep-alive")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		fmt.Fprintf(w, "retry: 3000\n\n")
+		if !complete {
+			fmt.Fprintf(w, "event: reset\ndata: {}\n\n")
+		}
+		for _, event := range backlog {
+			if err := writeStreamEvent(w, event); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+
+		heartbeat := time.NewTicker(config.HeartbeatInterval)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case <-r.Context().Done():
+				// The client disconnected
+				return
+			case event, ok := <-sub.Events():
+				if !ok {
+					// Dropped by the broker for falling behind, the client reconnects and resumes
+					return
+				}
+				if err := writeStreamEvent(w, event); err != nil {
+					return
+				}
+				flusher.Flush()
+			case <-heartbeat.C:
+				if _, err := fmt.Fprintf(w, ": heartbeat %d\n\n", time.Now().Unix()); err != nil {
+					return
+				}
+				flusher.Flush()
+			}
+		}
+	}
+}
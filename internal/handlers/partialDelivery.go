@@ -0,0 +1,162 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"go-application-task/configs"
+	"go-application-task/internal/ledger"
+	"go-application-task/internal/models"
+	"log"
+	"math"
+	"net/http"
+	"strings"
+)
+
+// PartialDeliveryHandler records that the recipient accepted only some items and paid a reduced COD (ops only).
+// The returned items are sent back to the store on a linked return consignment.
+func PartialDeliveryHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		consignmentID := mux.Vars(r)["consignment_id"]
+
+		var req struct {
+			CollectedAmount *float64 `json:"collected_amount"`
+			Reason          string   `json:"reason"`
+			ReturnedItems   []struct {
+				ItemID   int `json:"item_id"`
+				Quantity int `json:"quantity"`
+			} `json:"returned_items"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		user, ok := requireRole(w, r, db, models.RoleOps)
+		if !ok {
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			log.Printf("Failed to start transaction: %v", err)
+			http.Error(w, "Failed to record partial delivery", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		var order models.Order
+		err = tx.Get(&order, `SELECT `+orderColumns+` FROM orders WHERE consignment_id = $1 FOR UPDATE`, consignmentID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Order not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Order retrieval error: %v", err)
+			http.Error(w, "Failed to fetch order", http.StatusInternalServerError)
+			return
+		}
+
+		items, err := getOrderItems(tx, consignmentID)
+		if err != nil {
+			log.Printf("Order items retrieval error: %v", err)
+			http.Error(w, "Failed to fetch order", http.StatusInternalServerError)
+			return
+		}
+		itemsByID := make(map[int]*models.OrderItem, len(items))
+		for i := range items {
+			itemsByID[items[i].ID] = &items[i]
+		}
+
+		errs := make(map[string][]string)
+		if req.CollectedAmount == nil {
+			errs["collected_amount"] = append(errs["collected_amount"], "The collected amount field is required.")
+		} else if *req.CollectedAmount < 0 || *req.CollectedAmount > order.AmountToCollect {
+			errs["collected_amount"] = append(errs["collected_amount"], fmt.Sprintf("The collected amount must be between 0 and %.2f.", order.AmountToCollect))
+		}
+		if req.Reason == "" {
+			errs["reason"] = append(errs["reason"], "The reason field is required.")
+		}
+		if len(items) > 0 && len(req.ReturnedItems) == 0 {
+			errs["returned_items"] = append(errs["returned_items"], "At least one returned item is required.")
+		}
+		var returnedNames []string
+		for i, returned := range req.ReturnedItems {
+			field := fmt.Sprintf("returned_items.%d", i)
+			item, ok := itemsByID[returned.ItemID]
+			if !ok {
+				errs[field+".item_id"] = append(errs[field+".item_id"], "The item does not belong to this order.")
+				continue
+			}
+			if returned.Quantity < 1 || item.ReturnedQuantity+returned.Quantity > item.Quantity {
+				errs[field+".quantity"] = append(errs[field+".quantity"], fmt.Sprintf("The returned quantity must be between 1 and %d.", item.Quantity-item.ReturnedQuantity))
+				continue
+			}
+			item.ReturnedQuantity += returned.Quantity
+			returnedNames = append(returnedNames, fmt.Sprintf("%d x %s", returned.Quantity, item.Name))
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+
+		err = changeOrderStatus(tx, &order, models.OrderStatusPartiallyDelivered)
+		var invalid errInvalidTransition
+		if errors.As(err, &invalid) {
+			http.Error(w, fmt.Sprintf("Order in status %s cannot be partially delivered", invalid.From), http.StatusConflict)
+			return
+		}
+
+		var partial models.PartialDelivery
+		if err == nil {
+			_, err = tx.Exec(`UPDATE orders SET collected_amount = $2 WHERE consignment_id = $1`, consignmentID, *req.CollectedAmount)
+		}
+		for _, item := range items {
+			if err == nil {
+				_, err = tx.Exec(`UPDATE order_items SET returned_quantity = $2 WHERE id = $1`, item.ID, item.ReturnedQuantity)
+			}
+		}
+		if err == nil {
+			err = tx.Get(&partial, `
+				INSERT INTO partial_deliveries (consignment_id, collected_amount, reason, recorded_by)
+				VALUES ($1, $2, $3, $4)
+				RETURNING *
+			`, consignmentID, *req.CollectedAmount, req.Reason, user.ID)
+		}
+
+		// Returned items travel back to the store like any other return
+		var orderReturn *models.OrderReturn
+		if err == nil && len(returnedNames) > 0 {
+			orderReturn = &models.OrderReturn{
+				ConsignmentID: consignmentID,
+				Reason:        "Partial delivery: " + strings.Join(returnedNames, ", "),
+				ReturnCharge:  math.Round(order.DeliveryFee*configs.GetReturnChargePercent()) / 100,
+				InitiatedBy:   &user.ID,
+			}
+			err = openReturn(tx, orderReturn, order)
+			if err == nil {
+				err = ledger.PostReturnCharge(tx, order, orderReturn.ReturnCharge)
+			}
+		}
+		if err == nil {
+			err = ledger.PostDelivery(tx, order, *req.CollectedAmount)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			log.Printf("Failed to record partial delivery: %v", err)
+			http.Error(w, "Failed to record partial delivery", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Partial delivery recorded successfully.", map[string]interface{}{
+			"partial_delivery": partial,
+			"items":            items,
+			"return":           orderReturn,
+		})
+	}
+}
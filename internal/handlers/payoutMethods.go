@@ -0,0 +1,145 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"go-application-task/configs"
+	"go-application-task/internal/models"
+	"go-application-task/pkg/secret"
+	"log"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// accountNumberPattern matches bank account and mobile wallet numbers
+var accountNumberPattern = regexp.MustCompile(`^[0-9]{6,34}$`)
+
+// optionalString returns nil for a blank value so it is stored as NULL
+func optionalString(value string) *string {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// GetPayoutMethodHandler returns where the caller's payouts are sent. The account number is only shown by its last 4 digits.
+func GetPayoutMethodHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := requireRole(w, r, db, models.RoleMerchant)
+		if !ok {
+			return
+		}
+
+		var method models.PayoutMethod
+		err := db.Get(&method, `SELECT * FROM payout_methods WHERE user_id = $1`, user.ID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "No payout method set", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Payout method retrieval error: %v", err)
+			http.Error(w, "Failed to fetch payout method", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Payout method successfully fetched.", method)
+	}
+}
+
+// UpdatePayoutMethodHandler sets the bank account or mobile wallet the caller's payouts are sent to.
+// Payouts that have not been sent yet go to the new account.
+func UpdatePayoutMethodHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Method         string `json:"method"`
+			AccountName    string `json:"account_name"`
+			AccountNumber  string `json:"account_number"`
+			BankName       string `json:"bank_name"`
+			BranchName     string `json:"branch_name"`
+			RoutingNumber  string `json:"routing_number"`
+			WalletProvider string `json:"wallet_provider"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		user, ok := requireRole(w, r, db, models.RoleMerchant)
+		if !ok {
+			return
+		}
+
+		method := models.PayoutMethod{
+			UserID:        user.ID,
+			Method:        req.Method,
+			AccountName:   strings.TrimSpace(req.AccountName),
+			AccountNumber: strings.TrimSpace(req.AccountNumber),
+		}
+		errs := make(map[string][]string)
+		switch req.Method {
+		case models.PayoutMethodBankTransfer:
+			method.BankName = optionalString(req.BankName)
+			method.BranchName = optionalString(req.BranchName)
+			method.RoutingNumber = optionalString(req.RoutingNumber)
+			if method.BankName == nil {
+				errs["bank_name"] = append(errs["bank_name"], "The bank name field is required for bank transfers.")
+			}
+			if method.RoutingNumber == nil {
+				errs["routing_number"] = append(errs["routing_number"], "The routing number field is required for bank transfers.")
+			}
+		case models.PayoutMethodMobileWallet:
+			method.WalletProvider = optionalString(req.WalletProvider)
+			if method.WalletProvider == nil {
+				errs["wallet_provider"] = append(errs["wallet_provider"], "The wallet provider field is required for mobile wallets.")
+			}
+		default:
+			errs["method"] = append(errs["method"], fmt.Sprintf("The method must be %s or %s.", models.PayoutMethodBankTransfer, models.PayoutMethodMobileWallet))
+		}
+		if method.AccountName == "" {
+			errs["account_name"] = append(errs["account_name"], "The account name field is required.")
+		}
+		if !accountNumberPattern.MatchString(method.AccountNumber) {
+			errs["account_number"] = append(errs["account_number"], "The account number must be 6 to 34 digits.")
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+
+		key := configs.GetPayoutConfig().EncryptionKey
+		if key == nil {
+			log.Printf("Failed to update payout method: %v", secret.ErrNoKey)
+			http.Error(w, "Payout methods are not configured", http.StatusInternalServerError)
+			return
+		}
+		encrypted, err := secret.Seal(key, method.AccountNumber)
+		if err != nil {
+			log.Printf("Failed to encrypt account number: %v", err)
+			http.Error(w, "Failed to update payout method", http.StatusInternalServerError)
+			return
+		}
+
+		err = db.Get(&method, `
+			INSERT INTO payout_methods (user_id, method, account_name, account_number_encrypted, account_number_last4, bank_name, branch_name, routing_number, wallet_provider)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+			ON CONFLICT (user_id) DO UPDATE
+			SET method = EXCLUDED.method, account_name = EXCLUDED.account_name,
+			    account_number_encrypted = EXCLUDED.account_number_encrypted, account_number_last4 = EXCLUDED.account_number_last4,
+			    bank_name = EXCLUDED.bank_name, branch_name = EXCLUDED.branch_name, routing_number = EXCLUDED.routing_number,
+			    wallet_provider = EXCLUDED.wallet_provider, updated_at = NOW()
+			RETURNING *
+		`, user.ID, method.Method, method.AccountName, encrypted, method.AccountNumber[len(method.AccountNumber)-4:],
+			method.BankName, method.BranchName, method.RoutingNumber, method.WalletProvider)
+		if err != nil {
+			log.Printf("Failed to update payout method: %v", err)
+			http.Error(w, "Failed to update payout method", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Payout method updated successfully.", method)
+	}
+}
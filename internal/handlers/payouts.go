@@ -0,0 +1,417 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go-application-task/configs"
+	"go-application-task/internal/disbursement"
+	"go-application-task/internal/ledger"
+	"go-application-task/internal/models"
+	"go-application-task/internal/webhook"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// getPayout fetches a payout, scoped to userID unless it is 0
+func getPayout(db sqlx.Queryer, payoutID, userID int) (models.Payout, error) {
+	var payout models.Payout
+	err := sqlx.Get(db, &payout, `SELECT * FROM payouts WHERE id = $1 AND ($2 = 0 OR user_id = $2)`, payoutID, userID)
+	return payout, err
+}
+
+// getPayoutLines breaks a payout down per consignment
+func getPayoutLines(db sqlx.Queryer, payoutID int) ([]models.PayoutLine, error) {
+	lines := []models.PayoutLine{}
+	err := sqlx.Select(db, &lines, `
+		SELECT t.consignment_id,
+		       COALESCE(SUM(e.credit) FILTER (WHERE t.entry_type = 'cod_collected'), 0) AS cod_collected,
+		       COALESCE(SUM(e.debit) FILTER (WHERE t.entry_type = 'delivery_fee'), 0) AS delivery_fee,
+		       COALESCE(SUM(e.debit) FILTER (WHERE t.entry_type = 'surcharge'), 0) AS surcharge,
+		       COALESCE(SUM(e.debit) FILTER (WHERE t.entry_type = 'cod_fee'), 0) AS cod_fee,
+		       COALESCE(SUM(e.debit) FILTER (WHERE t.entry_type = 'return_charge'), 0) AS return_charge,
+		       COALESCE(SUM(e.credit) FILTER (WHERE t.entry_type = 'claim_compensation'), 0) AS compensation,
+		       SUM(e.credit - e.debit) AS net
+		FROM ledger_transactions t
+		JOIN ledger_entries e ON e.transaction_id = t.id AND e.account = 'merchant_payable'
+		WHERE t.payout_id = $1 AND t.consignment_id IS NOT NULL
+		GROUP BY t.consignment_id
+		ORDER BY t.consignment_id
+	`, payoutID)
+	return lines, err
+}
+
+// ownerScope returns the merchant whose payouts and invoices the caller may see, or 0 for ops who see everyone's
+func ownerScope(user models.User) int {
+	if user.Role == models.RoleOps {
+		return 0
+	}
+	return user.ID
+}
+
+// listScope is ownerScope for list endpoints, where ops may narrow the list down with the user_id query parameter
+func listScope(r *http.Request, user models.User) int {
+	if user.Role != models.RoleOps {
+		return user.ID
+	}
+	userID, _ := strconv.Atoi(r.URL.Query().Get("user_id"))
+	return userID
+}
+
+// BalanceHandler returns what the caller is owed for delivered orders that has not yet been batched into a payout
+func BalanceHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := requireRole(w, r, db, models.RoleMerchant)
+		if !ok {
+			return
+		}
+
+		var balance models.Balance
+		err := db.Get(&balance, `
+			SELECT COALESCE(SUM(e.credit - e.debit), 0) AS balance,
+			       COALESCE(SUM(e.credit) FILTER (WHERE t.entry_type = 'cod_collected'), 0) AS cod_collected,
+			       COALESCE(SUM(e.debit) FILTER (WHERE t.entry_type = 'delivery_fee'), 0) AS delivery_fees,
+			       COALESCE(SUM(e.debit) FILTER (WHERE t.entry_type = 'surcharge'), 0) AS surcharges,
+			       COALESCE(SUM(e.debit) FILTER (WHERE t.entry_type = 'cod_fee'), 0) AS cod_fees,
+			       COALESCE(SUM(e.debit) FILTER (WHERE t.entry_type = 'return_charge'), 0) AS return_charges,
+			       COALESCE(SUM(e.credit) FILTER (WHERE t.entry_type = 'fee_credit'), 0) AS fee_credits,
+			       COALESCE(SUM(e.credit) FILTER (WHERE t.entry_type = 'claim_compensation'), 0) AS compensation,
+			       (SELECT COALESCE(SUM(amount), 0) FROM payouts WHERE user_id = $1 AND status = 'succeeded') AS paid_out,
+			       (SELECT COALESCE(SUM(amount), 0) FROM payouts WHERE user_id = $1 AND status IN ('pending', 'initiated', 'needs_review')) AS pending_payouts
+			FROM ledger_transactions t
+			JOIN ledger_entries e ON e.transaction_id = t.id AND e.account = 'merchant_payable'
+			WHERE t.user_id = $1
+		`, user.ID)
+		if err != nil {
+			log.Printf("Failed to fetch balance: %v", err)
+			http.Error(w, "Failed to fetch balance", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Balance successfully fetched.", balance)
+	}
+}
+
+// CreatePayoutsHandler batches each merchant's settled ledger transactions up to period_end into a payout (ops only).
+// Merchants who are owed nothing, or owe money after return charges, are carried over to the next run.
+func CreatePayoutsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			UserID    int        `json:"user_id"`
+			PeriodEnd *time.Time `json:"period_end"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		user, ok := requireRole(w, r, db, models.RoleOps)
+		if !ok {
+			return
+		}
+
+		periodEnd := time.Now()
+		if req.PeriodEnd != nil {
+			if req.PeriodEnd.After(periodEnd) {
+				writeValidationErrors(w, map[string][]string{"period_end": {"The period end may not be in the future."}})
+				return
+			}
+			periodEnd = *req.PeriodEnd
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			log.Printf("Failed to start transaction: %v", err)
+			http.Error(w, "Failed to create payouts", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		// Lock the unbatched transactions so a concurrent run cannot pay them out twice,
+		// and batch exactly those even if more are posted meanwhile
+		var transactionIDs []int64
+		err = tx.Select(&transactionIDs, `
+			SELECT id FROM ledger_transactions
+			WHERE payout_id IS NULL AND created_at <= $1 AND ($2 = 0 OR user_id = $2)
+			FOR UPDATE
+		`, periodEnd, req.UserID)
+		var owed []struct {
+			UserID int     `db:"user_id"`
+			Amount float64 `db:"amount"`
+		}
+		if err == nil {
+			err = tx.Select(&owed, `
+				SELECT t.user_id, SUM(e.credit - e.debit) AS amount
+				FROM ledger_transactions t
+				JOIN ledger_entries e ON e.transaction_id = t.id AND e.account = 'merchant_payable'
+				WHERE t.id = ANY($1)
+				GROUP BY t.user_id
+				HAVING SUM(e.credit - e.debit) > 0
+				ORDER BY t.user_id
+			`, pq.Array(transactionIDs))
+		}
+
+		payouts := []models.Payout{}
+		for _, merchant := range owed {
+			if err != nil {
+				break
+			}
+			var payout models.Payout
+			err = tx.Get(&payout, `
+				INSERT INTO payouts (user_id, amount, period_end, created_by)
+				VALUES ($1, $2, $3, $4)
+				RETURNING *
+			`, merchant.UserID, merchant.Amount, periodEnd, user.ID)
+			if err == nil {
+				_, err = tx.Exec(`
+					UPDATE ledger_transactions SET payout_id = $1
+					WHERE id = ANY($2) AND user_id = $3
+				`, payout.ID, pq.Array(transactionIDs), merchant.UserID)
+			}
+			if err == nil {
+				err = ledger.Post(tx, ledger.Posting{
+					EntryType: models.EntryPayout,
+					UserID:    merchant.UserID,
+					PayoutID:  &payout.ID,
+					Debit:     models.AccountMerchantPayable,
+					Credit:    models.AccountPayoutsPayable,
+					Amount:    payout.Amount,
+				})
+			}
+			payouts = append(payouts, payout)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			log.Printf("Failed to create payouts: %v", err)
+			http.Error(w, "Failed to create payouts", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusCreated, fmt.Sprintf("%d payouts created.", len(payouts)), payouts)
+	}
+}
+
+// ListPayoutsHandler lists the caller's payouts, newest first. Ops see every merchant's payouts.
+func ListPayoutsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := requireRole(w, r, db, models.RoleMerchant, models.RoleOps)
+		if !ok {
+			return
+		}
+		userID := listScope(r, user)
+		status := r.URL.Query().Get("status")
+
+		page, perPage, offset := parsePagination(r)
+		payouts := []models.Payout{}
+		err := db.Select(&payouts, `
+			SELECT * FROM payouts
+			WHERE ($1 = 0 OR user_id = $1) AND ($2 = '' OR status = $2)
+			ORDER BY id DESC
+			LIMIT $3 OFFSET $4
+		`, userID, status, perPage, offset)
+		if err != nil {
+			log.Printf("Failed to fetch payouts: %v", err)
+			http.Error(w, "Failed to fetch payouts", http.StatusInternalServerError)
+			return
+		}
+
+		var total int
+		err = db.Get(&total, `SELECT COUNT(*) FROM payouts WHERE ($1 = 0 OR user_id = $1) AND ($2 = '' OR status = $2)`, userID, status)
+		if err != nil {
+			log.Printf("Error counting payouts: %v", err)
+			http.Error(w, "Failed to calculate pagination", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Payouts successfully fetched.", newPaginatedResponse(payouts, len(payouts), total, page, perPage))
+	}
+}
+
+// GetPayoutHandler returns a payout with the breakdown per consignment
+func GetPayoutHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payoutID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+		user, ok := requireRole(w, r, db, models.RoleMerchant, models.RoleOps)
+		if !ok {
+			return
+		}
+		payout, err := getPayout(db, payoutID, ownerScope(user))
+		if err == sql.ErrNoRows {
+			http.Error(w, "Payout not found", http.StatusNotFound)
+			return
+		}
+		if err == nil {
+			payout.Lines, err = getPayoutLines(db, payoutID)
+		}
+		if err != nil {
+			log.Printf("Payout retrieval error: %v", err)
+			http.Error(w, "Failed to fetch payout", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Payout successfully fetched.", payout)
+	}
+}
+
+// PayoutCallbackHandler applies a status update posted by a payout provider for a transfer it reported as initiated
+// or that is held for review.
+// The body must be signed with the callback secret in the X-Payout-Signature header. Updates for payouts that already
+// succeeded or failed are acknowledged and ignored, so providers can safely repeat them.
+func PayoutCallbackHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			http.Error(w, "Failed to read request body", http.StatusBadRequest)
+			return
+		}
+		callbackSecret := configs.GetPayoutConfig().CallbackSecret
+		if callbackSecret == "" || !webhook.Verify(callbackSecret, body, r.Header.Get("X-Payout-Signature")) {
+			http.Error(w, "Invalid signature", http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			Reference         string `json:"reference"`
+			ProviderReference string `json:"provider_reference"`
+			Status            string `json:"status"`
+			Error             string `json:"error"`
+		}
+		if err := json.Unmarshal(body, &req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		payoutID, ok := disbursement.ParseReference(req.Reference)
+		if !ok {
+			writeValidationErrors(w, map[string][]string{"reference": {"The reference is not a payout reference."}})
+			return
+		}
+		if req.Status != models.PayoutStatusSucceeded && req.Status != models.PayoutStatusFailed {
+			writeValidationErrors(w, map[string][]string{"status": {"The status must be succeeded or failed."}})
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			log.Printf("Failed to start transaction: %v", err)
+			http.Error(w, "Failed to update payout", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		var payout models.Payout
+		err = tx.Get(&payout, `SELECT * FROM payouts WHERE id = $1 FOR UPDATE`, payoutID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Payout not found", http.StatusNotFound)
+			return
+		}
+		if err == nil {
+			err = disbursement.RecordAttempt(tx, payout.ID, payout.Attempts, req.Status, req.ProviderReference, req.Error)
+		}
+		if err == nil && (payout.Status == models.PayoutStatusPending || payout.Status == models.PayoutStatusInitiated || payout.Status == models.PayoutStatusNeedsReview) {
+			if req.Status == models.PayoutStatusSucceeded {
+				err = disbursement.Succeed(tx, &payout, req.ProviderReference)
+			} else {
+				log.Printf("Payout %d declined by provider: %s", payout.ID, req.Error)
+				err = disbursement.Fail(tx, &payout, req.Error)
+			}
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			log.Printf("Failed to update payout: %v", err)
+			http.Error(w, "Failed to update payout", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Payout status received.", payout)
+	}
+}
+
+// ResolvePayoutHandler settles a payout held for review once ops have checked with the provider (ops only).
+// A succeeded payout is marked paid; a failed one goes back to the merchant's balance for the next payout run.
+func ResolvePayoutHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		payoutID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+		var req struct {
+			Status            string `json:"status"`
+			ProviderReference string `json:"provider_reference"`
+			Note              string `json:"note"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := requireRole(w, r, db, models.RoleOps); !ok {
+			return
+		}
+
+		errs := make(map[string][]string)
+		if req.Status != models.PayoutStatusSucceeded && req.Status != models.PayoutStatusFailed {
+			errs["status"] = append(errs["status"], "The status must be succeeded or failed.")
+		}
+		req.Note = strings.TrimSpace(req.Note)
+		if req.Status == models.PayoutStatusFailed && req.Note == "" {
+			errs["note"] = append(errs["note"], "The note field is required when the payout failed.")
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			log.Printf("Failed to start transaction: %v", err)
+			http.Error(w, "Failed to resolve payout", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		var payout models.Payout
+		err = tx.Get(&payout, `SELECT * FROM payouts WHERE id = $1 FOR UPDATE`, payoutID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Payout not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Payout retrieval error: %v", err)
+			http.Error(w, "Failed to fetch payout", http.StatusInternalServerError)
+			return
+		}
+		if payout.Status != models.PayoutStatusNeedsReview {
+			http.Error(w, fmt.Sprintf("Payout in status %s cannot be resolved", payout.Status), http.StatusConflict)
+			return
+		}
+
+		err = disbursement.RecordAttempt(tx, payout.ID, payout.Attempts, req.Status, req.ProviderReference, req.Note)
+		if err == nil {
+			if req.Status == models.PayoutStatusSucceeded {
+				err = disbursement.Succeed(tx, &payout, req.ProviderReference)
+			} else {
+				err = disbursement.Fail(tx, &payout, req.Note)
+			}
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			log.Printf("Failed to resolve payout: %v", err)
+			http.Error(w, "Failed to resolve payout", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Payout resolved successfully.", payout)
+	}
+}
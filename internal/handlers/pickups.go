@@ -0,0 +1,452 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go-application-task/configs"
+	"go-application-task/internal/models"
+	"go-application-task/pkg/db"
+	"go-application-task/pkg/utils"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// maxPickupOrders is the most orders a single pickup request may include
+const maxPickupOrders = 500
+
+// pickupColumns lists the pickup_requests columns that map onto models.PickupRequest
+const pickupColumns = `
+	id,
+	pickup_id,
+	user_id,
+	store_id,
+	zone_id,
+	to_char(pickup_date, 'YYYY-MM-DD') AS pickup_date,
+	window_start,
+	window_end,
+	status,
+	note,
+	confirmed_by,
+	confirmed_at,
+	reschedule_reason,
+	created_at,
+	updated_at`
+
+// pickupSlot is a pickup date and time window as sent by the client
+type pickupSlot struct {
+	PickupDate  string `json:"pickup_date"`
+	WindowStart string `json:"window_start"`
+	WindowEnd   string `json:"window_end"`
+}
+
+// parseClock parses an HH:MM time of day into an offset from midnight
+func parseClock(value string) (time.Duration, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute, nil
+}
+
+// formatClock formats an offset from midnight as HH:MM
+func formatClock(d time.Duration) string {
+	return fmt.Sprintf("%02d:%02d", int(d.Hours()), int(d.Minutes())%60)
+}
+
+// validate checks the slot against the store operating hours and the same day cut-off.
+// On success the window bounds are normalised to HH:MM.
+func (s *pickupSlot) validate(now time.Time, cfg configs.PickupConfig) map[string][]string {
+	errors := make(map[string][]string)
+
+	now = now.In(cfg.Location)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, cfg.Location)
+	sinceMidnight := now.Sub(today)
+
+	day, err := time.ParseInLocation("2006-01-02", s.PickupDate, cfg.Location)
+	sameDay := err == nil && day.Equal(today)
+	if s.PickupDate == "" {
+		errors["pickup_date"] = append(errors["pickup_date"], "The pickup date field is required.")
+	} else if err != nil {
+		errors["pickup_date"] = append(errors["pickup_date"], "The pickup date must be in YYYY-MM-DD format.")
+	} else if day.Before(today) {
+		errors["pickup_date"] = append(errors["pickup_date"], "The pickup date cannot be in the past.")
+	} else if day.After(today.AddDate(0, 0, cfg.MaxDaysAhead)) {
+		errors["pickup_date"] = append(errors["pickup_date"], fmt.Sprintf("Pickups can be requested at most %d days ahead.", cfg.MaxDaysAhead))
+	} else if sameDay && sinceMidnight >= cfg.Cutoff {
+		errors["pickup_date"] = append(errors["pickup_date"], fmt.Sprintf("Same day pickups must be requested before %s.", formatClock(cfg.Cutoff)))
+	}
+
+	start, startErr := parseClock(s.WindowStart)
+	if startErr != nil {
+		errors["window_start"] = append(errors["window_start"], "The window start must be in HH:MM format.")
+	}
+	end, endErr := parseClock(s.WindowEnd)
+	if endErr != nil {
+		errors["window_end"] = append(errors["window_end"], "The window end must be in HH:MM format.")
+	}
+	if startErr != nil || endErr != nil {
+		return errors
+	}
+
+	if start < cfg.OpensAt || end > cfg.ClosesAt {
+		errors["window_start"] = append(errors["window_start"], fmt.Sprintf("The pickup window must be within operating hours %s to %s.", formatClock(cfg.OpensAt), formatClock(cfg.ClosesAt)))
+	} else if end-start < cfg.MinWindow {
+		errors["window_end"] = append(errors["window_end"], fmt.Sprintf("The pickup window must be at least %d minutes long.", int(cfg.MinWindow.Minutes())))
+	} else if sameDay && end-sinceMidnight < cfg.MinWindow {
+		errors["window_end"] = append(errors["window_end"], "Too little of the pickup window is left today.")
+	}
+
+	s.WindowStart, s.WindowEnd = formatClock(start), formatClock(end)
+	return errors
+}
+
+// insertPickup stores a pickup request under a newly generated pickup ID, retrying on collisions.
+// Each attempt runs in a savepoint so a collision does not abort the surrounding transaction.
+func insertPickup(tx *sqlx.Tx, pickup *models.PickupRequest) error {
+	var err error
+	for attempt := 1; attempt <= consignmentIDAttempts; attempt++ {
+		pickup.PickupID, err = utils.GeneratePickupID()
+		if err != nil {
+			return err
+		}
+
+		if _, err = tx.Exec(`SAVEPOINT insert_pickup`); err != nil {
+			return err
+		}
+		err = tx.Get(pickup, `
+			INSERT INTO pickup_requests (pickup_id, user_id, store_id, zone_id, pickup_date, window_start, window_end, note)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+			RETURNING `+pickupColumns,
+			pickup.PickupID, pickup.UserID, pickup.StoreID, pickup.ZoneID, pickup.PickupDate, pickup.WindowStart, pickup.WindowEnd, pickup.Note)
+		if err == nil {
+			_, err = tx.Exec(`RELEASE SAVEPOINT insert_pickup`)
+			return err
+		}
+		if _, rollbackErr := tx.Exec(`ROLLBACK TO SAVEPOINT insert_pickup`); rollbackErr != nil {
+			return rollbackErr
+		}
+		if !db.IsUniqueViolation(err, "pickup_requests_pickup_id_key") {
+			return err
+		}
+		log.Printf("Pickup ID %s already exists, retrying (attempt %d)", pickup.PickupID, attempt)
+	}
+	return err
+}
+
+// CreatePickupHandler asks for pending orders of a store to be picked up within a time window.
+// The orders move to pickup_requested.
+func CreatePickupHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			pickupSlot
+			StoreID        int      `json:"store_id"`
+			ZoneID         int      `json:"zone_id"`
+			Note           *string  `json:"note"`
+			ConsignmentIDs []string `json:"consignment_ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		userID, err := GetUserIDFromToken(r, os.Getenv("JWT_SECRET"), db)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		errs := req.pickupSlot.validate(time.Now(), configs.GetPickupConfig())
+		if req.StoreID == 0 {
+			errs["store_id"] = append(errs["store_id"], "The store field is required")
+		} else if req.StoreID != ValidStoreID {
+			errs["store_id"] = append(errs["store_id"], "Wrong Store selected")
+		}
+		if req.ZoneID == 0 {
+			errs["zone_id"] = append(errs["zone_id"], "The zone field is required.")
+		} else if req.ZoneID != ValidRecipientZone {
+			errs["zone_id"] = append(errs["zone_id"], "Invalid zone selected")
+		}
+		if len(req.ConsignmentIDs) == 0 {
+			errs["consignment_ids"] = append(errs["consignment_ids"], "At least one order is required.")
+		} else if len(req.ConsignmentIDs) > maxPickupOrders {
+			errs["consignment_ids"] = append(errs["consignment_ids"], fmt.Sprintf("At most %d orders can be picked up at once.", maxPickupOrders))
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			log.Printf("Failed to start transaction: %v", err)
+			http.Error(w, "Failed to request pickup", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		var orders []models.Order
+		query := `SELECT ` + orderColumns + ` FROM orders WHERE consignment_id = ANY($1) AND user_id = $2 ORDER BY consignment_id FOR UPDATE`
+		if err := tx.Select(&orders, query, pq.Array(req.ConsignmentIDs), userID); err != nil {
+			log.Printf("Order retrieval error: %v", err)
+			http.Error(w, "Failed to fetch orders", http.StatusInternalServerError)
+			return
+		}
+
+		byID := make(map[string]models.Order, len(orders))
+		for _, order := range orders {
+			byID[order.ConsignmentID] = order
+		}
+		for _, consignmentID := range req.ConsignmentIDs {
+			order, ok := byID[consignmentID]
+			field := "consignment_ids." + consignmentID
+			switch {
+			case !ok:
+				errs[field] = append(errs[field], "The order was not found.")
+			case order.StoreID != req.StoreID:
+				errs[field] = append(errs[field], "The order belongs to another store.")
+			case order.PickupID != nil:
+				errs[field] = append(errs[field], fmt.Sprintf("The order is already in pickup %s.", *order.PickupID))
+			case order.OrderStatus != models.OrderStatusPending:
+				errs[field] = append(errs[field], fmt.Sprintf("Orders in status %s cannot be picked up.", order.OrderStatus))
+			}
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+
+		pickup := models.PickupRequest{
+			UserID:      userID,
+			StoreID:     req.StoreID,
+			ZoneID:      req.ZoneID,
+			PickupDate:  req.PickupDate,
+			WindowStart: req.WindowStart,
+			WindowEnd:   req.WindowEnd,
+			Note:        req.Note,
+		}
+		err = insertPickup(tx, &pickup)
+		for i := range orders {
+			if err != nil {
+				break
+			}
+			if err = changeOrderStatus(tx, &orders[i], models.OrderStatusPickupRequested); err == nil {
+				_, err = tx.Exec(`UPDATE orders SET pickup_id = $2 WHERE consignment_id = $1`, orders[i].ConsignmentID, pickup.PickupID)
+			}
+			pickup.ConsignmentIDs = append(pickup.ConsignmentIDs, orders[i].ConsignmentID)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			log.Printf("Failed to request pickup: %v", err)
+			http.Error(w, "Failed to request pickup", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusCreated, "Pickup requested successfully.", pickup)
+	}
+}
+
+// ListPickupsHandler lists pickup requests, merchants see their own and ops see all of them.
+// Results can be filtered by zone_id, pickup_date and status.
+func ListPickupsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := requireRole(w, r, db, models.RoleMerchant, models.RoleOps)
+		if !ok {
+			return
+		}
+
+		page, perPage, offset := parsePagination(r)
+		q := r.URL.Query()
+		status := q.Get("status")
+		pickupDate := q.Get("pickup_date")
+		if pickupDate != "" {
+			if _, err := time.Parse("2006-01-02", pickupDate); err != nil {
+				http.Error(w, "invalid pickup_date, expected YYYY-MM-DD", http.StatusBadRequest)
+				return
+			}
+		}
+		var zoneID int
+		if zone := q.Get("zone_id"); zone != "" {
+			id, err := strconv.Atoi(zone)
+			if err != nil {
+				http.Error(w, "invalid zone_id", http.StatusBadRequest)
+				return
+			}
+			zoneID = id
+		}
+
+		// Ops see every merchant's pickups
+		scopeUserID := user.ID
+		if user.Role == models.RoleOps {
+			scopeUserID = 0
+		}
+
+		where := `
+			WHERE ($1 = 0 OR user_id = $1)
+			AND ($2 = 0 OR zone_id = $2)
+			AND ($3 = '' OR pickup_date = NULLIF($3, '')::date)
+			AND ($4 = '' OR status = $4)`
+
+		type pickupRow struct {
+			models.PickupRequest
+			OrderCount int `json:"order_count" db:"order_count"`
+		}
+		pickups := []pickupRow{}
+		err := db.Select(&pickups, `
+			SELECT `+pickupColumns+`, (SELECT COUNT(*) FROM orders o WHERE o.pickup_id = pickup_requests.pickup_id) AS order_count
+			FROM pickup_requests
+			`+where+`
+			ORDER BY pickup_requests.pickup_date, pickup_requests.window_start, pickup_requests.id
+			LIMIT $5 OFFSET $6
+		`, scopeUserID, zoneID, pickupDate, status, perPage, offset)
+		if err != nil {
+			log.Printf("Failed to fetch pickups: %v", err)
+			http.Error(w, "Failed to fetch pickups", http.StatusInternalServerError)
+			return
+		}
+
+		var total int
+		err = db.Get(&total, `SELECT COUNT(*) FROM pickup_requests `+where, scopeUserID, zoneID, pickupDate, status)
+		if err != nil {
+			log.Printf("Error counting pickups: %v", err)
+			http.Error(w, "Failed to calculate pagination", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Pickups successfully fetched.", newPaginatedResponse(pickups, len(pickups), total, page, perPage))
+	}
+}
+
+// errPickupNotFound is returned by updatePickup when there is no pickup with the given ID
+var errPickupNotFound = errors.New("pickup not found")
+
+// errPickupStatus is returned by updatePickup when the pickup is not in a status the change is allowed from
+type errPickupStatus struct {
+	Status string
+}
+
+func (e errPickupStatus) Error() string {
+	return fmt.Sprintf("Pickup in status %s cannot be changed", e.Status)
+}
+
+// updatePickup locks a pickup request, checks it is in one of the allowed statuses and applies update to it
+func updatePickup(db *sqlx.DB, pickupID string, allowed []string, update func(tx *sqlx.Tx, pickup *models.PickupRequest) error) (models.PickupRequest, error) {
+	var pickup models.PickupRequest
+	tx, err := db.Beginx()
+	if err != nil {
+		return pickup, err
+	}
+	defer tx.Rollback()
+
+	err = tx.Get(&pickup, `SELECT `+pickupColumns+` FROM pickup_requests WHERE pickup_id = $1 FOR UPDATE`, pickupID)
+	if err == sql.ErrNoRows {
+		return pickup, errPickupNotFound
+	}
+	if err != nil {
+		return pickup, err
+	}
+
+	permitted := false
+	for _, status := range allowed {
+		permitted = permitted || pickup.Status == status
+	}
+	if !permitted {
+		return pickup, errPickupStatus{Status: pickup.Status}
+	}
+
+	if err := update(tx, &pickup); err != nil {
+		return pickup, err
+	}
+	return pickup, tx.Commit()
+}
+
+// writePickupError writes the response for an error returned by updatePickup
+func writePickupError(w http.ResponseWriter, err error, action string) {
+	var invalid errPickupStatus
+	switch {
+	case errors.Is(err, errPickupNotFound):
+		http.Error(w, "Pickup not found", http.StatusNotFound)
+	case errors.As(err, &invalid):
+		http.Error(w, invalid.Error(), http.StatusConflict)
+	default:
+		log.Printf("Failed to %s pickup: %v", action, err)
+		http.Error(w, fmt.Sprintf("Failed to %s pickup", action), http.StatusInternalServerError)
+	}
+}
+
+// ConfirmPickupHandler confirms that a pickup will happen in its requested window (ops only)
+func ConfirmPickupHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := requireRole(w, r, db, models.RoleOps)
+		if !ok {
+			return
+		}
+
+		allowed := []string{models.PickupStatusRequested, models.PickupStatusRescheduled}
+		pickup, err := updatePickup(db, mux.Vars(r)["pickup_id"], allowed, func(tx *sqlx.Tx, pickup *models.PickupRequest) error {
+			return tx.Get(pickup, `
+				UPDATE pickup_requests
+				SET status = $2, confirmed_by = $3, confirmed_at = NOW(), updated_at = NOW()
+				WHERE id = $1
+				RETURNING `+pickupColumns, pickup.ID, models.PickupStatusConfirmed, user.ID)
+		})
+		if err != nil {
+			writePickupError(w, err, "confirm")
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Pickup confirmed successfully.", pickup)
+	}
+}
+
+// ReschedulePickupHandler moves a pickup to another date or time window (ops only)
+func ReschedulePickupHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			pickupSlot
+			Reason string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := requireRole(w, r, db, models.RoleOps); !ok {
+			return
+		}
+
+		errs := req.pickupSlot.validate(time.Now(), configs.GetPickupConfig())
+		if req.Reason == "" {
+			errs["reason"] = append(errs["reason"], "The reason field is required.")
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+
+		allowed := []string{models.PickupStatusRequested, models.PickupStatusConfirmed, models.PickupStatusRescheduled}
+		pickup, err := updatePickup(db, mux.Vars(r)["pickup_id"], allowed, func(tx *sqlx.Tx, pickup *models.PickupRequest) error {
+			return tx.Get(pickup, `
+				UPDATE pickup_requests
+				SET status = $2, pickup_date = $3, window_start = $4, window_end = $5, reschedule_reason = $6,
+					confirmed_by = NULL, confirmed_at = NULL, updated_at = NOW()
+				WHERE id = $1
+				RETURNING `+pickupColumns, pickup.ID, models.PickupStatusRescheduled, req.PickupDate, req.WindowStart, req.WindowEnd, req.Reason)
+		})
+		if err != nil {
+			writePickupError(w, err, "reschedule")
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Pickup rescheduled successfully.", pickup)
+	}
+}
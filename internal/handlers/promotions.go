@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go-application-task/internal/models"
+	"go-application-task/internal/pricing"
+	"go-application-task/internal/promotion"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// promotionOrder describes an order to the promotion rules
+func promotionOrder(order *models.Order) promotion.Order {
+	p := promotion.Order{UserID: order.UserID, Zone: order.RecipientZone}
+	if order.PromoCode != nil {
+		p.Code = *order.PromoCode
+	}
+	return p
+}
+
+// applyPromotion takes the best promotion for an order off its delivery fee and records it on the order.
+// A promo code that does not apply is reported as a validation error; without one the best automatic promotion is used.
+func applyPromotion(db sqlx.Queryer, order *models.Order, quote *pricing.Quote) (map[string][]string, error) {
+	errs := make(map[string][]string)
+	// The promotion is always picked here, never taken from the request
+	order.PromotionID = nil
+	if order.PromoCode != nil && strings.TrimSpace(*order.PromoCode) == "" {
+		order.PromoCode = nil
+	}
+
+	p, discount, err := promotion.Best(db, promotionOrder(order), order.DeliveryFee)
+	if err == promotion.ErrNotFound {
+		if order.PromoCode != nil {
+			errs["promo_code"] = append(errs["promo_code"], "The promo code is invalid, expired or does not apply to this order.")
+		}
+		return errs, nil
+	}
+	if err != nil {
+		return errs, err
+	}
+
+	quote.ApplyDiscount(discount)
+	order.PromotionID = &p.ID
+	order.PromoCode = p.Code
+	setOrderFees(order, *quote)
+	return errs, nil
+}
+
+// promotionRequest is the body of the create and update promotion endpoints
+type promotionRequest struct {
+	Code             *string    `json:"code"`
+	Name             string     `json:"name"`
+	UserID           *int       `json:"user_id"`
+	DiscountType     string     `json:"discount_type"`
+	DiscountValue    float64    `json:"discount_value"`
+	Zones            []int64    `json:"zones"`
+	StartsAt         *time.Time `json:"starts_at"`
+	EndsAt           *time.Time `json:"ends_at"`
+	UsageLimit       *int       `json:"usage_limit"`
+	PerMerchantLimit *int       `json:"per_merchant_limit"`
+	Active           *bool      `json:"active"`
+}
+
+// validate normalizes the promo code and checks the promotion fields
+func (req *promotionRequest) validate(db sqlx.Queryer) (map[string][]string, error) {
+	errs := make(map[string][]string)
+	if req.Code != nil {
+		code := promotion.NormalizeCode(*req.Code)
+		req.Code = &code
+		if code == "" {
+			req.Code = nil
+		} else if len(code) > 50 {
+			errs["code"] = append(errs["code"], "The code may not be longer than 50 characters.")
+		}
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		errs["name"] = append(errs["name"], "The name field is required.")
+	}
+	switch req.DiscountType {
+	case models.DiscountTypePercent:
+		if req.DiscountValue <= 0 || req.DiscountValue > 100 {
+			errs["discount_value"] = append(errs["discount_value"], "A percent discount must be greater than 0 and at most 100.")
+		}
+	case models.DiscountTypeFlat:
+		if req.DiscountValue <= 0 {
+			errs["discount_value"] = append(errs["discount_value"], "The discount value must be greater than 0.")
+		}
+	default:
+		errs["discount_type"] = append(errs["discount_type"], "The discount type must be percent or flat.")
+	}
+	for i, zone := range req.Zones {
+		if zone <= 0 {
+			field := fmt.Sprintf("zones.%d", i)
+			errs[field] = append(errs[field], "Invalid zone selected")
+		}
+	}
+	if req.StartsAt != nil && req.EndsAt != nil && !req.EndsAt.After(*req.StartsAt) {
+		errs["ends_at"] = append(errs["ends_at"], "The end must be after the start.")
+	}
+	if req.UsageLimit != nil && *req.UsageLimit < 1 {
+		errs["usage_limit"] = append(errs["usage_limit"], "The usage limit must be at least 1.")
+	}
+	if req.PerMerchantLimit != nil && *req.PerMerchantLimit < 1 {
+		errs["per_merchant_limit"] = append(errs["per_merchant_limit"], "The per merchant limit must be at least 1.")
+	}
+	if req.UserID != nil {
+		var role string
+		err := sqlx.Get(db, &role, `SELECT role FROM users WHERE id = $1`, *req.UserID)
+		if err == sql.ErrNoRows || (err == nil && role != models.RoleMerchant) {
+			errs["user_id"] = append(errs["user_id"], "The merchant was not found.")
+		} else if err != nil {
+			return errs, err
+		}
+	}
+	if req.Zones == nil {
+		req.Zones = []int64{}
+	}
+	if req.Active == nil {
+		active := true
+		req.Active = &active
+	}
+	return errs, nil
+}
+
+// CreatePromotionHandler creates a promo code, or an automatic promotion when no code is given (ops only).
+// A user_id restricts it to that merchant and zones to orders for those recipient zones.
+func CreatePromotionHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req promotionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		user, ok := requireRole(w, r, db, models.RoleOps)
+		if !ok {
+			return
+		}
+
+		errs, err := req.validate(db)
+		if err != nil {
+			log.Printf("Failed to validate promotion: %v", err)
+			http.Error(w, "Failed to create promotion", http.StatusInternalServerError)
+			return
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+
+		var p models.Promotion
+		err = db.Get(&p, `
+			INSERT INTO promotions (code, name, user_id, discount_type, discount_value, zones, starts_at, ends_at, usage_limit, per_merchant_limit, active, created_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+			RETURNING *
+		`, req.Code, strings.TrimSpace(req.Name), req.UserID, req.DiscountType, req.DiscountValue, pq.Array(req.Zones),
+			req.StartsAt, req.EndsAt, req.UsageLimit, req.PerMerchantLimit, *req.Active, user.ID)
+		if isUniqueViolation(err, "promotions_code_key") {
+			writeValidationErrors(w, map[string][]string{"code": {"The code has already been taken."}})
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to create promotion: %v", err)
+			http.Error(w, "Failed to create promotion", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusCreated, "Promotion created successfully.", p)
+	}
+}
+
+// ListPromotionsHandler lists promotions, newest first, optionally only those of user_id or that are active (ops only)
+func ListPromotionsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := requireRole(w, r, db, models.RoleOps); !ok {
+			return
+		}
+		userID, _ := strconv.Atoi(r.URL.Query().Get("user_id"))
+		activeOnly := r.URL.Query().Get("active") == "true"
+
+		page, perPage, offset := parsePagination(r)
+		promotions := []models.Promotion{}
+		err := db.Select(&promotions, `
+			SELECT * FROM promotions
+			WHERE ($1 = 0 OR user_id = $1) AND (NOT $2 OR active)
+			ORDER BY id DESC
+			LIMIT $3 OFFSET $4
+		`, userID, activeOnly, perPage, offset)
+		if err != nil {
+			log.Printf("Failed to fetch promotions: %v", err)
+			http.Error(w, "Failed to fetch promotions", http.StatusInternalServerError)
+			return
+		}
+
+		var total int
+		err = db.Get(&total, `SELECT COUNT(*) FROM promotions WHERE ($1 = 0 OR user_id = $1) AND (NOT $2 OR active)`, userID, activeOnly)
+		if err != nil {
+			log.Printf("Error counting promotions: %v", err)
+			http.Error(w, "Failed to calculate pagination", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Promotions successfully fetched.", newPaginatedResponse(promotions, len(promotions), total, page, perPage))
+	}
+}
+
+// UpdatePromotionHandler replaces a promotion's settings, for example to end or deactivate it (ops only).
+// Orders it was already applied to keep their discount.
+func UpdatePromotionHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		promotionID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+		var req promotionRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := requireRole(w, r, db, models.RoleOps); !ok {
+			return
+		}
+
+		errs, err := req.validate(db)
+		if err != nil {
+			log.Printf("Failed to validate promotion: %v", err)
+			http.Error(w, "Failed to update promotion", http.StatusInternalServerError)
+			return
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+
+		var p models.Promotion
+		err = db.Get(&p, `
+			UPDATE promotions
+			SET code = $2, name = $3, user_id = $4, discount_type = $5, discount_value = $6, zones = $7, starts_at = $8, ends_at = $9,
+			    usage_limit = $10, per_merchant_limit = $11, active = $12, updated_at = NOW()
+			WHERE id = $1
+			RETURNING *
+		`, promotionID, req.Code, strings.TrimSpace(req.Name), req.UserID, req.DiscountType, req.DiscountValue, pq.Array(req.Zones),
+			req.StartsAt, req.EndsAt, req.UsageLimit, req.PerMerchantLimit, *req.Active)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Promotion not found", http.StatusNotFound)
+			return
+		}
+		if isUniqueViolation(err, "promotions_code_key") {
+			writeValidationErrors(w, map[string][]string{"code": {"The code has already been taken."}})
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to update promotion: %v", err)
+			http.Error(w, "Failed to update promotion", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Promotion updated successfully.", p)
+	}
+}
@@ -0,0 +1,281 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"go-application-task/internal/models"
+	"go-application-task/pkg/storage"
+	"io"
+	"log"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+)
+
+// maxPODUploadSize caps the size of a proof of delivery upload, both images included
+const maxPODUploadSize = 10 << 20
+
+// imageTypes maps the accepted image content types to the extension they are stored with
+var imageTypes = map[string]string{
+	"image/jpeg": ".jpg",
+	"image/png":  ".png",
+}
+
+// podDeliveredStatuses are the order statuses in which proof of delivery can be recorded
+var podDeliveredStatuses = []string{
+	models.OrderStatusOutForDelivery,
+	models.OrderStatusCompleted,
+	models.OrderStatusPartiallyDelivered,
+}
+
+// podFileURL is where the merchant can download a proof of delivery image
+func podFileURL(consignmentID, kind string) string {
+	return fmt.Sprintf("/orders/%s/pod/%s", consignmentID, kind)
+}
+
+// getProofOfDelivery fetches the proof of delivery of an order with its image URLs filled in
+func getProofOfDelivery(db sqlx.Queryer, consignmentID string) (models.ProofOfDelivery, error) {
+	var pod models.ProofOfDelivery
+	if err := sqlx.Get(db, &pod, `SELECT * FROM proof_of_deliveries WHERE consignment_id = $1`, consignmentID); err != nil {
+		return pod, err
+	}
+	if pod.PhotoKey != nil {
+		pod.PhotoURL = podFileURL(consignmentID, "photo")
+	}
+	if pod.SignatureKey != nil {
+		pod.SignatureURL = podFileURL(consignmentID, "signature")
+	}
+	return pod, nil
+}
+
+// errUnsupportedType is returned by storeFile for uploads of a type that is not accepted
+var errUnsupportedType = errors.New("the file type is not supported")
+
+// storeFile checks an uploaded file is one of types and stores it under keyPrefix, returning its blob key
+func storeFile(ctx context.Context, store storage.BlobStore, file io.Reader, keyPrefix string, types map[string]string) (string, error) {
+	// Sniff the content type rather than trusting the client
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF {
+		return "", err
+	}
+	ext, ok := types[http.DetectContentType(head[:n])]
+	if !ok {
+		return "", errUnsupportedType
+	}
+
+	key := fmt.Sprintf("%s-%d%s", keyPrefix, time.Now().UnixNano(), ext)
+	return key, store.Put(ctx, key, io.MultiReader(bytes.NewReader(head[:n]), file))
+}
+
+// storeImage stores an uploaded JPEG or PNG image under keyPrefix, returning its blob key
+func storeImage(ctx context.Context, store storage.BlobStore, file io.Reader, keyPrefix string) (string, error) {
+	return storeFile(ctx, store, file, keyPrefix, imageTypes)
+}
+
+// storePODImage stores the image uploaded under field as proof of delivery, returning its blob key.
+// It returns an empty key when no file was uploaded under field.
+func storePODImage(r *http.Request, store storage.BlobStore, consignmentID, field string) (string, error) {
+	file, _, err := r.FormFile(field)
+	if err == http.ErrMissingFile {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+	return storeImage(r.Context(), store, file, fmt.Sprintf("pod/%s/%s", consignmentID, field))
+}
+
+// serveFile writes a stored file to w with the content type of its extension
+func serveFile(w http.ResponseWriter, r *http.Request, store storage.BlobStore, key, notFound string) {
+	file, err := store.Open(r.Context(), key)
+	if err == storage.ErrNotFound {
+		http.Error(w, notFound, http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("Failed to open %s: %v", key, err)
+		http.Error(w, "Failed to fetch file", http.StatusInternalServerError)
+		return
+	}
+	defer file.Close()
+
+	contentType := "application/octet-stream"
+	if ext := path.Ext(key); ext != "" {
+		if value := mime.TypeByExtension(ext); value != "" {
+			contentType = value
+		}
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", "private, max-age=3600")
+	if _, err := io.Copy(w, file); err != nil {
+		log.Printf("Failed to write %s: %v", key, err)
+	}
+}
+
+// ProofOfDeliveryHandler records the proof of delivery for a parcel assigned to the caller (riders only).
+// It takes a multipart form with a photo and/or signature image, latitude and longitude.
+// The recipient's delivery code is checked when the parcel is marked delivered, not here.
+func ProofOfDeliveryHandler(db *sqlx.DB, store storage.BlobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		consignmentID := mux.Vars(r)["consignment_id"]
+
+		rider, ok := requireRider(w, r, db)
+		if !ok {
+			return
+		}
+
+		r.Body = http.MaxBytesReader(w, r.Body, maxPODUploadSize)
+		if err := r.ParseMultipartForm(maxPODUploadSize); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		defer r.MultipartForm.RemoveAll()
+
+		errs := make(map[string][]string)
+		latitude, err := strconv.ParseFloat(r.FormValue("latitude"), 64)
+		if err != nil || latitude < -90 || latitude > 90 {
+			errs["latitude"] = append(errs["latitude"], "The latitude must be a number between -90 and 90.")
+		}
+		longitude, err := strconv.ParseFloat(r.FormValue("longitude"), 64)
+		if err != nil || longitude < -180 || longitude > 180 {
+			errs["longitude"] = append(errs["longitude"], "The longitude must be a number between -180 and 180.")
+		}
+		if !hasFile(r.MultipartForm, "photo") && !hasFile(r.MultipartForm, "signature") {
+			errs["photo"] = append(errs["photo"], "A photo or a signature is required.")
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+
+		var order models.Order
+		err = db.Get(&order, `SELECT `+orderColumns+` FROM orders WHERE consignment_id = $1`, consignmentID)
+		if err == sql.ErrNoRows || (err == nil && (order.RiderID == nil || *order.RiderID != rider.ID)) {
+			http.Error(w, "Order not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Order retrieval error: %v", err)
+			http.Error(w, "Failed to fetch order", http.StatusInternalServerError)
+			return
+		}
+		delivered := false
+		for _, status := range podDeliveredStatuses {
+			delivered = delivered || order.OrderStatus == status
+		}
+		if !delivered {
+			http.Error(w, fmt.Sprintf("Proof of delivery cannot be recorded for an order in status %s", order.OrderStatus), http.StatusConflict)
+			return
+		}
+
+		// Evidence already on record must never be overwritten
+		var recorded bool
+		if err := db.Get(&recorded, `SELECT EXISTS(SELECT 1 FROM proof_of_deliveries WHERE consignment_id = $1)`, consignmentID); err != nil {
+			log.Printf("Proof of delivery lookup error: %v", err)
+			http.Error(w, "Failed to record proof of delivery", http.StatusInternalServerError)
+			return
+		}
+		if recorded {
+			http.Error(w, "Proof of delivery has already been recorded", http.StatusConflict)
+			return
+		}
+
+		keys := make(map[string]*string)
+		for _, field := range []string{"photo", "signature"} {
+			key, err := storePODImage(r, store, consignmentID, field)
+			if err == errUnsupportedType {
+				writeValidationErrors(w, map[string][]string{field: {"The " + field + " must be a JPEG or PNG image."}})
+				return
+			}
+			if err != nil {
+				log.Printf("Failed to store %s: %v", field, err)
+				http.Error(w, "Failed to store proof of delivery", http.StatusInternalServerError)
+				return
+			}
+			if key != "" {
+				keys[field] = &key
+			}
+		}
+
+		_, err = db.Exec(`
+			INSERT INTO proof_of_deliveries (consignment_id, rider_id, photo_key, signature_key, latitude, longitude)
+			VALUES ($1, $2, $3, $4, $5, $6)
+		`, consignmentID, rider.ID, keys["photo"], keys["signature"], latitude, longitude)
+		if isUniqueViolation(err, "proof_of_deliveries_consignment_id_key") {
+			http.Error(w, "Proof of delivery has already been recorded", http.StatusConflict)
+			return
+		}
+		var pod models.ProofOfDelivery
+		if err == nil {
+			pod, err = getProofOfDelivery(db, consignmentID)
+		}
+		if err != nil {
+			log.Printf("Failed to record proof of delivery: %v", err)
+			http.Error(w, "Failed to record proof of delivery", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusCreated, "Proof of delivery recorded successfully.", pod)
+	}
+}
+
+// hasFile reports whether a non-empty file was uploaded under field
+func hasFile(form *multipart.Form, field string) bool {
+	files := form.File[field]
+	return len(files) > 0 && files[0].Size > 0
+}
+
+// ProofOfDeliveryFileHandler serves a proof of delivery photo or signature to the order's merchant or to ops
+func ProofOfDeliveryFileHandler(db *sqlx.DB, store storage.BlobStore) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		consignmentID := mux.Vars(r)["consignment_id"]
+		kind := mux.Vars(r)["kind"]
+
+		user, ok := requireRole(w, r, db, models.RoleMerchant, models.RoleOps)
+		if !ok {
+			return
+		}
+
+		var pod struct {
+			UserID       int     `db:"user_id"`
+			PhotoKey     *string `db:"photo_key"`
+			SignatureKey *string `db:"signature_key"`
+		}
+		err := db.Get(&pod, `
+			SELECT o.user_id, p.photo_key, p.signature_key
+			FROM proof_of_deliveries p
+			JOIN orders o ON o.consignment_id = p.consignment_id
+			WHERE p.consignment_id = $1
+		`, consignmentID)
+		if err == sql.ErrNoRows || (err == nil && user.Role != models.RoleOps && pod.UserID != user.ID) {
+			http.Error(w, "Proof of delivery not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Proof of delivery retrieval error: %v", err)
+			http.Error(w, "Failed to fetch proof of delivery", http.StatusInternalServerError)
+			return
+		}
+
+		key := pod.PhotoKey
+		if kind == "signature" {
+			key = pod.SignatureKey
+		}
+		if key == nil {
+			http.Error(w, "Proof of delivery not found", http.StatusNotFound)
+			return
+		}
+
+		serveFile(w, r, store, *key, "Proof of delivery not found")
+	}
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"go-application-task/internal/models"
+	"log"
+	"net/http"
+	"os"
+)
+
+// QuoteHandler prices a parcel without creating an order.
+// It accepts the same recipient_city, recipient_zone, weight, dimension, items, amount_to_collect and promo_code fields
+// as order creation, and applies the same promotions.
+func QuoteHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		userID, err := GetUserIDFromToken(r, os.Getenv("JWT_SECRET"), db)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var order models.Order
+		if err := json.NewDecoder(r.Body).Decode(&order); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		order.ApplyItemTotals()
+		order.UserID = userID
+
+		errors := validateParcel(&order)
+		if order.RecipientCity == 0 {
+			errors["recipient_city"] = append(errors["recipient_city"], "The recipient city field is required.")
+		}
+		if order.AmountToCollect < 0 {
+			errors["amount_to_collect"] = append(errors["amount_to_collect"], "The amount to collect cannot be negative.")
+		}
+		if len(errors) > 0 {
+			writeValidationErrors(w, errors)
+			return
+		}
+
+		quote, err := quoteOrder(db, &order)
+		if err == nil {
+			errors, err = applyPromotion(db, &order, &quote)
+		}
+		if err != nil {
+			log.Printf("Failed to calculate quote: %v", err)
+			http.Error(w, "Failed to calculate quote", http.StatusInternalServerError)
+			return
+		}
+		if len(errors) > 0 {
+			writeValidationErrors(w, errors)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Quote calculated successfully.", quote)
+	}
+}
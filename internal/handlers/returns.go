@@ -0,0 +1,287 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"go-application-task/configs"
+	"go-application-task/internal/ledger"
+	"go-application-task/internal/models"
+	"go-application-task/pkg/db"
+	"go-application-task/pkg/utils"
+	"log"
+	"math"
+	"net/http"
+)
+
+// returnTransitions lists the statuses a return may move to from each status
+var returnTransitions = map[string]string{
+	models.ReturnStatusInitiated: models.ReturnStatusInTransit,
+	models.ReturnStatusInTransit: models.ReturnStatusReturned,
+}
+
+// insertReturn stores a return under a newly generated consignment ID, retrying on collisions with orders and returns.
+// Each attempt runs in a savepoint so a collision does not abort the surrounding transaction.
+func insertReturn(tx *sqlx.Tx, orderReturn *models.OrderReturn, cityID int) error {
+	var err error
+	for attempt := 1; attempt <= consignmentIDAttempts; attempt++ {
+		orderReturn.ReturnConsignmentID, err = utils.GenerateConsignmentID(utils.CityCode(cityID))
+		if err != nil {
+			return err
+		}
+		taken, err := consignmentIDTaken(tx, orderReturn.ReturnConsignmentID)
+		if err != nil {
+			return err
+		}
+		if taken {
+			err = errConsignmentIDTaken
+			log.Printf("Return consignment ID %s already exists, retrying (attempt %d)", orderReturn.ReturnConsignmentID, attempt)
+			continue
+		}
+
+		if _, err = tx.Exec(`SAVEPOINT insert_return`); err != nil {
+			return err
+		}
+		err = tx.Get(orderReturn, `
+			INSERT INTO order_returns (consignment_id, return_consignment_id, reason, return_charge, initiated_by)
+			VALUES ($1, $2, $3, $4, $5)
+			RETURNING *
+		`, orderReturn.ConsignmentID, orderReturn.ReturnConsignmentID, orderReturn.Reason, orderReturn.ReturnCharge, orderReturn.InitiatedBy)
+		if err == nil {
+			_, err = tx.Exec(`RELEASE SAVEPOINT insert_return`)
+			return err
+		}
+		if _, rollbackErr := tx.Exec(`ROLLBACK TO SAVEPOINT insert_return`); rollbackErr != nil {
+			return rollbackErr
+		}
+		if !db.IsUniqueViolation(err, "order_returns_return_consignment_id_key") {
+			return err
+		}
+		log.Printf("Return consignment ID %s already exists, retrying (attempt %d)", orderReturn.ReturnConsignmentID, attempt)
+	}
+	return err
+}
+
+// openReturn opens the return consignment that brings an order's parcel back to the store.
+// An exchange already has a return leg for the old item from when it was booked, and an order can only have one
+// return, so while that leg has not left it is reused to carry the parcel back as well.
+func openReturn(tx *sqlx.Tx, orderReturn *models.OrderReturn, order models.Order) error {
+	if order.OrderType == models.OrderTypeExchange {
+		err := tx.Get(orderReturn, `
+			UPDATE order_returns
+			SET reason = LEFT(reason || '; ' || $2, 255), return_charge = $3, initiated_by = $4, updated_at = NOW()
+			WHERE consignment_id = $1 AND status = $5
+			RETURNING *
+		`, order.ConsignmentID, orderReturn.Reason, orderReturn.ReturnCharge, orderReturn.InitiatedBy, models.ReturnStatusInitiated)
+		if err != sql.ErrNoRows {
+			return err
+		}
+	}
+	return insertReturn(tx, orderReturn, order.RecipientCity)
+}
+
+// InitiateReturnHandler marks a refused order as return-initiated and creates its return consignment (ops only)
+func InitiateReturnHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		consignmentID := mux.Vars(r)["consignment_id"]
+
+		var req struct {
+			Reason string `json:"reason"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+		if req.Reason == "" {
+			writeValidationErrors(w, map[string][]string{"reason": {"The reason field is required."}})
+			return
+		}
+
+		user, ok := requireRole(w, r, db, models.RoleOps)
+		if !ok {
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			log.Printf("Failed to start transaction: %v", err)
+			http.Error(w, "Failed to initiate return", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		var order models.Order
+		err = tx.Get(&order, `SELECT `+orderColumns+` FROM orders WHERE consignment_id = $1 FOR UPDATE`, consignmentID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Order not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Order retrieval error: %v", err)
+			http.Error(w, "Failed to fetch order", http.StatusInternalServerError)
+			return
+		}
+
+		err = changeOrderStatus(tx, &order, models.OrderStatusReturnInitiated)
+		var invalid errInvalidTransition
+		if errors.As(err, &invalid) {
+			http.Error(w, fmt.Sprintf("Order in status %s cannot be returned", invalid.From), http.StatusConflict)
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to update order status: %v", err)
+			http.Error(w, "Failed to initiate return", http.StatusInternalServerError)
+			return
+		}
+
+		orderReturn := models.OrderReturn{
+			ConsignmentID: consignmentID,
+			Reason:        req.Reason,
+			ReturnCharge:  math.Round(order.DeliveryFee*configs.GetReturnChargePercent()) / 100,
+			InitiatedBy:   &user.ID,
+		}
+		err = openReturn(tx, &orderReturn, order)
+		if err == nil {
+			err = ledger.PostReturnCharge(tx, order, orderReturn.ReturnCharge)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			log.Printf("Failed to create return: %v", err)
+			http.Error(w, "Failed to initiate return", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusCreated, "Return initiated successfully.", orderReturn)
+	}
+}
+
+// UpdateReturnStatusHandler moves a return along its way back to the store (ops only).
+// Once a refused parcel is back at the store the original order becomes returned.
+func UpdateReturnStatusHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		returnConsignmentID := mux.Vars(r)["return_consignment_id"]
+
+		var req struct {
+			Status string `json:"status"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := requireRole(w, r, db, models.RoleOps); !ok {
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			log.Printf("Failed to start transaction: %v", err)
+			http.Error(w, "Failed to update return", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		var orderReturn models.OrderReturn
+		err = tx.Get(&orderReturn, `SELECT * FROM order_returns WHERE return_consignment_id = $1 FOR UPDATE`, returnConsignmentID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Return not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Return retrieval error: %v", err)
+			http.Error(w, "Failed to fetch return", http.StatusInternalServerError)
+			return
+		}
+
+		if returnTransitions[orderReturn.Status] != req.Status {
+			http.Error(w, fmt.Sprintf("Return in status %s cannot move to %s", orderReturn.Status, req.Status), http.StatusConflict)
+			return
+		}
+
+		err = tx.Get(&orderReturn, `
+			UPDATE order_returns
+			SET status = $2, updated_at = NOW(), returned_at = CASE WHEN $2 = 'returned' THEN NOW() ELSE returned_at END
+			WHERE id = $1
+			RETURNING *
+		`, orderReturn.ID, req.Status)
+		if err == nil && req.Status == models.ReturnStatusReturned {
+			var order models.Order
+			err = tx.Get(&order, `SELECT `+orderColumns+` FROM orders WHERE consignment_id = $1 FOR UPDATE`, orderReturn.ConsignmentID)
+			// Items sent back after a partial delivery leave the order partially delivered
+			if err == nil && order.OrderStatus == models.OrderStatusReturnInitiated {
+				err = changeOrderStatus(tx, &order, models.OrderStatusReturned)
+			}
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			log.Printf("Failed to update return: %v", err)
+			http.Error(w, "Failed to update return", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Return updated successfully.", orderReturn)
+	}
+}
+
+// ListReturnsHandler lists returns, merchants see the returns of their own orders and ops see all of them
+func ListReturnsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		user, ok := requireRole(w, r, db, models.RoleMerchant, models.RoleOps)
+		if !ok {
+			return
+		}
+
+		page, perPage, offset := parsePagination(r)
+		status := r.URL.Query().Get("status")
+
+		// Ops see every merchant's returns
+		scopeUserID := user.ID
+		if user.Role == models.RoleOps {
+			scopeUserID = 0
+		}
+
+		type returnRow struct {
+			models.OrderReturn
+			StoreID         int     `json:"store_id" db:"store_id"`
+			MerchantOrderID *string `json:"merchant_order_id,omitempty" db:"merchant_order_id"`
+			RecipientName   string  `json:"recipient_name" db:"recipient_name"`
+			AmountToCollect float64 `json:"amount_to_collect" db:"amount_to_collect"`
+		}
+		returns := []returnRow{}
+		err := db.Select(&returns, `
+			SELECT r.*, o.store_id, o.merchant_order_id, o.recipient_name, o.amount_to_collect
+			FROM order_returns r
+			JOIN orders o ON o.consignment_id = r.consignment_id
+			WHERE ($1 = 0 OR o.user_id = $1) AND ($2 = '' OR r.status = $2)
+			ORDER BY r.id DESC
+			LIMIT $3 OFFSET $4
+		`, scopeUserID, status, perPage, offset)
+		if err != nil {
+			log.Printf("Failed to fetch returns: %v", err)
+			http.Error(w, "Failed to fetch returns", http.StatusInternalServerError)
+			return
+		}
+
+		var total int
+		err = db.Get(&total, `
+			SELECT COUNT(*)
+			FROM order_returns r
+			JOIN orders o ON o.consignment_id = r.consignment_id
+			WHERE ($1 = 0 OR o.user_id = $1) AND ($2 = '' OR r.status = $2)
+		`, scopeUserID, status)
+		if err != nil {
+			log.Printf("Error counting returns: %v", err)
+			http.Error(w, "Failed to calculate pagination", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Returns successfully fetched.", newPaginatedResponse(returns, len(returns), total, page, perPage))
+	}
+}
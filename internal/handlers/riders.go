@@ -0,0 +1,500 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go-application-task/configs"
+	"go-application-task/internal/ledger"
+	"go-application-task/internal/models"
+	"go-application-task/pkg/db"
+	"golang.org/x/crypto/bcrypt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// riderColumns selects a rider together with the email of their user account
+const riderColumns = `riders.id, riders.user_id, users.email, riders.name, riders.phone, riders.home_hub, riders.active, riders.created_at, riders.updated_at`
+
+// assignableStatuses are the order statuses in which a rider can still be assigned
+var assignableStatuses = []string{
+	models.OrderStatusPending,
+	models.OrderStatusPickupRequested,
+	models.OrderStatusPicked,
+	models.OrderStatusOutForDelivery,
+	models.OrderStatusDeliveryFailed,
+}
+
+// riderStatuses maps the statuses a rider reports to the order status they move the order to
+var riderStatuses = map[string]string{
+	"picked":           models.OrderStatusPicked,
+	"out_for_delivery": models.OrderStatusOutForDelivery,
+	"delivered":        models.OrderStatusCompleted,
+	"failed":           models.OrderStatusDeliveryFailed,
+}
+
+// getRider fetches a rider by ID
+func getRider(db sqlx.Queryer, riderID int) (models.Rider, error) {
+	var rider models.Rider
+	err := sqlx.Get(db, &rider, `SELECT `+riderColumns+` FROM riders JOIN users ON users.id = riders.user_id WHERE riders.id = $1`, riderID)
+	return rider, err
+}
+
+// requireRider authenticates the caller as an active rider.
+// It writes the error response and returns false when they are not.
+func requireRider(w http.ResponseWriter, r *http.Request, db *sqlx.DB) (models.Rider, bool) {
+	var rider models.Rider
+	user, ok := requireRole(w, r, db, models.RoleRider)
+	if !ok {
+		return rider, false
+	}
+	err := db.Get(&rider, `SELECT `+riderColumns+` FROM riders JOIN users ON users.id = riders.user_id WHERE riders.user_id = $1`, user.ID)
+	if err == sql.ErrNoRows || (err == nil && !rider.Active) {
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return rider, false
+	}
+	if err != nil {
+		log.Printf("Rider retrieval error: %v", err)
+		http.Error(w, "Failed to fetch rider", http.StatusInternalServerError)
+		return rider, false
+	}
+	return rider, true
+}
+
+// CreateRiderHandler creates a rider together with their user account (ops only)
+func CreateRiderHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Email    string `json:"email"`
+			Password string `json:"password"`
+			Name     string `json:"name"`
+			Phone    string `json:"phone"`
+			HomeHub  string `json:"home_hub"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := requireRole(w, r, db, models.RoleOps); !ok {
+			return
+		}
+
+		errs := make(map[string][]string)
+		if req.Email == "" {
+			errs["email"] = append(errs["email"], "The email field is required.")
+		}
+		if len(req.Password) < 6 {
+			errs["password"] = append(errs["password"], "The password must be at least 6 characters.")
+		}
+		if req.Name == "" {
+			errs["name"] = append(errs["name"], "The name field is required.")
+		}
+		if !validatePhone(req.Phone) {
+			errs["phone"] = append(errs["phone"], "The phone must be a valid Bangladesh phone number.")
+		}
+		if req.HomeHub == "" {
+			errs["home_hub"] = append(errs["home_hub"], "The home hub field is required.")
+		} else if exists, err := hubExists(db, req.HomeHub); err != nil {
+			log.Printf("Hub lookup error: %v", err)
+			http.Error(w, "Failed to create rider", http.StatusInternalServerError)
+			return
+		} else if !exists {
+			errs["home_hub"] = append(errs["home_hub"], "The home hub was not found.")
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+
+		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if err != nil {
+			log.Printf("Failed to hash rider password: %v", err)
+			http.Error(w, "Failed to create rider", http.StatusInternalServerError)
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			log.Printf("Failed to start transaction: %v", err)
+			http.Error(w, "Failed to create rider", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		var userID, riderID int
+		err = tx.Get(&userID, `INSERT INTO users (email, password, role) VALUES ($1, $2, $3) RETURNING id`, req.Email, string(hashedPassword), models.RoleRider)
+		if isUniqueViolation(err, "users_email_key") {
+			writeValidationErrors(w, map[string][]string{"email": {"The email has already been taken."}})
+			return
+		}
+		if err == nil {
+			err = tx.Get(&riderID, `
+				INSERT INTO riders (user_id, name, phone, home_hub)
+				VALUES ($1, $2, $3, $4)
+				RETURNING id
+			`, userID, req.Name, req.Phone, req.HomeHub)
+		}
+		var rider models.Rider
+		if err == nil {
+			rider, err = getRider(tx, riderID)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			log.Printf("Failed to create rider: %v", err)
+			http.Error(w, "Failed to create rider", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusCreated, "Rider created successfully.", rider)
+	}
+}
+
+// isUniqueViolation reports whether err is a unique violation on the given constraint.
+// Handlers use it where their db parameter shadows the db package.
+func isUniqueViolation(err error, constraint string) bool {
+	return db.IsUniqueViolation(err, constraint)
+}
+
+// ListRidersHandler lists riders, optionally filtered by home_hub and active (ops only)
+func ListRidersHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := requireRole(w, r, db, models.RoleOps); !ok {
+			return
+		}
+
+		page, perPage, offset := parsePagination(r)
+		homeHub := r.URL.Query().Get("home_hub")
+		active := r.URL.Query().Get("active")
+		if active != "" && active != "true" && active != "false" {
+			http.Error(w, "invalid active, expected true or false", http.StatusBadRequest)
+			return
+		}
+
+		where := `WHERE ($1 = '' OR riders.home_hub = $1) AND ($2 = '' OR riders.active = ($2 = 'true'))`
+
+		riders := []models.Rider{}
+		err := db.Select(&riders, `
+			SELECT `+riderColumns+`
+			FROM riders JOIN users ON users.id = riders.user_id
+			`+where+`
+			ORDER BY riders.name, riders.id
+			LIMIT $3 OFFSET $4
+		`, homeHub, active, perPage, offset)
+		if err != nil {
+			log.Printf("Failed to fetch riders: %v", err)
+			http.Error(w, "Failed to fetch riders", http.StatusInternalServerError)
+			return
+		}
+
+		var total int
+		if err := db.Get(&total, `SELECT COUNT(*) FROM riders `+where, homeHub, active); err != nil {
+			log.Printf("Error counting riders: %v", err)
+			http.Error(w, "Failed to calculate pagination", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Riders successfully fetched.", newPaginatedResponse(riders, len(riders), total, page, perPage))
+	}
+}
+
+// UpdateRiderHandler changes a rider's details, home hub or active flag (ops only).
+// Fields left out of the request are kept.
+func UpdateRiderHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		riderID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+		var req struct {
+			Name    *string `json:"name"`
+			Phone   *string `json:"phone"`
+			HomeHub *string `json:"home_hub"`
+			Active  *bool   `json:"active"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := requireRole(w, r, db, models.RoleOps); !ok {
+			return
+		}
+
+		errs := make(map[string][]string)
+		if req.Name != nil && *req.Name == "" {
+			errs["name"] = append(errs["name"], "The name cannot be empty.")
+		}
+		if req.Phone != nil && !validatePhone(*req.Phone) {
+			errs["phone"] = append(errs["phone"], "The phone must be a valid Bangladesh phone number.")
+		}
+		if req.HomeHub != nil {
+			if exists, err := hubExists(db, *req.HomeHub); err != nil {
+				log.Printf("Hub lookup error: %v", err)
+				http.Error(w, "Failed to update rider", http.StatusInternalServerError)
+				return
+			} else if !exists {
+				errs["home_hub"] = append(errs["home_hub"], "The home hub was not found.")
+			}
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+
+		result, err := db.Exec(`
+			UPDATE riders
+			SET name = COALESCE($2, name), phone = COALESCE($3, phone), home_hub = COALESCE($4, home_hub),
+				active = COALESCE($5, active), updated_at = NOW()
+			WHERE id = $1
+		`, riderID, req.Name, req.Phone, req.HomeHub, req.Active)
+		if err != nil {
+			log.Printf("Failed to update rider: %v", err)
+			http.Error(w, "Failed to update rider", http.StatusInternalServerError)
+			return
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			http.Error(w, "Rider not found", http.StatusNotFound)
+			return
+		}
+
+		rider, err := getRider(db, riderID)
+		if err != nil {
+			log.Printf("Rider retrieval error: %v", err)
+			http.Error(w, "Failed to fetch rider", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Rider updated successfully.", rider)
+	}
+}
+
+// AssignOrderHandler assigns an order to a rider, or moves it to another rider (ops only)
+func AssignOrderHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		consignmentID := mux.Vars(r)["consignment_id"]
+
+		var req struct {
+			RiderID int `json:"rider_id"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		user, ok := requireRole(w, r, db, models.RoleOps)
+		if !ok {
+			return
+		}
+
+		if req.RiderID == 0 {
+			writeValidationErrors(w, map[string][]string{"rider_id": {"The rider field is required."}})
+			return
+		}
+		rider, err := getRider(db, req.RiderID)
+		if err == sql.ErrNoRows || (err == nil && !rider.Active) {
+			writeValidationErrors(w, map[string][]string{"rider_id": {"The rider was not found or is inactive."}})
+			return
+		}
+		if err != nil {
+			log.Printf("Rider retrieval error: %v", err)
+			http.Error(w, "Failed to fetch rider", http.StatusInternalServerError)
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			log.Printf("Failed to start transaction: %v", err)
+			http.Error(w, "Failed to assign order", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		var order models.Order
+		err = tx.Get(&order, `SELECT `+orderColumns+` FROM orders WHERE consignment_id = $1 FOR UPDATE`, consignmentID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Order not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Order retrieval error: %v", err)
+			http.Error(w, "Failed to fetch order", http.StatusInternalServerError)
+			return
+		}
+
+		assignable := false
+		for _, status := range assignableStatuses {
+			assignable = assignable || order.OrderStatus == status
+		}
+		if !assignable {
+			http.Error(w, fmt.Sprintf("Order in status %s cannot be assigned", order.OrderStatus), http.StatusConflict)
+			return
+		}
+
+		_, err = tx.Exec(`UPDATE orders SET rider_id = $2, assigned_at = NOW() WHERE consignment_id = $1`, consignmentID, rider.ID)
+		if err == nil {
+			_, err = tx.Exec(`
+				INSERT INTO rider_assignments (consignment_id, rider_id, previous_rider_id, assigned_by)
+				VALUES ($1, $2, $3, $4)
+			`, consignmentID, rider.ID, order.RiderID, user.ID)
+		}
+		if err == nil {
+			err = tx.Get(&order, `SELECT `+orderColumns+` FROM orders WHERE consignment_id = $1`, consignmentID)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			log.Printf("Failed to assign order: %v", err)
+			http.Error(w, "Failed to assign order", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Order assigned successfully.", map[string]interface{}{
+			"consignment_id": order.ConsignmentID,
+			"order_status":   order.OrderStatus,
+			"rider":          rider,
+			"assigned_at":    order.AssignedAt,
+		})
+	}
+}
+
+// RiderParcelsHandler lists the caller's parcels for today: everything assigned to them today
+// and anything assigned earlier that is still on its way (riders only)
+func RiderParcelsHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		rider, ok := requireRider(w, r, db)
+		if !ok {
+			return
+		}
+
+		// The operations day follows the pickup time zone
+		location := configs.GetPickupConfig().Location
+		now := time.Now().In(location)
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, location)
+
+		orders := []models.Order{}
+		err := db.Select(&orders, `
+			SELECT `+orderColumns+`
+			FROM orders
+			WHERE rider_id = $1 AND (assigned_at >= $2 OR order_status::text = ANY($3))
+			ORDER BY assigned_at, consignment_id
+		`, rider.ID, today.UTC(), pq.Array(assignableStatuses))
+		if err != nil {
+			log.Printf("Failed to fetch rider parcels: %v", err)
+			http.Error(w, "Failed to fetch parcels", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Parcels successfully fetched.", orders)
+	}
+}
+
+// RiderUpdateStatusHandler records a rider's progress with a parcel assigned to them (riders only).
+// Riders report picked, out_for_delivery, delivered or failed; a failed attempt needs a reason.
+// Delivering a parcel that has a delivery code needs the code the recipient received.
+func RiderUpdateStatusHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		consignmentID := mux.Vars(r)["consignment_id"]
+
+		var req struct {
+			Status string `json:"status"`
+			Reason string `json:"reason"`
+			OTP    string `json:"otp"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		rider, ok := requireRider(w, r, db)
+		if !ok {
+			return
+		}
+
+		status, known := riderStatuses[req.Status]
+		if !known {
+			writeValidationErrors(w, map[string][]string{"status": {"The status must be picked, out_for_delivery, delivered or failed."}})
+			return
+		}
+		if status == models.OrderStatusDeliveryFailed && req.Reason == "" {
+			writeValidationErrors(w, map[string][]string{"reason": {"The reason field is required for a failed attempt."}})
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			log.Printf("Failed to start transaction: %v", err)
+			http.Error(w, "Failed to update order status", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		var order models.Order
+		err = tx.Get(&order, `SELECT `+orderColumns+` FROM orders WHERE consignment_id = $1 FOR UPDATE`, consignmentID)
+		if err == sql.ErrNoRows || (err == nil && (order.RiderID == nil || *order.RiderID != rider.ID)) {
+			http.Error(w, "Order not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Order retrieval error: %v", err)
+			http.Error(w, "Failed to fetch order", http.StatusInternalServerError)
+			return
+		}
+
+		if status == models.OrderStatusCompleted && canTransition(order.OrderStatus, status) {
+			err = verifyDeliveryOTP(tx, consignmentID, rider.ID, req.OTP)
+			var wrong errWrongOTP
+			var locked errOTPLocked
+			if errors.As(err, &wrong) || errors.As(err, &locked) {
+				// Keep the failed attempt on record even though the delivery is refused
+				if commitErr := tx.Commit(); commitErr != nil {
+					log.Printf("Failed to record delivery OTP attempt: %v", commitErr)
+				}
+				if errors.As(err, &locked) {
+					http.Error(w, "Too many wrong delivery codes, try again after "+locked.Until.Format(time.RFC3339), http.StatusTooManyRequests)
+					return
+				}
+				writeValidationErrors(w, map[string][]string{"otp": {fmt.Sprintf("The delivery code is wrong, %d attempts left.", wrong.AttemptsLeft)}})
+				return
+			}
+			if err != nil {
+				log.Printf("Failed to verify delivery OTP: %v", err)
+				http.Error(w, "Failed to update order status", http.StatusInternalServerError)
+				return
+			}
+		}
+
+		err = changeOrderStatus(tx, &order, status)
+		var invalid errInvalidTransition
+		if errors.As(err, &invalid) {
+			http.Error(w, fmt.Sprintf("Order in status %s cannot be marked %s", invalid.From, req.Status), http.StatusConflict)
+			return
+		}
+		if err == nil && status == models.OrderStatusDeliveryFailed {
+			_, err = tx.Exec(`INSERT INTO delivery_attempts (consignment_id, rider_id, reason) VALUES ($1, $2, $3)`, consignmentID, rider.ID, req.Reason)
+		}
+		if err == nil && status == models.OrderStatusCompleted {
+			err = ledger.PostDelivery(tx, order, order.AmountToCollect)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			log.Printf("Failed to update order status: %v", err)
+			http.Error(w, "Failed to update order status", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Order status updated successfully.", map[string]interface{}{
+			"consignment_id": order.ConsignmentID,
+			"order_status":   order.OrderStatus,
+		})
+	}
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"go-application-task/internal/models"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// effectiveSurcharges returns the surcharges in effect now, oldest first
+func effectiveSurcharges(db sqlx.Queryer) ([]models.Surcharge, error) {
+	var surcharges []models.Surcharge
+	err := sqlx.Select(db, &surcharges, `
+		SELECT * FROM surcharges
+		WHERE effective_from <= NOW() AND (effective_to IS NULL OR effective_to > NOW())
+		ORDER BY id
+	`)
+	return surcharges, err
+}
+
+// surchargeRequest is the body of the create and update surcharge endpoints
+type surchargeRequest struct {
+	Name          string     `json:"name"`
+	Kind          string     `json:"kind"`
+	Calculation   string     `json:"calculation"`
+	Value         float64    `json:"value"`
+	EffectiveFrom *time.Time `json:"effective_from"`
+	EffectiveTo   *time.Time `json:"effective_to"`
+}
+
+// validate checks the surcharge fields
+func (req *surchargeRequest) validate() map[string][]string {
+	errs := make(map[string][]string)
+	if strings.TrimSpace(req.Name) == "" {
+		errs["name"] = append(errs["name"], "The name field is required.")
+	}
+	if req.Kind != models.SurchargeKindFuel && req.Kind != models.SurchargeKindPeak {
+		errs["kind"] = append(errs["kind"], "The kind must be fuel or peak.")
+	}
+	switch req.Calculation {
+	case models.SurchargeCalculationPercent:
+		if req.Value <= 0 || req.Value > 100 {
+			errs["value"] = append(errs["value"], "A percent surcharge must be greater than 0 and at most 100.")
+		}
+	case models.SurchargeCalculationFlat:
+		if req.Value <= 0 {
+			errs["value"] = append(errs["value"], "The value must be greater than 0.")
+		}
+	default:
+		errs["calculation"] = append(errs["calculation"], "The calculation must be percent or flat.")
+	}
+	if req.EffectiveFrom == nil {
+		errs["effective_from"] = append(errs["effective_from"], "The effective from field is required.")
+	} else if req.EffectiveTo != nil && !req.EffectiveTo.After(*req.EffectiveFrom) {
+		errs["effective_to"] = append(errs["effective_to"], "The effective to must be after the effective from.")
+	}
+	return errs
+}
+
+// CreateSurchargeHandler adds a fuel or peak surcharge for orders created in its effective period (ops only)
+func CreateSurchargeHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req surchargeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		user, ok := requireRole(w, r, db, models.RoleOps)
+		if !ok {
+			return
+		}
+
+		if errs := req.validate(); len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+
+		var surcharge models.Surcharge
+		err := db.Get(&surcharge, `
+			INSERT INTO surcharges (name, kind, calculation, value, effective_from, effective_to, created_by)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			RETURNING *
+		`, strings.TrimSpace(req.Name), req.Kind, req.Calculation, req.Value, req.EffectiveFrom, req.EffectiveTo, user.ID)
+		if err != nil {
+			log.Printf("Failed to create surcharge: %v", err)
+			http.Error(w, "Failed to create surcharge", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusCreated, "Surcharge created successfully.", surcharge)
+	}
+}
+
+// ListSurchargesHandler lists surcharges, newest first, or only those in effect now with effective=true (ops only)
+func ListSurchargesHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := requireRole(w, r, db, models.RoleOps); !ok {
+			return
+		}
+		effectiveOnly := r.URL.Query().Get("effective") == "true"
+		where := `NOT $1 OR (effective_from <= NOW() AND (effective_to IS NULL OR effective_to > NOW()))`
+
+		page, perPage, offset := parsePagination(r)
+		surcharges := []models.Surcharge{}
+		err := db.Select(&surcharges, `SELECT * FROM surcharges WHERE `+where+` ORDER BY id DESC LIMIT $2 OFFSET $3`, effectiveOnly, perPage, offset)
+		if err != nil {
+			log.Printf("Failed to fetch surcharges: %v", err)
+			http.Error(w, "Failed to fetch surcharges", http.StatusInternalServerError)
+			return
+		}
+
+		var total int
+		err = db.Get(&total, `SELECT COUNT(*) FROM surcharges WHERE `+where, effectiveOnly)
+		if err != nil {
+			log.Printf("Error counting surcharges: %v", err)
+			http.Error(w, "Failed to calculate pagination", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Surcharges successfully fetched.", newPaginatedResponse(surcharges, len(surcharges), total, page, perPage))
+	}
+}
+
+// UpdateSurchargeHandler replaces a surcharge's settings, for example to end it (ops only).
+// Orders already created keep the surcharge they were charged.
+func UpdateSurchargeHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		surchargeID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+		var req surchargeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		if _, ok := requireRole(w, r, db, models.RoleOps); !ok {
+			return
+		}
+
+		if errs := req.validate(); len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+
+		var surcharge models.Surcharge
+		err := db.Get(&surcharge, `
+			UPDATE surcharges
+			SET name = $2, kind = $3, calculation = $4, value = $5, effective_from = $6, effective_to = $7, updated_at = NOW()
+			WHERE id = $1
+			RETURNING *
+		`, surchargeID, strings.TrimSpace(req.Name), req.Kind, req.Calculation, req.Value, req.EffectiveFrom, req.EffectiveTo)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Surcharge not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to update surcharge: %v", err)
+			http.Error(w, "Failed to update surcharge", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Surcharge updated successfully.", surcharge)
+	}
+}
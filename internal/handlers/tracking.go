@@ -0,0 +1,57 @@
+package handlers
+
+import (
+	"database/sql"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"go-application-task/internal/models"
+	"go-application-task/pkg/utils"
+	"log"
+	"net/http"
+	"time"
+)
+
+// TrackOrderHandler is the public tracking page linked from labels and SMS messages.
+// It shows where a parcel is without exposing recipient or payment details.
+func TrackOrderHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		consignmentID := mux.Vars(r)["consignment_id"]
+		if !utils.ValidateConsignmentID(consignmentID) {
+			http.Error(w, "Order not found", http.StatusNotFound)
+			return
+		}
+
+		var tracking struct {
+			ConsignmentID      string  `json:"consignment_id" db:"consignment_id"`
+			OrderStatus        string  `json:"order_status" db:"order_status"`
+			TransferStatus     int     `json:"transfer_status" db:"transfer_status"`
+			TransferStatusName string  `json:"transfer_status_name" db:"-"`
+			CurrentHub         *string `json:"current_hub,omitempty" db:"current_hub"`
+			History            []struct {
+				Status    string    `json:"status" db:"status"`
+				CreatedAt time.Time `json:"created_at" db:"created_at"`
+			} `json:"history" db:"-"`
+		}
+		err := db.Get(&tracking, `
+			SELECT o.consignment_id, o.order_status, o.transfer_status, h.name AS current_hub
+			FROM orders o
+			LEFT JOIN hubs h ON h.id = o.current_hub_id
+			WHERE o.consignment_id = $1 AND o.archive = 0
+		`, consignmentID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Order not found", http.StatusNotFound)
+			return
+		}
+		if err == nil {
+			err = db.Select(&tracking.History, `SELECT status, created_at FROM order_status_history WHERE consignment_id = $1 ORDER BY id`, consignmentID)
+		}
+		if err != nil {
+			log.Printf("Tracking retrieval error: %v", err)
+			http.Error(w, "Failed to fetch order", http.StatusInternalServerError)
+			return
+		}
+		tracking.TransferStatusName = models.TransferStatusNames[tracking.TransferStatus]
+
+		writeResponse(w, http.StatusOK, "Order successfully tracked.", tracking)
+	}
+}
@@ -0,0 +1,376 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"go-application-task/internal/models"
+	"log"
+	"net/http"
+	"strconv"
+)
+
+// getManifest fetches a transfer manifest with its items
+func getManifest(db sqlx.Queryer, manifestID int) (models.TransferManifest, error) {
+	var manifest models.TransferManifest
+	if err := sqlx.Get(db, &manifest, `SELECT * FROM transfer_manifests WHERE id = $1`, manifestID); err != nil {
+		return manifest, err
+	}
+	err := sqlx.Select(db, &manifest.Items, `SELECT * FROM transfer_manifest_items WHERE manifest_id = $1 ORDER BY consignment_id`, manifestID)
+	return manifest, err
+}
+
+// lockManifest locks a transfer manifest and loads its items
+func lockManifest(tx *sqlx.Tx, manifestID int) (models.TransferManifest, error) {
+	var manifest models.TransferManifest
+	if err := tx.Get(&manifest, `SELECT * FROM transfer_manifests WHERE id = $1 FOR UPDATE`, manifestID); err != nil {
+		return manifest, err
+	}
+	return getManifest(tx, manifestID)
+}
+
+// CreateTransferHandler creates a manifest of parcels to move from one hub to another (ops only).
+// Every parcel must be held by the origin hub and not be on another open manifest.
+func CreateTransferHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			OriginHubID      int      `json:"origin_hub_id"`
+			DestinationHubID int      `json:"destination_hub_id"`
+			ConsignmentIDs   []string `json:"consignment_ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		user, ok := requireRole(w, r, db, models.RoleOps)
+		if !ok {
+			return
+		}
+
+		errs := make(map[string][]string)
+		for field, hubID := range map[string]int{"origin_hub_id": req.OriginHubID, "destination_hub_id": req.DestinationHubID} {
+			hub, err := getHub(db, hubID)
+			if err == sql.ErrNoRows || (err == nil && !hub.Active) {
+				errs[field] = append(errs[field], "The hub was not found or is inactive.")
+			} else if err != nil {
+				log.Printf("Hub retrieval error: %v", err)
+				http.Error(w, "Failed to fetch hub", http.StatusInternalServerError)
+				return
+			}
+		}
+		if req.OriginHubID == req.DestinationHubID {
+			errs["destination_hub_id"] = append(errs["destination_hub_id"], "The destination hub must differ from the origin hub.")
+		}
+		if len(req.ConsignmentIDs) == 0 {
+			errs["consignment_ids"] = append(errs["consignment_ids"], "At least one parcel is required.")
+		} else if len(req.ConsignmentIDs) > maxScanParcels {
+			errs["consignment_ids"] = append(errs["consignment_ids"], fmt.Sprintf("At most %d parcels can be transferred at once.", maxScanParcels))
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			log.Printf("Failed to start transaction: %v", err)
+			http.Error(w, "Failed to create transfer", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		orders, errs, err := lockScannedOrders(tx, req.ConsignmentIDs)
+		if err != nil {
+			log.Printf("Order retrieval error: %v", err)
+			http.Error(w, "Failed to fetch orders", http.StatusInternalServerError)
+			return
+		}
+		for _, order := range orders {
+			field := "consignment_ids." + order.ConsignmentID
+			if order.TransferStatus != models.TransferStatusAtHub || order.CurrentHubID == nil || *order.CurrentHubID != req.OriginHubID {
+				errs[field] = append(errs[field], "The parcel is not at the origin hub.")
+				continue
+			}
+			var open bool
+			err = tx.Get(&open, `
+				SELECT EXISTS(
+					SELECT 1 FROM transfer_manifest_items i
+					JOIN transfer_manifests m ON m.id = i.manifest_id
+					WHERE i.consignment_id = $1 AND m.status = $2
+				)
+			`, order.ConsignmentID, models.ManifestStatusCreated)
+			if err != nil {
+				log.Printf("Manifest lookup error: %v", err)
+				http.Error(w, "Failed to create transfer", http.StatusInternalServerError)
+				return
+			}
+			if open {
+				errs[field] = append(errs[field], "The parcel is already on an open transfer.")
+			}
+		}
+		if len(errs) > 0 {
+			writeValidationErrors(w, errs)
+			return
+		}
+
+		var manifestID int
+		err = tx.Get(&manifestID, `
+			INSERT INTO transfer_manifests (origin_hub_id, destination_hub_id, created_by)
+			VALUES ($1, $2, $3)
+			RETURNING id
+		`, req.OriginHubID, req.DestinationHubID, user.ID)
+		for _, order := range orders {
+			if err != nil {
+				break
+			}
+			_, err = tx.Exec(`INSERT INTO transfer_manifest_items (manifest_id, consignment_id) VALUES ($1, $2)`, manifestID, order.ConsignmentID)
+		}
+		var manifest models.TransferManifest
+		if err == nil {
+			manifest, err = getManifest(tx, manifestID)
+		}
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			log.Printf("Failed to create transfer: %v", err)
+			http.Error(w, "Failed to create transfer", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusCreated, "Transfer created successfully.", manifest)
+	}
+}
+
+// ListTransfersHandler lists transfer manifests touching a hub, optionally filtered by status (ops only)
+func ListTransfersHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := requireRole(w, r, db, models.RoleOps); !ok {
+			return
+		}
+
+		page, perPage, offset := parsePagination(r)
+		status := r.URL.Query().Get("status")
+		var hubID int
+		if hub := r.URL.Query().Get("hub_id"); hub != "" {
+			id, err := strconv.Atoi(hub)
+			if err != nil {
+				http.Error(w, "invalid hub_id", http.StatusBadRequest)
+				return
+			}
+			hubID = id
+		}
+
+		where := `WHERE ($1 = 0 OR origin_hub_id = $1 OR destination_hub_id = $1) AND ($2 = '' OR status = $2)`
+
+		type manifestRow struct {
+			models.TransferManifest
+			ParcelCount int `json:"parcel_count" db:"parcel_count"`
+		}
+		manifests := []manifestRow{}
+		err := db.Select(&manifests, `
+			SELECT m.*, (SELECT COUNT(*) FROM transfer_manifest_items i WHERE i.manifest_id = m.id) AS parcel_count
+			FROM transfer_manifests m
+			`+where+`
+			ORDER BY m.id DESC
+			LIMIT $3 OFFSET $4
+		`, hubID, status, perPage, offset)
+		if err != nil {
+			log.Printf("Failed to fetch transfers: %v", err)
+			http.Error(w, "Failed to fetch transfers", http.StatusInternalServerError)
+			return
+		}
+
+		var total int
+		if err := db.Get(&total, `SELECT COUNT(*) FROM transfer_manifests `+where, hubID, status); err != nil {
+			log.Printf("Error counting transfers: %v", err)
+			http.Error(w, "Failed to calculate pagination", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Transfers successfully fetched.", newPaginatedResponse(manifests, len(manifests), total, page, perPage))
+	}
+}
+
+// GetTransferHandler returns a transfer manifest with the scan times of its parcels (ops only)
+func GetTransferHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		manifestID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+		if _, ok := requireRole(w, r, db, models.RoleOps); !ok {
+			return
+		}
+
+		manifest, err := getManifest(db, manifestID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Transfer not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Transfer retrieval error: %v", err)
+			http.Error(w, "Failed to fetch transfer", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Transfer successfully fetched.", manifest)
+	}
+}
+
+// scanManifest runs a dispatch or receive scan of a manifest's parcels in a transaction.
+// It checks the manifest is in status from, that every scanned parcel is on it, and lets apply record the scan.
+// Validation problems are written as a 422 response, in which case ok is false and err nil.
+func scanManifest(w http.ResponseWriter, db *sqlx.DB, manifestID int, consignmentIDs []string, from string,
+	apply func(tx *sqlx.Tx, manifest *models.TransferManifest, scanned map[string]bool) error) (manifest models.TransferManifest, ok bool, err error) {
+	tx, err := db.Beginx()
+	if err != nil {
+		return manifest, false, err
+	}
+	defer tx.Rollback()
+
+	manifest, err = lockManifest(tx, manifestID)
+	if err == sql.ErrNoRows {
+		http.Error(w, "Transfer not found", http.StatusNotFound)
+		return manifest, false, nil
+	}
+	if err != nil {
+		return manifest, false, err
+	}
+	if manifest.Status != from {
+		http.Error(w, fmt.Sprintf("Transfer in status %s cannot be scanned", manifest.Status), http.StatusConflict)
+		return manifest, false, nil
+	}
+
+	onManifest := make(map[string]bool, len(manifest.Items))
+	for _, item := range manifest.Items {
+		onManifest[item.ConsignmentID] = true
+	}
+	errs := make(map[string][]string)
+	scanned := make(map[string]bool, len(consignmentIDs))
+	for _, consignmentID := range consignmentIDs {
+		if !onManifest[consignmentID] {
+			field := "consignment_ids." + consignmentID
+			errs[field] = append(errs[field], "The parcel is not on this transfer.")
+		}
+		scanned[consignmentID] = true
+	}
+	if len(errs) > 0 {
+		writeValidationErrors(w, errs)
+		return manifest, false, nil
+	}
+
+	// Lock the parcels in a consistent order before moving them
+	if _, _, err = lockScannedOrders(tx, consignmentIDs); err != nil {
+		return manifest, false, err
+	}
+	if err = apply(tx, &manifest, scanned); err != nil {
+		return manifest, false, err
+	}
+	if manifest, err = getManifest(tx, manifestID); err != nil {
+		return manifest, false, err
+	}
+	return manifest, true, tx.Commit()
+}
+
+// DispatchTransferHandler records the parcels scanned onto the vehicle leaving the origin hub (ops only).
+// Parcels on the manifest that were not scanned are dropped from it and stay at the origin hub.
+func DispatchTransferHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		manifestID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+		consignmentIDs, ok := readScannedIDs(w, r)
+		if !ok {
+			return
+		}
+
+		user, ok := requireRole(w, r, db, models.RoleOps)
+		if !ok {
+			return
+		}
+
+		manifest, ok, err := scanManifest(w, db, manifestID, consignmentIDs, models.ManifestStatusCreated,
+			func(tx *sqlx.Tx, manifest *models.TransferManifest, scanned map[string]bool) error {
+				for _, item := range manifest.Items {
+					if !scanned[item.ConsignmentID] {
+						if _, err := tx.Exec(`DELETE FROM transfer_manifest_items WHERE id = $1`, item.ID); err != nil {
+							return err
+						}
+						continue
+					}
+					if _, err := tx.Exec(`UPDATE transfer_manifest_items SET dispatched_at = NOW() WHERE id = $1`, item.ID); err != nil {
+						return err
+					}
+					if err := recordHubScan(tx, item.ConsignmentID, manifest.OriginHubID, models.ScanTypeDispatch, &manifest.ID, user.ID); err != nil {
+						return err
+					}
+				}
+				_, err := tx.Exec(`UPDATE transfer_manifests SET status = $2, dispatched_at = NOW() WHERE id = $1`, manifest.ID, models.ManifestStatusDispatched)
+				return err
+			})
+		if err != nil {
+			log.Printf("Failed to dispatch transfer: %v", err)
+			http.Error(w, "Failed to dispatch transfer", http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Transfer dispatched successfully.", manifest)
+	}
+}
+
+// ReceiveTransferHandler records the parcels scanned in at the destination hub (ops only).
+// A transfer can be received in several scans; it is received once every parcel has arrived.
+func ReceiveTransferHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		manifestID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+		consignmentIDs, ok := readScannedIDs(w, r)
+		if !ok {
+			return
+		}
+
+		user, ok := requireRole(w, r, db, models.RoleOps)
+		if !ok {
+			return
+		}
+
+		manifest, ok, err := scanManifest(w, db, manifestID, consignmentIDs, models.ManifestStatusDispatched,
+			func(tx *sqlx.Tx, manifest *models.TransferManifest, scanned map[string]bool) error {
+				pending := 0
+				for _, item := range manifest.Items {
+					if item.ReceivedAt != nil {
+						continue
+					}
+					if !scanned[item.ConsignmentID] {
+						pending++
+						continue
+					}
+					if _, err := tx.Exec(`UPDATE transfer_manifest_items SET received_at = NOW() WHERE id = $1`, item.ID); err != nil {
+						return err
+					}
+					if err := recordHubScan(tx, item.ConsignmentID, manifest.DestinationHubID, models.ScanTypeReceive, &manifest.ID, user.ID); err != nil {
+						return err
+					}
+				}
+				if pending > 0 {
+					return nil
+				}
+				_, err := tx.Exec(`UPDATE transfer_manifests SET status = $2, received_at = NOW() WHERE id = $1`, manifest.ID, models.ManifestStatusReceived)
+				return err
+			})
+		if err != nil {
+			log.Printf("Failed to receive transfer: %v", err)
+			http.Error(w, "Failed to receive transfer", http.StatusInternalServerError)
+			return
+		}
+		if !ok {
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Transfer received successfully.", manifest)
+	}
+}
@@ -0,0 +1,297 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"github.com/gorilla/mux"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"go-application-task/internal/models"
+	"go-application-task/internal/webhook"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+)
+
+// CreateWebhookHandler registers a webhook endpoint for the caller. The signing secret is only returned here.
+func CreateWebhookHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			URL    string   `json:"url"`
+			Events []string `json:"events"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, fmt.Sprintf("Invalid request body: %v", err), http.StatusBadRequest)
+			return
+		}
+
+		errors := make(map[string][]string)
+		if req.URL == "" {
+			errors["url"] = append(errors["url"], "The url field is required.")
+		} else if u, err := url.Parse(req.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			errors["url"] = append(errors["url"], "The url must be an absolute http or https URL.")
+		}
+		if len(req.Events) == 0 {
+			errors["events"] = append(errors["events"], "At least one event is required.")
+		}
+		for _, event := range req.Events {
+			if !webhook.IsEvent(event) {
+				errors["events"] = append(errors["events"], fmt.Sprintf("Unknown event %s.", event))
+			}
+		}
+		if len(errors) > 0 {
+			writeValidationErrors(w, errors)
+			return
+		}
+
+		// Extract userID from token
+		userID, err := GetUserIDFromToken(r, os.Getenv("JWT_SECRET"), db)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		secret, err := webhook.GenerateSecret()
+		if err != nil {
+			log.Printf("Webhook secret error: %v", err)
+			http.Error(w, "Failed to create webhook", http.StatusInternalServerError)
+			return
+		}
+
+		var endpoint models.WebhookEndpoint
+		err = db.Get(&endpoint, `
+			INSERT INTO webhook_endpoints (user_id, url, secret, events)
+			VALUES ($1, $2, $3, $4)
+			RETURNING *
+		`, userID, req.URL, secret, pq.Array(req.Events))
+		if err != nil {
+			log.Printf("Failed to create webhook: %v", err)
+			http.Error(w, "Failed to create webhook", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusCreated, "Webhook created successfully. Store the secret, it will not be shown again.", endpoint)
+	}
+}
+
+// ListWebhooksHandler lists the caller's webhook endpoints
+func ListWebhooksHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// Extract userID from token
+		userID, err := GetUserIDFromToken(r, os.Getenv("JWT_SECRET"), db)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		endpoints := []models.WebhookEndpoint{}
+		err = db.Select(&endpoints, `
+			SELECT id, user_id, url, events, active, created_at
+			FROM webhook_endpoints
+			WHERE user_id = $1
+			ORDER BY id
+		`, userID)
+		if err != nil {
+			log.Printf("Failed to fetch webhooks: %v", err)
+			http.Error(w, "Failed to fetch webhooks", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Webhooks successfully fetched.", endpoints)
+	}
+}
+
+// DeleteWebhookHandler deactivates a webhook endpoint, its delivery logs are kept
+func DeleteWebhookHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		endpointID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+		// Extract userID from token
+		userID, err := GetUserIDFromToken(r, os.Getenv("JWT_SECRET"), db)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		tx, err := db.Beginx()
+		if err != nil {
+			log.Printf("Failed to start transaction: %v", err)
+			http.Error(w, "Failed to delete webhook", http.StatusInternalServerError)
+			return
+		}
+		defer tx.Rollback()
+
+		result, err := tx.Exec(`UPDATE webhook_endpoints SET active = FALSE WHERE id = $1 AND user_id = $2`, endpointID, userID)
+		if err != nil {
+			log.Printf("Failed to delete webhook: %v", err)
+			http.Error(w, "Failed to delete webhook", http.StatusInternalServerError)
+			return
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			http.Error(w, "Webhook not found", http.StatusNotFound)
+			return
+		}
+
+		// Deliveries still waiting to be sent are dropped along with the endpoint
+		_, err = tx.Exec(`
+			UPDATE webhook_deliveries SET status = $2, next_attempt_at = NULL, updated_at = NOW()
+			WHERE endpoint_id = $1 AND status IN ($3, $4)
+		`, endpointID, models.DeliveryCancelled, models.DeliveryPending, models.DeliveryFailed)
+		if err == nil {
+			err = tx.Commit()
+		}
+		if err != nil {
+			log.Printf("Failed to delete webhook: %v", err)
+			http.Error(w, "Failed to delete webhook", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Webhook deleted successfully.", nil)
+	}
+}
+
+// ListWebhookDeliveriesHandler lists the deliveries of one of the caller's endpoints, optionally filtered by status
+func ListWebhookDeliveriesHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		endpointID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+		// Extract userID from token
+		userID, err := GetUserIDFromToken(r, os.Getenv("JWT_SECRET"), db)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var exists bool
+		err = db.Get(&exists, `SELECT EXISTS(SELECT 1 FROM webhook_endpoints WHERE id = $1 AND user_id = $2)`, endpointID, userID)
+		if err != nil {
+			log.Printf("Failed to fetch webhook: %v", err)
+			http.Error(w, "Failed to fetch deliveries", http.StatusInternalServerError)
+			return
+		}
+		if !exists {
+			http.Error(w, "Webhook not found", http.StatusNotFound)
+			return
+		}
+
+		page, perPage, offset := parsePagination(r)
+		status := r.URL.Query().Get("status")
+
+		deliveries := []models.WebhookDelivery{}
+		err = db.Select(&deliveries, `
+			SELECT * FROM webhook_deliveries
+			WHERE endpoint_id = $1 AND ($2 = '' OR status = $2)
+			ORDER BY id DESC
+			LIMIT $3 OFFSET $4
+		`, endpointID, status, perPage, offset)
+		if err != nil {
+			log.Printf("Failed to fetch deliveries: %v", err)
+			http.Error(w, "Failed to fetch deliveries", http.StatusInternalServerError)
+			return
+		}
+
+		var total int
+		err = db.Get(&total, `SELECT COUNT(*) FROM webhook_deliveries WHERE endpoint_id = $1 AND ($2 = '' OR status = $2)`, endpointID, status)
+		if err != nil {
+			log.Printf("Error counting deliveries: %v", err)
+			http.Error(w, "Failed to calculate pagination", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Deliveries successfully fetched.", newPaginatedResponse(deliveries, len(deliveries), total, page, perPage))
+	}
+}
+
+// getUserDelivery fetches a delivery that belongs to one of the user's endpoints
+func getUserDelivery(db *sqlx.DB, deliveryID, userID int) (models.WebhookDelivery, error) {
+	var delivery models.WebhookDelivery
+	err := db.Get(&delivery, `
+		SELECT d.* FROM webhook_deliveries d
+		JOIN webhook_endpoints e ON e.id = d.endpoint_id
+		WHERE d.id = $1 AND e.user_id = $2
+	`, deliveryID, userID)
+	return delivery, err
+}
+
+// GetWebhookDeliveryHandler shows a delivery with the log of all its attempts
+func GetWebhookDeliveryHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		deliveryID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+		// Extract userID from token
+		userID, err := GetUserIDFromToken(r, os.Getenv("JWT_SECRET"), db)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		delivery, err := getUserDelivery(db, deliveryID, userID)
+		if err == sql.ErrNoRows {
+			http.Error(w, "Delivery not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("Failed to fetch delivery: %v", err)
+			http.Error(w, "Failed to fetch delivery", http.StatusInternalServerError)
+			return
+		}
+
+		attempts := []models.WebhookDeliveryAttempt{}
+		err = db.Select(&attempts, `SELECT * FROM webhook_delivery_attempts WHERE delivery_id = $1 ORDER BY id`, deliveryID)
+		if err != nil {
+			log.Printf("Failed to fetch delivery attempts: %v", err)
+			http.Error(w, "Failed to fetch delivery", http.StatusInternalServerError)
+			return
+		}
+
+		writeResponse(w, http.StatusOK, "Delivery successfully fetched.", map[string]interface{}{
+			"delivery": delivery,
+			"attempts": attempts,
+		})
+	}
+}
+
+// RedeliverWebhookHandler queues a delivery to be sent again immediately, including dead-lettered ones
+func RedeliverWebhookHandler(db *sqlx.DB) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		deliveryID, _ := strconv.Atoi(mux.Vars(r)["id"])
+
+		// Extract userID from token
+		userID, err := GetUserIDFromToken(r, os.Getenv("JWT_SECRET"), db)
+		if err != nil {
+			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if _, err := getUserDelivery(db, deliveryID, userID); err == sql.ErrNoRows {
+			http.Error(w, "Delivery not found", http.StatusNotFound)
+			return
+		} else if err != nil {
+			log.Printf("Failed to fetch delivery: %v", err)
+			http.Error(w, "Failed to redeliver", http.StatusInternalServerError)
+			return
+		}
+
+		// Attempts restart from zero so a dead-lettered delivery gets the full retry schedule again
+		result, err := db.Exec(`
+			UPDATE webhook_deliveries d
+			SET status = $2, attempts = 0, next_attempt_at = NOW(), updated_at = NOW()
+			FROM webhook_endpoints e
+			WHERE d.id = $1 AND e.id = d.endpoint_id AND e.active
+		`, deliveryID, models.DeliveryPending)
+		if err != nil {
+			log.Printf("Failed to redeliver: %v", err)
+			http.Error(w, "Failed to redeliver", http.StatusInternalServerError)
+			return
+		}
+		if affected, _ := result.RowsAffected(); affected == 0 {
+			http.Error(w, "The webhook has been deleted", http.StatusConflict)
+			return
+		}
+
+		writeResponse(w, http.StatusAccepted, "Delivery queued for redelivery.", nil)
+	}
+}
@@ -0,0 +1,92 @@
+package ledger
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+
+	"go-application-task/internal/models"
+)
+
+// Tx is the part of *sqlx.Tx the ledger writes through
+type Tx interface {
+	Get(dest interface{}, query string, args ...interface{}) error
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// Posting debits one account and credits another with the same amount on behalf of a merchant
+type Posting struct {
+	EntryType     string
+	UserID        int
+	ConsignmentID *string
+	PayoutID      *int
+	Debit         string
+	Credit        string
+	Amount        float64
+}
+
+// Post records a posting as a balanced ledger transaction. Pass the transaction performing the mutation
+// that moved the money so the ledger never disagrees with it.
+// A consignment is charged each entry type once; posting it again is a no-op. Zero amounts are skipped.
+func Post(tx Tx, p Posting) error {
+	amount := math.Round(p.Amount*100) / 100
+	if amount == 0 {
+		return nil
+	}
+	if amount < 0 {
+		return fmt.Errorf("negative %s posting of %.2f", p.EntryType, amount)
+	}
+
+	var transactionID int64
+	err := tx.Get(&transactionID, `
+		INSERT INTO ledger_transactions (entry_type, user_id, consignment_id, payout_id)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (consignment_id, entry_type) WHERE consignment_id IS NOT NULL DO NOTHING
+		RETURNING id
+	`, p.EntryType, p.UserID, p.ConsignmentID, p.PayoutID)
+	if err == sql.ErrNoRows {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to post %s: %w", p.EntryType, err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO ledger_entries (transaction_id, account, debit, credit)
+		VALUES ($1, $2, $4, 0), ($1, $3, 0, $4)
+	`, transactionID, p.Debit, p.Credit, amount)
+	if err != nil {
+		return fmt.Errorf("failed to post %s: %w", p.EntryType, err)
+	}
+	return nil
+}
+
+// PostDelivery credits the merchant with the cash collected for a delivered order less its delivery fee, surcharges and COD fee
+func PostDelivery(tx Tx, order models.Order, collected float64) error {
+	consignmentID := &order.ConsignmentID
+	postings := []Posting{
+		{EntryType: models.EntryCODCollected, Debit: models.AccountCODReceivable, Credit: models.AccountMerchantPayable, Amount: collected},
+		{EntryType: models.EntryDeliveryFee, Debit: models.AccountMerchantPayable, Credit: models.AccountFeeRevenue, Amount: order.DeliveryFee},
+		{EntryType: models.EntrySurcharge, Debit: models.AccountMerchantPayable, Credit: models.AccountFeeRevenue, Amount: order.Surcharge},
+		{EntryType: models.EntryCODFee, Debit: models.AccountMerchantPayable, Credit: models.AccountFeeRevenue, Amount: order.CODFee},
+	}
+	for _, p := range postings {
+		p.UserID, p.ConsignmentID = order.UserID, consignmentID
+		if err := Post(tx, p); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PostReturnCharge debits the merchant with the charge for sending a parcel back to the store
+func PostReturnCharge(tx Tx, order models.Order, charge float64) error {
+	return Post(tx, Posting{
+		EntryType:     models.EntryReturnCharge,
+		UserID:        order.UserID,
+		ConsignmentID: &order.ConsignmentID,
+		Debit:         models.AccountMerchantPayable,
+		Credit:        models.AccountFeeRevenue,
+		Amount:        charge,
+	})
+}
@@ -0,0 +1,222 @@
+package ledger
+
+import (
+	"database/sql"
+	"errors"
+	"math"
+	"testing"
+
+	"go-application-task/internal/models"
+)
+
+type entry struct {
+	account string
+	debit   float64
+	credit  float64
+}
+
+// fakeTx keeps ledger rows in memory and enforces the (consignment_id, entry_type) unique index like Postgres does
+type fakeTx struct {
+	transactions map[string]int64
+	entries      []entry
+}
+
+func newFakeTx() *fakeTx {
+	return &fakeTx{transactions: map[string]int64{}}
+}
+
+func (f *fakeTx) Get(dest interface{}, query string, args ...interface{}) error {
+	entryType := args[0].(string)
+	if consignmentID, ok := args[2].(*string); ok && consignmentID != nil {
+		key := *consignmentID + "/" + entryType
+		if _, exists := f.transactions[key]; exists {
+			return sql.ErrNoRows
+		}
+		f.transactions[key] = int64(len(f.transactions) + 1)
+	}
+	*dest.(*int64) = int64(len(f.transactions))
+	return nil
+}
+
+func (f *fakeTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	debit, credit, amount := args[1].(string), args[2].(string), args[3].(float64)
+	f.entries = append(f.entries, entry{account: debit, debit: amount}, entry{account: credit, credit: amount})
+	return nil, nil
+}
+
+// balance returns the credit balance of an account
+func (f *fakeTx) balance(account string) float64 {
+	var total float64
+	for _, e := range f.entries {
+		if e.account == account {
+			total += e.credit - e.debit
+		}
+	}
+	return math.Round(total*100) / 100
+}
+
+func (f *fakeTx) totals() (debits, credits float64) {
+	for _, e := range f.entries {
+		debits += e.debit
+		credits += e.credit
+	}
+	return debits, credits
+}
+
+func consignment(id string) *string {
+	return &id
+}
+
+func TestPost(t *testing.T) {
+	tests := []struct {
+		name     string
+		postings []Posting
+
+		entries int
+		err     bool
+	}{
+		{
+			name: "balanced posting",
+			postings: []Posting{
+				{EntryType: models.EntryDeliveryFee, ConsignmentID: consignment("C1"), Debit: models.AccountMerchantPayable, Credit: models.AccountFeeRevenue, Amount: 60},
+			},
+			entries: 2,
+		},
+		{
+			name: "same consignment and entry type posted twice",
+			postings: []Posting{
+				{EntryType: models.EntryDeliveryFee, ConsignmentID: consignment("C1"), Debit: models.AccountMerchantPayable, Credit: models.AccountFeeRevenue, Amount: 60},
+				{EntryType: models.EntryDeliveryFee, ConsignmentID: consignment("C1"), Debit: models.AccountMerchantPayable, Credit: models.AccountFeeRevenue, Amount: 60},
+			},
+			entries: 2,
+		},
+		{
+			name: "different entry types on one consignment",
+			postings: []Posting{
+				{EntryType: models.EntryDeliveryFee, ConsignmentID: consignment("C1"), Debit: models.AccountMerchantPayable, Credit: models.AccountFeeRevenue, Amount: 60},
+				{EntryType: models.EntryCODFee, ConsignmentID: consignment("C1"), Debit: models.AccountMerchantPayable, Credit: models.AccountFeeRevenue, Amount: 10},
+			},
+			entries: 4,
+		},
+		{
+			name: "postings without a consignment are never deduplicated",
+			postings: []Posting{
+				{EntryType: models.EntryPayoutPaid, Debit: models.AccountPayoutsPayable, Credit: models.AccountCash, Amount: 500},
+				{EntryType: models.EntryPayoutPaid, Debit: models.AccountPayoutsPayable, Credit: models.AccountCash, Amount: 500},
+			},
+			entries: 4,
+		},
+		{
+			name: "zero amount is skipped",
+			postings: []Posting{
+				{EntryType: models.EntrySurcharge, ConsignmentID: consignment("C1"), Debit: models.AccountMerchantPayable, Credit: models.AccountFeeRevenue, Amount: 0.001},
+			},
+			entries: 0,
+		},
+		{
+			name: "negative amount is rejected",
+			postings: []Posting{
+				{EntryType: models.EntryCODFee, ConsignmentID: consignment("C1"), Debit: models.AccountMerchantPayable, Credit: models.AccountFeeRevenue, Amount: -5},
+			},
+			err: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := newFakeTx()
+			var err error
+			for _, p := range tt.postings {
+				if err = Post(tx, p); err != nil {
+					break
+				}
+			}
+			if (err != nil) != tt.err {
+				t.Fatalf("Post error = %v, want error %v", err, tt.err)
+			}
+			if len(tx.entries) != tt.entries {
+				t.Errorf("entries = %d, want %d", len(tx.entries), tt.entries)
+			}
+			if debits, credits := tx.totals(); debits != credits {
+				t.Errorf("debits %v != credits %v", debits, credits)
+			}
+		})
+	}
+}
+
+func TestPostDelivery(t *testing.T) {
+	tests := []struct {
+		name      string
+		order     models.Order
+		collected float64
+
+		merchant float64
+		revenue  float64
+	}{
+		{
+			name:      "COD order",
+			order:     models.Order{ConsignmentID: "C1", UserID: 7, DeliveryFee: 60, Surcharge: 6, CODFee: 10},
+			collected: 1000,
+			merchant:  924,
+			revenue:   76,
+		},
+		{
+			name:      "prepaid order leaves the merchant owing the fees",
+			order:     models.Order{ConsignmentID: "C2", UserID: 7, DeliveryFee: 60},
+			collected: 0,
+			merchant:  -60,
+			revenue:   60,
+		},
+		{
+			name:      "fees are rounded to cents",
+			order:     models.Order{ConsignmentID: "C3", UserID: 7, DeliveryFee: 60.004, Surcharge: 0.333, CODFee: 12.5},
+			collected: 500.001,
+			merchant:  427.17,
+			revenue:   72.83,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tx := newFakeTx()
+			if err := PostDelivery(tx, tt.order, tt.collected); err != nil {
+				t.Fatalf("PostDelivery: %v", err)
+			}
+			if got := tx.balance(models.AccountMerchantPayable); got != tt.merchant {
+				t.Errorf("merchant payable = %v, want %v", got, tt.merchant)
+			}
+			if got := tx.balance(models.AccountFeeRevenue); got != tt.revenue {
+				t.Errorf("fee revenue = %v, want %v", got, tt.revenue)
+			}
+			if debits, credits := tx.totals(); math.Abs(debits-credits) > 1e-9 {
+				t.Errorf("debits %v != credits %v", debits, credits)
+			}
+
+			// Redelivering the same order must not charge the merchant twice
+			before := len(tx.entries)
+			if err := PostDelivery(tx, tt.order, tt.collected); err != nil {
+				t.Fatalf("PostDelivery again: %v", err)
+			}
+			if len(tx.entries) != before {
+				t.Errorf("second PostDelivery added %d entries", len(tx.entries)-before)
+			}
+		})
+	}
+}
+
+func TestPostPropagatesErrors(t *testing.T) {
+	err := Post(failingTx{}, Posting{EntryType: models.EntryPayout, Debit: models.AccountMerchantPayable, Credit: models.AccountPayoutsPayable, Amount: 1})
+	if !errors.Is(err, errFailing) {
+		t.Errorf("Post error = %v, want %v", err, errFailing)
+	}
+}
+
+var errFailing = errors.New("connection reset")
+
+type failingTx struct{}
+
+func (failingTx) Get(dest interface{}, query string, args ...interface{}) error { return errFailing }
+
+func (failingTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	return nil, errFailing
+}
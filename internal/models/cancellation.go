@@ -0,0 +1,23 @@
+package models
+
+import "time"
+
+// CancellationReason is one of the reasons, managed by ops, an order may be cancelled for
+type CancellationReason struct {
+	ID        int       `json:"id" db:"id"`
+	Code      string    `json:"code" db:"code"`
+	Label     string    `json:"label" db:"label"`
+	Active    bool      `json:"active" db:"active"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// OrderCancellation records why and by whom an order was cancelled
+type OrderCancellation struct {
+	ID            int       `json:"id" db:"id"`
+	ConsignmentID string    `json:"consignment_id" db:"consignment_id"`
+	ReasonCode    string    `json:"reason_code" db:"reason_code"`
+	Note          *string   `json:"note,omitempty" db:"note"`
+	CancelledBy   *int      `json:"cancelled_by,omitempty" db:"cancelled_by"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,45 @@
+package models
+
+import "time"
+
+// Reconciliation statuses, comparing a rider's deposits with the COD they collected
+const (
+	CashStatusBalanced = "balanced"
+	CashStatusShort    = "short"
+	CashStatusOver     = "over"
+)
+
+// CashDeposit is cash a rider handed over to a hub cashier for a business day
+type CashDeposit struct {
+	ID           int       `json:"id" db:"id"`
+	RiderID      int       `json:"rider_id" db:"rider_id"`
+	HubID        int       `json:"hub_id" db:"hub_id"`
+	BusinessDate string    `json:"business_date" db:"business_date"`
+	Amount       float64   `json:"amount" db:"amount"`
+	Note         *string   `json:"note,omitempty" db:"note"`
+	RecordedBy   *int      `json:"recorded_by,omitempty" db:"recorded_by"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// CashReconciliation compares what a rider deposited for a day with the COD they collected on delivery that day
+type CashReconciliation struct {
+	RiderID    int     `json:"rider_id" db:"rider_id"`
+	RiderName  string  `json:"rider_name" db:"rider_name"`
+	CODOrders  int     `json:"cod_orders" db:"cod_orders"`
+	Expected   float64 `json:"expected" db:"expected"`
+	Deposited  float64 `json:"deposited" db:"deposited"`
+	Difference float64 `json:"difference" db:"difference"`
+	Status     string  `json:"status" db:"-"`
+}
+
+// CashReconciliationReport is the day end reconciliation of every rider based at a hub
+type CashReconciliationReport struct {
+	Hub             Hub                  `json:"hub"`
+	BusinessDate    string               `json:"business_date"`
+	Expected        float64              `json:"expected"`
+	Deposited       float64              `json:"deposited"`
+	Difference      float64              `json:"difference"`
+	Shortages       int                  `json:"shortages"`
+	Overages        int                  `json:"overages"`
+	Reconciliations []CashReconciliation `json:"reconciliations"`
+}
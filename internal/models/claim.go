@@ -0,0 +1,54 @@
+package models
+
+import "time"
+
+// Claim types
+const (
+	ClaimTypeLost    = "lost"
+	ClaimTypeDamaged = "damaged"
+)
+
+// Claim statuses
+const (
+	ClaimStatusOpen          = "open"
+	ClaimStatusInvestigating = "investigating"
+	ClaimStatusApproved      = "approved"
+	ClaimStatusRejected      = "rejected"
+)
+
+// Claim is a merchant's request for compensation for a lost or damaged parcel
+type Claim struct {
+	ID                 int             `json:"id" db:"id"`
+	ConsignmentID      string          `json:"consignment_id" db:"consignment_id"`
+	UserID             int             `json:"user_id" db:"user_id"`
+	ClaimType          string          `json:"claim_type" db:"claim_type"`
+	Description        string          `json:"description" db:"description"`
+	Status             string          `json:"status" db:"status"`
+	RequestedAmount    *float64        `json:"requested_amount,omitempty" db:"requested_amount"`
+	CompensationAmount *float64        `json:"compensation_amount,omitempty" db:"compensation_amount"`
+	DecidedBy          *int            `json:"decided_by,omitempty" db:"decided_by"`
+	DecidedAt          *time.Time      `json:"decided_at,omitempty" db:"decided_at"`
+	CreatedAt          time.Time       `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time       `json:"updated_at" db:"updated_at"`
+	Evidence           []ClaimEvidence `json:"evidence,omitempty" db:"-"`
+	Comments           []ClaimComment  `json:"comments,omitempty" db:"-"`
+}
+
+// ClaimEvidence is a photo submitted with a claim. The image is kept in blob storage under FileKey.
+type ClaimEvidence struct {
+	ID        int       `json:"id" db:"id"`
+	ClaimID   int       `json:"-" db:"claim_id"`
+	FileKey   string    `json:"-" db:"file_key"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+
+	URL string `json:"url" db:"-"`
+}
+
+// ClaimComment is a note left on a claim
+type ClaimComment struct {
+	ID        int       `json:"id" db:"id"`
+	ClaimID   int       `json:"-" db:"claim_id"`
+	UserID    *int      `json:"user_id,omitempty" db:"user_id"`
+	Body      string    `json:"body" db:"body"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,34 @@
+package models
+
+import "time"
+
+// Order comment visibilities. Merchant comments are shown to the order's merchant and ops, internal ones to ops only.
+const (
+	CommentVisibilityMerchant = "merchant"
+	CommentVisibilityInternal = "internal"
+)
+
+// OrderComment is a note on an order's comment thread
+type OrderComment struct {
+	ID            int                 `json:"id" db:"id"`
+	ConsignmentID string              `json:"consignment_id" db:"consignment_id"`
+	UserID        *int                `json:"user_id,omitempty" db:"user_id"`
+	AuthorEmail   *string             `json:"author_email,omitempty" db:"author_email"`
+	AuthorRole    string              `json:"author_role" db:"author_role"`
+	Visibility    string              `json:"visibility" db:"visibility"`
+	Body          string              `json:"body" db:"body"`
+	CreatedAt     time.Time           `json:"created_at" db:"created_at"`
+	Attachments   []CommentAttachment `json:"attachments" db:"-"`
+}
+
+// CommentAttachment is a file attached to an order comment. The file is kept in blob storage under FileKey.
+type CommentAttachment struct {
+	ID        int       `json:"id" db:"id"`
+	CommentID int       `json:"-" db:"comment_id"`
+	FileKey   string    `json:"-" db:"file_key"`
+	FileName  string    `json:"file_name" db:"file_name"`
+	Size      int64     `json:"size" db:"size"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+
+	URL string `json:"url" db:"-"`
+}
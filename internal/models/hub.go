@@ -0,0 +1,76 @@
+package models
+
+import "time"
+
+// Hub types
+const (
+	HubTypeSortingCenter = "sorting_center"
+	HubTypeWarehouse     = "warehouse"
+)
+
+// Transfer statuses, where a parcel physically is
+const (
+	TransferStatusAtMerchant = 1
+	TransferStatusAtHub      = 2
+	TransferStatusInTransit  = 3
+	TransferStatusWithRider  = 4
+	TransferStatusDelivered  = 5
+)
+
+// TransferStatusNames maps transfer statuses to the names used in the API
+var TransferStatusNames = map[int]string{
+	TransferStatusAtMerchant: "at_merchant",
+	TransferStatusAtHub:      "at_hub",
+	TransferStatusInTransit:  "in_transit",
+	TransferStatusWithRider:  "with_rider",
+	TransferStatusDelivered:  "delivered",
+}
+
+// Transfer manifest statuses
+const (
+	ManifestStatusCreated    = "created"
+	ManifestStatusDispatched = "dispatched"
+	ManifestStatusReceived   = "received"
+)
+
+// Hub scan types
+const (
+	ScanTypeInbound  = "inbound"
+	ScanTypeDispatch = "dispatch"
+	ScanTypeReceive  = "receive"
+)
+
+// Hub is a sorting center or warehouse serving a zone
+type Hub struct {
+	ID        int       `json:"id" db:"id"`
+	Code      string    `json:"code" db:"code"`
+	Name      string    `json:"name" db:"name"`
+	HubType   string    `json:"hub_type" db:"hub_type"`
+	ZoneID    int       `json:"zone_id" db:"zone_id"`
+	Address   *string   `json:"address,omitempty" db:"address"`
+	Active    bool      `json:"active" db:"active"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// TransferManifest is a batch of parcels moved from one hub to another
+type TransferManifest struct {
+	ID               int        `json:"id" db:"id"`
+	OriginHubID      int        `json:"origin_hub_id" db:"origin_hub_id"`
+	DestinationHubID int        `json:"destination_hub_id" db:"destination_hub_id"`
+	Status           string     `json:"status" db:"status"`
+	CreatedBy        *int       `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	DispatchedAt     *time.Time `json:"dispatched_at,omitempty" db:"dispatched_at"`
+	ReceivedAt       *time.Time `json:"received_at,omitempty" db:"received_at"`
+
+	Items []TransferManifestItem `json:"items,omitempty" db:"-"`
+}
+
+// TransferManifestItem is a parcel on a transfer manifest and when it was scanned at each end
+type TransferManifestItem struct {
+	ID            int        `json:"-" db:"id"`
+	ManifestID    int        `json:"-" db:"manifest_id"`
+	ConsignmentID string     `json:"consignment_id" db:"consignment_id"`
+	DispatchedAt  *time.Time `json:"dispatched_at,omitempty" db:"dispatched_at"`
+	ReceivedAt    *time.Time `json:"received_at,omitempty" db:"received_at"`
+}
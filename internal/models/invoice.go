@@ -0,0 +1,44 @@
+package models
+
+import "time"
+
+// Invoice kinds
+const (
+	InvoiceKindInvoice    = "invoice"
+	InvoiceKindCreditNote = "credit_note"
+)
+
+// Invoice is an issued invoice for the fees settled in a payout, or a credit note correcting one.
+// Credit notes list the amounts credited back as positive numbers.
+type Invoice struct {
+	ID                int           `json:"id" db:"id"`
+	Number            string        `json:"number" db:"number"`
+	Kind              string        `json:"kind" db:"kind"`
+	UserID            int           `json:"user_id" db:"user_id"`
+	PayoutID          *int          `json:"payout_id,omitempty" db:"payout_id"`
+	OriginalInvoiceID *int          `json:"original_invoice_id,omitempty" db:"original_invoice_id"`
+	PeriodStart       time.Time     `json:"period_start" db:"period_start"`
+	PeriodEnd         time.Time     `json:"period_end" db:"period_end"`
+	VATPercent        float64       `json:"vat_percent" db:"vat_percent"`
+	Subtotal          float64       `json:"subtotal" db:"subtotal"`
+	VAT               float64       `json:"vat" db:"vat"`
+	Total             float64       `json:"total" db:"total"`
+	Reason            *string       `json:"reason,omitempty" db:"reason"`
+	IssuedBy          *int          `json:"issued_by,omitempty" db:"issued_by"`
+	IssuedAt          time.Time     `json:"issued_at" db:"issued_at"`
+	Lines             []InvoiceLine `json:"lines,omitempty" db:"-"`
+}
+
+// InvoiceLine is what a single consignment was charged on an invoice. Total is VAT inclusive.
+type InvoiceLine struct {
+	ID            int     `json:"id" db:"id"`
+	InvoiceID     int     `json:"invoice_id" db:"invoice_id"`
+	ConsignmentID string  `json:"consignment_id" db:"consignment_id"`
+	DeliveryFee   float64 `json:"delivery_fee" db:"delivery_fee"`
+	Surcharge     float64 `json:"surcharge" db:"surcharge"`
+	CODFee        float64 `json:"cod_fee" db:"cod_fee"`
+	ReturnCharge  float64 `json:"return_charge" db:"return_charge"`
+	Net           float64 `json:"net" db:"net"`
+	VAT           float64 `json:"vat" db:"vat"`
+	Total         float64 `json:"total" db:"total"`
+}
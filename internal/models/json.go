@@ -0,0 +1,40 @@
+package models
+
+import (
+	"database/sql/driver"
+	"fmt"
+)
+
+// JSONB holds the raw value of a JSON or JSONB column
+type JSONB []byte
+
+// Scan copies the raw column value, the driver may reuse its buffer after the row is scanned
+func (j *JSONB) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*j = nil
+	case []byte:
+		*j = append((*j)[:0], v...)
+	case string:
+		*j = JSONB(v)
+	default:
+		return fmt.Errorf("cannot scan %T into JSONB", src)
+	}
+	return nil
+}
+
+// Value sends the JSON as text so PostgreSQL parses it into the column type
+func (j JSONB) Value() (driver.Value, error) {
+	if len(j) == 0 {
+		return nil, nil
+	}
+	return string(j), nil
+}
+
+// MarshalJSON embeds the raw JSON as is
+func (j JSONB) MarshalJSON() ([]byte, error) {
+	if len(j) == 0 {
+		return []byte("null"), nil
+	}
+	return j, nil
+}
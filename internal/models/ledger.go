@@ -0,0 +1,84 @@
+package models
+
+import "time"
+
+// Ledger accounts. Money owed to merchants sits in merchant_payable until it is batched into a payout,
+// then in payouts_payable until the payout is paid.
+const (
+	AccountCODReceivable   = "cod_receivable"
+	AccountMerchantPayable = "merchant_payable"
+	AccountPayoutsPayable  = "payouts_payable"
+	AccountFeeRevenue      = "fee_revenue"
+	AccountCash            = "cash"
+	AccountClaimsExpense   = "claims_expense"
+)
+
+// Ledger entry types, one per kind of money movement
+const (
+	EntryCODCollected      = "cod_collected"
+	EntryDeliveryFee       = "delivery_fee"
+	EntrySurcharge         = "surcharge"
+	EntryCODFee            = "cod_fee"
+	EntryReturnCharge      = "return_charge"
+	EntryFeeCredit         = "fee_credit"
+	EntryPayout            = "payout"
+	EntryPayoutPaid        = "payout_paid"
+	EntryPayoutReversal    = "payout_reversal"
+	EntryClaimCompensation = "claim_compensation"
+)
+
+// Payout statuses. A pending payout waits to be sent to the provider, an initiated one waits for the provider's outcome.
+// A payout that ran out of attempts needs review: the transfer may still have gone through, so ops settle it
+// with the provider before it is paid again.
+const (
+	PayoutStatusPending     = "pending"
+	PayoutStatusInitiated   = "initiated"
+	PayoutStatusSucceeded   = "succeeded"
+	PayoutStatusFailed      = "failed"
+	PayoutStatusNeedsReview = "needs_review"
+)
+
+// Payout is a batch of settled ledger transactions paid out to a merchant
+type Payout struct {
+	ID                int          `json:"id" db:"id"`
+	UserID            int          `json:"user_id" db:"user_id"`
+	Amount            float64      `json:"amount" db:"amount"`
+	Status            string       `json:"status" db:"status"`
+	PeriodEnd         time.Time    `json:"period_end" db:"period_end"`
+	CreatedBy         *int         `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt         time.Time    `json:"created_at" db:"created_at"`
+	PaidAt            *time.Time   `json:"paid_at,omitempty" db:"paid_at"`
+	Method            *string      `json:"method,omitempty" db:"method"`
+	ProviderReference *string      `json:"provider_reference,omitempty" db:"provider_reference"`
+	Attempts          int          `json:"attempts" db:"attempts"`
+	NextAttemptAt     time.Time    `json:"-" db:"next_attempt_at"`
+	LastError         *string      `json:"last_error,omitempty" db:"last_error"`
+	UpdatedAt         time.Time    `json:"updated_at" db:"updated_at"`
+	Lines             []PayoutLine `json:"lines,omitempty" db:"-"`
+}
+
+// PayoutLine breaks down what a single consignment contributed to a payout
+type PayoutLine struct {
+	ConsignmentID string  `json:"consignment_id" db:"consignment_id"`
+	CODCollected  float64 `json:"cod_collected" db:"cod_collected"`
+	DeliveryFee   float64 `json:"delivery_fee" db:"delivery_fee"`
+	Surcharge     float64 `json:"surcharge" db:"surcharge"`
+	CODFee        float64 `json:"cod_fee" db:"cod_fee"`
+	ReturnCharge  float64 `json:"return_charge" db:"return_charge"`
+	Compensation  float64 `json:"compensation" db:"compensation"`
+	Net           float64 `json:"net" db:"net"`
+}
+
+// Balance summarises what a merchant is owed. The totals are all time.
+type Balance struct {
+	Balance        float64 `json:"balance" db:"balance"`
+	PendingPayouts float64 `json:"pending_payouts" db:"pending_payouts"`
+	CODCollected   float64 `json:"cod_collected" db:"cod_collected"`
+	DeliveryFees   float64 `json:"delivery_fees" db:"delivery_fees"`
+	Surcharges     float64 `json:"surcharges" db:"surcharges"`
+	CODFees        float64 `json:"cod_fees" db:"cod_fees"`
+	ReturnCharges  float64 `json:"return_charges" db:"return_charges"`
+	FeeCredits     float64 `json:"fee_credits" db:"fee_credits"`
+	Compensation   float64 `json:"compensation" db:"compensation"`
+	PaidOut        float64 `json:"paid_out" db:"paid_out"`
+}
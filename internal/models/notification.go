@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// Notification channels
+const (
+	ChannelSMS = "sms"
+)
+
+// Notification delivery states
+const (
+	NotificationQueued = "queued"
+	NotificationSent   = "sent"
+	NotificationFailed = "failed"
+)
+
+// Notification languages
+const (
+	LanguageEnglish = "en"
+	LanguageBangla  = "bn"
+)
+
+// StoreNotificationSettings holds a store's opt-in for recipient notifications
+type StoreNotificationSettings struct {
+	UserID     int       `json:"user_id" db:"user_id"`
+	StoreID    int       `json:"store_id" db:"store_id"`
+	SMSEnabled bool      `json:"sms_enabled" db:"sms_enabled"`
+	Language   string    `json:"language" db:"language"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// Notification is a message sent to an order's recipient
+type Notification struct {
+	ID                int        `json:"id" db:"id"`
+	EventID           int64      `json:"event_id" db:"event_id"`
+	ConsignmentID     string     `json:"consignment_id" db:"consignment_id"`
+	Channel           string     `json:"channel" db:"channel"`
+	Recipient         string     `json:"recipient" db:"recipient"`
+	Template          string     `json:"template" db:"template"`
+	Language          string     `json:"language" db:"language"`
+	Body              string     `json:"body" db:"body"`
+	Status            string     `json:"status" db:"status"`
+	Attempts          int        `json:"attempts" db:"attempts"`
+	NextAttemptAt     *time.Time `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	ProviderMessageID *string    `json:"provider_message_id,omitempty" db:"provider_message_id"`
+	LastError         *string    `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	SentAt            *time.Time `json:"sent_at,omitempty" db:"sent_at"`
+}
@@ -1,6 +1,29 @@
 package models
 
-import "time"
+import (
+	"math"
+	"time"
+)
+
+// Order statuses
+const (
+	OrderStatusPending            = "pending"
+	OrderStatusCompleted          = "completed"
+	OrderStatusCancelled          = "cancelled"
+	OrderStatusReturnInitiated    = "return_initiated"
+	OrderStatusReturned           = "returned"
+	OrderStatusPartiallyDelivered = "partially_delivered"
+	OrderStatusPickupRequested    = "pickup_requested"
+	OrderStatusPicked             = "picked"
+	OrderStatusOutForDelivery     = "out_for_delivery"
+	OrderStatusDeliveryFailed     = "delivery_failed"
+)
+
+// Order types
+const (
+	OrderTypeRegular  = "regular"
+	OrderTypeExchange = "exchange"
+)
 
 // Order represents the order data structure
 type Order struct {
@@ -15,6 +38,7 @@ type Order struct {
 	DeliveryType       int       `json:"delivery_type" validate:"required" db:"delivery_type"`
 	ItemType           int       `json:"item_type" validate:"required" db:"item_type"`
 	TransferStatus     int       `json:"transfer_status,omitempty" db:"transfer_status"`
+	TransferStatusName string    `json:"transfer_status_name,omitempty" db:"-"`
 	Archive            int       `json:"archive,omitempty" db:"archive"`
 	SpecialInstruction *string   `json:"special_instruction,omitempty" db:"special_instruction"`
 	ItemQuantity       int       `json:"item_quantity" validate:"required" db:"item_quantity"`
@@ -22,9 +46,77 @@ type Order struct {
 	AmountToCollect    float64   `json:"amount_to_collect" validate:"required" db:"amount_to_collect"`
 	ItemDescription    *string   `json:"item_description,omitempty" db:"item_description"`
 	ConsignmentID      string    `json:"consignment_id" validate:"required,len=16" db:"consignment_id"`
-	OrderStatus        string    `json:"order_status" validate:"required,oneof=pending completed cancelled" db:"order_status"`
+	OrderStatus        string    `json:"order_status" validate:"required,oneof=pending completed cancelled return_initiated returned partially_delivered pickup_requested picked out_for_delivery delivery_failed" db:"order_status"`
 	DeliveryFee        float64   `json:"delivery_fee" validate:"required" db:"delivery_fee"`
 	CODFee             float64   `json:"cod_fee" validate:"required" db:"cod_fee"`
 	UserID             int       `json:"user_id" db:"user_id"`
 	OrderCreatedAt     time.Time `json:"created_at" db:"created_at"`
+
+	OrderType           string      `json:"order_type,omitempty" db:"order_type"`
+	ParentConsignmentID *string     `json:"parent_consignment_id,omitempty" db:"parent_consignment_id"`
+	CollectedAmount     *float64    `json:"collected_amount,omitempty" db:"collected_amount"`
+	DeclaredValue       *float64    `json:"declared_value,omitempty" db:"declared_value"`
+	Length              float64     `json:"length,omitempty" db:"length"`
+	Width               float64     `json:"width,omitempty" db:"width"`
+	Height              float64     `json:"height,omitempty" db:"height"`
+	VolumetricWeight    float64     `json:"volumetric_weight,omitempty" db:"volumetric_weight"`
+	ChargeableWeight    float64     `json:"chargeable_weight,omitempty" db:"chargeable_weight"`
+	PickupID            *string     `json:"pickup_id,omitempty" db:"pickup_id"`
+	RiderID             *int        `json:"rider_id,omitempty" db:"rider_id"`
+	AssignedAt          *time.Time  `json:"assigned_at,omitempty" db:"assigned_at"`
+	CurrentHubID        *int        `json:"current_hub_id,omitempty" db:"current_hub_id"`
+	PromotionID         *int        `json:"promotion_id,omitempty" db:"promotion_id"`
+	PromoCode           *string     `json:"promo_code,omitempty" db:"promo_code"`
+	DeliveryFeeDiscount float64     `json:"delivery_fee_discount" db:"delivery_fee_discount"`
+	Surcharge           float64     `json:"surcharge" db:"surcharge"`
+	VAT                 float64     `json:"vat" db:"vat"`
+	FeeBreakdown        JSONB       `json:"fee_breakdown,omitempty" db:"fee_breakdown"`
+	Items               []OrderItem `json:"items,omitempty" db:"-"`
+
+	// ExchangeReturn is the return consignment that brings the replaced item of an exchange back to the store
+	ExchangeReturn *OrderReturn `json:"exchange_return,omitempty" db:"-"`
+
+	// CheckAmountToCollect asks for amount_to_collect to be validated against the declared value
+	CheckAmountToCollect bool `json:"check_amount_to_collect,omitempty" db:"-"`
+}
+
+// ApplyItemTotals sets the item quantity, weight and declared value from the order items.
+// It leaves the order untouched when no items were submitted.
+func (o *Order) ApplyItemTotals() {
+	if len(o.Items) == 0 {
+		return
+	}
+	var quantity int
+	var weight, value float64
+	for _, item := range o.Items {
+		quantity += item.Quantity
+		weight += item.Weight * float64(item.Quantity)
+		value += item.UnitPrice * float64(item.Quantity)
+	}
+	o.ItemQuantity = quantity
+	o.ItemWeight = math.Round(weight*1000) / 1000
+	value = math.Round(value*100) / 100
+	o.DeclaredValue = &value
+}
+
+// OrderItem is a single line of the goods packed in a parcel. UnitPrice and Weight are per unit.
+type OrderItem struct {
+	ID               int     `json:"id" db:"id"`
+	ConsignmentID    string  `json:"-" db:"consignment_id"`
+	SKU              string  `json:"sku,omitempty" db:"sku"`
+	Name             string  `json:"name" db:"name"`
+	Quantity         int     `json:"quantity" db:"quantity"`
+	UnitPrice        float64 `json:"unit_price" db:"unit_price"`
+	Weight           float64 `json:"weight" db:"weight"`
+	ReturnedQuantity int     `json:"returned_quantity" db:"returned_quantity"`
+}
+
+// PartialDelivery records that the recipient accepted only part of a parcel
+type PartialDelivery struct {
+	ID              int       `json:"id" db:"id"`
+	ConsignmentID   string    `json:"consignment_id" db:"consignment_id"`
+	CollectedAmount float64   `json:"collected_amount" db:"collected_amount"`
+	Reason          string    `json:"reason" db:"reason"`
+	RecordedBy      *int      `json:"recorded_by,omitempty" db:"recorded_by"`
+	CreatedAt       time.Time `json:"created_at" db:"created_at"`
 }
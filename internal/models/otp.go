@@ -0,0 +1,22 @@
+package models
+
+import "time"
+
+// StoreDeliverySettings holds a store's opt-in for delivery OTP on all of its parcels
+type StoreDeliverySettings struct {
+	UserID     int       `json:"user_id" db:"user_id"`
+	StoreID    int       `json:"store_id" db:"store_id"`
+	OTPEnabled bool      `json:"otp_enabled" db:"otp_enabled"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// DeliveryOTP is the hashed one-time code required to mark a parcel delivered
+type DeliveryOTP struct {
+	ConsignmentID  string     `json:"consignment_id" db:"consignment_id"`
+	CodeHash       string     `json:"-" db:"code_hash"`
+	FailedAttempts int        `json:"failed_attempts" db:"failed_attempts"`
+	LockedUntil    *time.Time `json:"locked_until,omitempty" db:"locked_until"`
+	VerifiedAt     *time.Time `json:"verified_at,omitempty" db:"verified_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updated_at" db:"updated_at"`
+}
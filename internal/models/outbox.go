@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Domain events published through the outbox
+const (
+	EventOrderCreated       = "order.created"
+	EventOrderCancelled     = "order.cancelled"
+	EventOrderStatusChanged = "order.status_changed"
+)
+
+// OutboxEvent is a domain event stored in the outbox until every sink has received it
+type OutboxEvent struct {
+	ID            int64      `json:"id" db:"id"`
+	AggregateID   string     `json:"aggregate_id" db:"aggregate_id"`
+	EventType     string     `json:"event_type" db:"event_type"`
+	UserID        *int       `json:"user_id,omitempty" db:"user_id"`
+	Payload       JSONB      `json:"payload" db:"payload"`
+	Attempts      int        `json:"attempts" db:"attempts"`
+	LastError     *string    `json:"last_error,omitempty" db:"last_error"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	PublishedAt   *time.Time `json:"published_at,omitempty" db:"published_at"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+}
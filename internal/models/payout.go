@@ -0,0 +1,35 @@
+package models
+
+import "time"
+
+// Payout methods
+const (
+	PayoutMethodBankTransfer = "bank_transfer"
+	PayoutMethodMobileWallet = "mobile_wallet"
+)
+
+// PayoutMethod is where a merchant's payouts are sent. The account number is only stored encrypted.
+type PayoutMethod struct {
+	UserID                 int       `json:"user_id" db:"user_id"`
+	Method                 string    `json:"method" db:"method"`
+	AccountName            string    `json:"account_name" db:"account_name"`
+	AccountNumber          string    `json:"-" db:"-"`
+	AccountNumberEncrypted string    `json:"-" db:"account_number_encrypted"`
+	AccountNumberLast4     string    `json:"account_number_last4" db:"account_number_last4"`
+	BankName               *string   `json:"bank_name,omitempty" db:"bank_name"`
+	BranchName             *string   `json:"branch_name,omitempty" db:"branch_name"`
+	RoutingNumber          *string   `json:"routing_number,omitempty" db:"routing_number"`
+	WalletProvider         *string   `json:"wallet_provider,omitempty" db:"wallet_provider"`
+	UpdatedAt              time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// PayoutAttempt is one try at sending a payout through its provider
+type PayoutAttempt struct {
+	ID                int       `json:"id" db:"id"`
+	PayoutID          int       `json:"payout_id" db:"payout_id"`
+	Attempt           int       `json:"attempt" db:"attempt"`
+	Status            string    `json:"status" db:"status"`
+	ProviderReference *string   `json:"provider_reference,omitempty" db:"provider_reference"`
+	Error             *string   `json:"error,omitempty" db:"error"`
+	CreatedAt         time.Time `json:"created_at" db:"created_at"`
+}
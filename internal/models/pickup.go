@@ -0,0 +1,32 @@
+package models
+
+import "time"
+
+// Pickup request statuses
+const (
+	PickupStatusRequested   = "requested"
+	PickupStatusConfirmed   = "confirmed"
+	PickupStatusRescheduled = "rescheduled"
+)
+
+// PickupRequest asks for parcels to be collected from a store within a time window.
+// PickupDate is a YYYY-MM-DD date and the window bounds are HH:MM in the pickup time zone.
+type PickupRequest struct {
+	ID               int        `json:"-" db:"id"`
+	PickupID         string     `json:"pickup_id" db:"pickup_id"`
+	UserID           int        `json:"user_id" db:"user_id"`
+	StoreID          int        `json:"store_id" db:"store_id"`
+	ZoneID           int        `json:"zone_id" db:"zone_id"`
+	PickupDate       string     `json:"pickup_date" db:"pickup_date"`
+	WindowStart      string     `json:"window_start" db:"window_start"`
+	WindowEnd        string     `json:"window_end" db:"window_end"`
+	Status           string     `json:"status" db:"status"`
+	Note             *string    `json:"note,omitempty" db:"note"`
+	ConfirmedBy      *int       `json:"confirmed_by,omitempty" db:"confirmed_by"`
+	ConfirmedAt      *time.Time `json:"confirmed_at,omitempty" db:"confirmed_at"`
+	RescheduleReason *string    `json:"reschedule_reason,omitempty" db:"reschedule_reason"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
+
+	ConsignmentIDs []string `json:"consignment_ids,omitempty" db:"-"`
+}
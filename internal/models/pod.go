@@ -0,0 +1,19 @@
+package models
+
+import "time"
+
+// ProofOfDelivery is the evidence a rider captured when handing over a parcel.
+// The photo and signature images are kept in blob storage under their keys.
+type ProofOfDelivery struct {
+	ID            int       `json:"-" db:"id"`
+	ConsignmentID string    `json:"consignment_id" db:"consignment_id"`
+	RiderID       int       `json:"rider_id" db:"rider_id"`
+	PhotoKey      *string   `json:"-" db:"photo_key"`
+	SignatureKey  *string   `json:"-" db:"signature_key"`
+	Latitude      float64   `json:"latitude" db:"latitude"`
+	Longitude     float64   `json:"longitude" db:"longitude"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+
+	PhotoURL     string `json:"photo_url,omitempty" db:"-"`
+	SignatureURL string `json:"signature_url,omitempty" db:"-"`
+}
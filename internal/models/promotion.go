@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Promotion discount types. A percent discount takes a share of the delivery fee, a flat one a fixed amount off it.
+const (
+	DiscountTypePercent = "percent"
+	DiscountTypeFlat    = "flat"
+)
+
+// Promotion is a discount on the delivery fee, either redeemed with a code or applied automatically
+type Promotion struct {
+	ID               int           `json:"id" db:"id"`
+	Code             *string       `json:"code,omitempty" db:"code"`
+	Name             string        `json:"name" db:"name"`
+	UserID           *int          `json:"user_id,omitempty" db:"user_id"`
+	DiscountType     string        `json:"discount_type" db:"discount_type"`
+	DiscountValue    float64       `json:"discount_value" db:"discount_value"`
+	Zones            pq.Int64Array `json:"zones" db:"zones"`
+	StartsAt         *time.Time    `json:"starts_at,omitempty" db:"starts_at"`
+	EndsAt           *time.Time    `json:"ends_at,omitempty" db:"ends_at"`
+	UsageLimit       *int          `json:"usage_limit,omitempty" db:"usage_limit"`
+	PerMerchantLimit *int          `json:"per_merchant_limit,omitempty" db:"per_merchant_limit"`
+	TimesUsed        int           `json:"times_used" db:"times_used"`
+	Active           bool          `json:"active" db:"active"`
+	CreatedBy        *int          `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt        time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time     `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import "time"
+
+// Return statuses, tracking the parcel on its way back to the store
+const (
+	ReturnStatusInitiated = "initiated"
+	ReturnStatusInTransit = "in_transit"
+	ReturnStatusReturned  = "returned"
+)
+
+// OrderReturn is a refused parcel sent back to the store under its own consignment ID
+type OrderReturn struct {
+	ID                  int        `json:"id" db:"id"`
+	ConsignmentID       string     `json:"consignment_id" db:"consignment_id"`
+	ReturnConsignmentID string     `json:"return_consignment_id" db:"return_consignment_id"`
+	Reason              string     `json:"reason" db:"reason"`
+	Status              string     `json:"status" db:"status"`
+	ReturnCharge        float64    `json:"return_charge" db:"return_charge"`
+	InitiatedBy         *int       `json:"initiated_by,omitempty" db:"initiated_by"`
+	CreatedAt           time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt           time.Time  `json:"updated_at" db:"updated_at"`
+	ReturnedAt          *time.Time `json:"returned_at,omitempty" db:"returned_at"`
+}
@@ -0,0 +1,25 @@
+package models
+
+import "time"
+
+// Rider is a user who picks up and delivers parcels from a home hub
+type Rider struct {
+	ID        int       `json:"id" db:"id"`
+	UserID    int       `json:"user_id" db:"user_id"`
+	Email     string    `json:"email,omitempty" db:"email"`
+	Name      string    `json:"name" db:"name"`
+	Phone     string    `json:"phone" db:"phone"`
+	HomeHub   string    `json:"home_hub" db:"home_hub"`
+	Active    bool      `json:"active" db:"active"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// DeliveryAttempt is a failed attempt to deliver a parcel
+type DeliveryAttempt struct {
+	ID            int       `json:"id" db:"id"`
+	ConsignmentID string    `json:"consignment_id" db:"consignment_id"`
+	RiderID       int       `json:"rider_id" db:"rider_id"`
+	Reason        string    `json:"reason" db:"reason"`
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+}
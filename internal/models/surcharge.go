@@ -0,0 +1,29 @@
+package models
+
+import "time"
+
+// Surcharge kinds
+const (
+	SurchargeKindFuel = "fuel"
+	SurchargeKindPeak = "peak"
+)
+
+// Surcharge calculations. A percent surcharge is a share of the delivery fee, a flat one a fixed amount per order.
+const (
+	SurchargeCalculationPercent = "percent"
+	SurchargeCalculationFlat    = "flat"
+)
+
+// Surcharge is added to the delivery fee of orders created between EffectiveFrom and EffectiveTo
+type Surcharge struct {
+	ID            int        `json:"id" db:"id"`
+	Name          string     `json:"name" db:"name"`
+	Kind          string     `json:"kind" db:"kind"`
+	Calculation   string     `json:"calculation" db:"calculation"`
+	Value         float64    `json:"value" db:"value"`
+	EffectiveFrom time.Time  `json:"effective_from" db:"effective_from"`
+	EffectiveTo   *time.Time `json:"effective_to,omitempty" db:"effective_to"`
+	CreatedBy     *int       `json:"created_by,omitempty" db:"created_by"`
+	CreatedAt     time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time  `json:"updated_at" db:"updated_at"`
+}
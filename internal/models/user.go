@@ -1,7 +1,16 @@
 package models
 
+// User roles
+const (
+	RoleMerchant = "merchant"
+	RoleOps      = "ops"
+	RoleRider    = "rider"
+	RoleCashier  = "cashier"
+)
+
 type User struct {
 	ID       int    `db:"id"`
 	Email    string `db:"email"`
 	Password string `db:"password"`
+	Role     string `db:"role"`
 }
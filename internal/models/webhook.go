@@ -0,0 +1,57 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// WebhookEvents lists every event a webhook endpoint can subscribe to
+var WebhookEvents = []string{EventOrderCreated, EventOrderCancelled, EventOrderStatusChanged}
+
+// Webhook delivery states. Deliveries still queued when their endpoint is deleted are cancelled.
+const (
+	DeliveryPending   = "pending"
+	DeliverySucceeded = "succeeded"
+	DeliveryFailed    = "failed"
+	DeliveryDead      = "dead"
+	DeliveryCancelled = "cancelled"
+)
+
+// WebhookEndpoint is a merchant URL that receives signed event notifications
+type WebhookEndpoint struct {
+	ID        int            `json:"id" db:"id"`
+	UserID    int            `json:"user_id" db:"user_id"`
+	URL       string         `json:"url" db:"url"`
+	Secret    string         `json:"secret,omitempty" db:"secret"`
+	Events    pq.StringArray `json:"events" db:"events"`
+	Active    bool           `json:"active" db:"active"`
+	CreatedAt time.Time      `json:"created_at" db:"created_at"`
+}
+
+// WebhookDelivery is a single event queued for delivery to an endpoint
+type WebhookDelivery struct {
+	ID               int        `json:"id" db:"id"`
+	EndpointID       int        `json:"endpoint_id" db:"endpoint_id"`
+	EventID          *int64     `json:"event_id,omitempty" db:"event_id"`
+	Event            string     `json:"event" db:"event"`
+	Payload          JSONB      `json:"payload" db:"payload"`
+	Status           string     `json:"status" db:"status"`
+	Attempts         int        `json:"attempts" db:"attempts"`
+	NextAttemptAt    *time.Time `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	LastResponseCode *int       `json:"last_response_code,omitempty" db:"last_response_code"`
+	LastError        *string    `json:"last_error,omitempty" db:"last_error"`
+	CreatedAt        time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt        time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// WebhookDeliveryAttempt logs one HTTP attempt of a delivery
+type WebhookDeliveryAttempt struct {
+	ID           int       `json:"id" db:"id"`
+	DeliveryID   int       `json:"delivery_id" db:"delivery_id"`
+	Attempt      int       `json:"attempt" db:"attempt"`
+	ResponseCode *int      `json:"response_code,omitempty" db:"response_code"`
+	Error        *string   `json:"error,omitempty" db:"error"`
+	DurationMs   int       `json:"duration_ms" db:"duration_ms"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
@@ -0,0 +1,62 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"sync"
+	"time"
+
+	"go-application-task/configs"
+)
+
+// SMSProvider sends text messages to phone numbers
+type SMSProvider interface {
+	// Send delivers body to phone and returns the provider's message ID
+	Send(ctx context.Context, phone, body string) (string, error)
+}
+
+// NewSMSProvider returns the SMS driver selected in config
+func NewSMSProvider(config configs.NotificationConfig) (SMSProvider, error) {
+	switch config.SMSDriver {
+	case "console":
+		return ConsoleSMSProvider{}, nil
+	case "file":
+		return &FileSMSProvider{Path: config.SMSFilePath}, nil
+	default:
+		return nil, fmt.Errorf("unknown SMS driver %q", config.SMSDriver)
+	}
+}
+
+// ConsoleSMSProvider is a stand-in driver that writes messages to the application log
+type ConsoleSMSProvider struct{}
+
+func (ConsoleSMSProvider) Send(ctx context.Context, phone, body string) (string, error) {
+	id := fmt.Sprintf("console-%d", time.Now().UnixNano())
+	log.Printf("SMS %s to %s: %s", id, phone, body)
+	return id, nil
+}
+
+// FileSMSProvider is a stand-in driver that appends messages to a file, one per line
+type FileSMSProvider struct {
+	Path string
+	mu   sync.Mutex
+}
+
+func (p *FileSMSProvider) Send(ctx context.Context, phone, body string) (string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	f, err := os.OpenFile(p.Path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to open SMS file: %w", err)
+	}
+	defer f.Close()
+
+	id := fmt.Sprintf("file-%d", time.Now().UnixNano())
+	if _, err := fmt.Fprintf(f, "%s\t%s\t%s\t%q\n", time.Now().Format(time.RFC3339), id, phone, body); err != nil {
+		return "", fmt.Errorf("failed to write SMS file: %w", err)
+	}
+	return id, nil
+}
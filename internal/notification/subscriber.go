@@ -0,0 +1,88 @@
+package notification
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"go-application-task/configs"
+	"go-application-task/internal/models"
+	"go-application-task/internal/outbox"
+)
+
+// statusTemplates maps order statuses to the message sent when an order reaches them
+var statusTemplates = map[string]string{
+	models.OrderStatusOutForDelivery: TemplateOutForDelivery,
+}
+
+// TriggerEvents are the outbox events the subscriber reacts to
+var TriggerEvents = []string{models.EventOrderCreated, models.EventOrderStatusChanged}
+
+// Subscriber returns an outbox handler that queues an SMS to the recipient when an order is booked
+// or reaches a status with a template, provided the order's store has opted in.
+// Sending happens later in the Worker so order creation is never held up by the SMS provider.
+func Subscriber(db *sqlx.DB) outbox.Handler {
+	return func(ctx context.Context, event models.OutboxEvent) error {
+		var payload struct {
+			ConsignmentID string `json:"consignment_id"`
+			OrderStatus   string `json:"order_status"`
+		}
+		if err := json.Unmarshal(event.Payload, &payload); err != nil {
+			return fmt.Errorf("invalid %s payload: %w", event.EventType, err)
+		}
+
+		templateName := TemplateOrderBooked
+		if event.EventType == models.EventOrderStatusChanged {
+			var ok bool
+			if templateName, ok = statusTemplates[payload.OrderStatus]; !ok {
+				return nil
+			}
+		}
+
+		var order struct {
+			RecipientName   string  `db:"recipient_name"`
+			RecipientPhone  string  `db:"recipient_phone"`
+			StoreID         int     `db:"store_id"`
+			AmountToCollect float64 `db:"amount_to_collect"`
+			SMSEnabled      bool    `db:"sms_enabled"`
+			Language        string  `db:"language"`
+		}
+		err := db.GetContext(ctx, &order, `
+			SELECT o.recipient_name, o.recipient_phone, o.store_id, o.amount_to_collect, s.sms_enabled, s.language
+			FROM orders o
+			JOIN store_notification_settings s ON s.user_id = o.user_id AND s.store_id = o.store_id
+			WHERE o.consignment_id = $1
+		`, payload.ConsignmentID)
+		if err == sql.ErrNoRows || (err == nil && !order.SMSEnabled) {
+			// The store has not opted in
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to load order %s: %w", payload.ConsignmentID, err)
+		}
+
+		body, err := render(templateName, order.Language, messageData{
+			RecipientName: order.RecipientName,
+			ConsignmentID: payload.ConsignmentID,
+			StoreID:       order.StoreID,
+			Amount:        fmt.Sprintf("%.0f", order.AmountToCollect),
+			TrackingURL:   configs.GetTrackingBaseURL() + "/" + payload.ConsignmentID,
+		})
+		if err != nil {
+			return err
+		}
+
+		// The event may be published more than once, the conflict clause keeps a single message per event
+		_, err = db.ExecContext(ctx, `
+			INSERT INTO notifications (event_id, consignment_id, channel, recipient, template, language, body)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+			ON CONFLICT (event_id, channel) DO NOTHING
+		`, event.ID, payload.ConsignmentID, models.ChannelSMS, order.RecipientPhone, templateName, order.Language, body)
+		if err != nil {
+			return fmt.Errorf("failed to queue notification for %s: %w", payload.ConsignmentID, err)
+		}
+		return nil
+	}
+}
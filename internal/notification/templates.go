@@ -0,0 +1,63 @@
+package notification
+
+import (
+	"fmt"
+	"strings"
+	"text/template"
+
+	"go-application-task/internal/models"
+)
+
+// Notification templates
+const (
+	TemplateOrderBooked    = "order_booked"
+	TemplateOutForDelivery = "out_for_delivery"
+)
+
+// messageData is the data available to message templates
+type messageData struct {
+	RecipientName string
+	ConsignmentID string
+	StoreID       int
+	Amount        string
+	TrackingURL   string
+}
+
+// templates holds the message text per template and language
+var templates = map[string]map[string]*template.Template{
+	TemplateOrderBooked: {
+		models.LanguageEnglish: template.Must(template.New("order_booked_en").Parse(
+			"Dear {{.RecipientName}}, your parcel {{.ConsignmentID}} from Store #{{.StoreID}} has been booked. COD: Tk {{.Amount}}. Track: {{.TrackingURL}}")),
+		models.LanguageBangla: template.Must(template.New("order_booked_bn").Parse(
+			"প্রিয় {{.RecipientName}}, স্টোর #{{.StoreID}} থেকে আপনার পার্সেল {{.ConsignmentID}} বুক করা হয়েছে। ক্যাশ অন ডেলিভারি: {{.Amount}} টাকা। ট্র্যাক করুন: {{.TrackingURL}}")),
+	},
+	TemplateOutForDelivery: {
+		models.LanguageEnglish: template.Must(template.New("out_for_delivery_en").Parse(
+			"Dear {{.RecipientName}}, your parcel {{.ConsignmentID}} is out for delivery today. Please keep Tk {{.Amount}} ready.")),
+		models.LanguageBangla: template.Must(template.New("out_for_delivery_bn").Parse(
+			"প্রিয় {{.RecipientName}}, আপনার পার্সেল {{.ConsignmentID}} আজ ডেলিভারির জন্য রওনা হয়েছে। অনুগ্রহ করে {{.Amount}} টাকা প্রস্তুত রাখুন।")),
+	},
+}
+
+// IsLanguage reports whether messages can be sent in lang
+func IsLanguage(lang string) bool {
+	return lang == models.LanguageEnglish || lang == models.LanguageBangla
+}
+
+// render builds the message text of a template, falling back to English for unknown languages
+func render(name, lang string, data messageData) (string, error) {
+	byLanguage, ok := templates[name]
+	if !ok {
+		return "", fmt.Errorf("unknown notification template %q", name)
+	}
+	tpl, ok := byLanguage[lang]
+	if !ok {
+		tpl = byLanguage[models.LanguageEnglish]
+	}
+
+	var sb strings.Builder
+	if err := tpl.Execute(&sb, data); err != nil {
+		return "", fmt.Errorf("failed to render %s: %w", name, err)
+	}
+	return sb.String(), nil
+}
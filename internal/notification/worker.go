@@ -0,0 +1,117 @@
+package notification
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go-application-task/configs"
+	"go-application-task/internal/models"
+)
+
+// batchSize is how many queued notifications are claimed per poll
+const batchSize = 50
+
+// leaseDuration keeps a claimed notification from being picked up again while it is being sent
+const leaseDuration = time.Minute
+
+// retryDelay is the delay before a failed notification is tried again
+const retryDelay = 30 * time.Second
+
+// Worker sends queued notifications in the background and tracks their delivery status
+type Worker struct {
+	db     *sqlx.DB
+	sms    SMSProvider
+	config configs.NotificationConfig
+}
+
+// NewWorker creates a worker sending notifications from db through sms
+func NewWorker(db *sqlx.DB, sms SMSProvider, config configs.NotificationConfig) *Worker {
+	return &Worker{db: db, sms: sms, config: config}
+}
+
+// Run polls for queued notifications until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.config.PollInterval)
+	defer ticker.Stop()
+
+	log.Println("Notification worker started")
+	for {
+		for {
+			n, err := w.sendDue(ctx)
+			if err != nil {
+				log.Printf("Notification worker error: %v", err)
+			}
+			// Keep draining while full batches are being claimed
+			if err != nil || n < batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Notification worker stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// sendDue claims a batch of due notifications, sends them and returns how many were claimed
+func (w *Worker) sendDue(ctx context.Context) (int, error) {
+	var notifications []models.Notification
+	err := w.db.SelectContext(ctx, &notifications, `
+		WITH due AS (
+			SELECT id FROM notifications
+			WHERE status = $1 AND next_attempt_at <= NOW()
+			ORDER BY id
+			LIMIT $2
+			FOR UPDATE SKIP LOCKED
+		)
+		UPDATE notifications n
+		SET next_attempt_at = NOW() + $3 * INTERVAL '1 second'
+		FROM due
+		WHERE n.id = due.id
+		RETURNING n.*
+	`, models.NotificationQueued, batchSize, int(leaseDuration.Seconds()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim notifications: %w", err)
+	}
+
+	for _, n := range notifications {
+		w.send(ctx, n)
+	}
+	return len(notifications), nil
+}
+
+// send delivers a single notification and records the outcome
+func (w *Worker) send(ctx context.Context, n models.Notification) {
+	attempts := n.Attempts + 1
+	messageID, err := w.sms.Send(ctx, n.Recipient, n.Body)
+	if err == nil {
+		_, err = w.db.ExecContext(ctx, `
+			UPDATE notifications SET status = $2, attempts = $3, provider_message_id = $4, last_error = NULL, sent_at = NOW()
+			WHERE id = $1
+		`, n.ID, models.NotificationSent, attempts, messageID)
+		if err != nil {
+			log.Printf("Failed to record notification %d: %v", n.ID, err)
+		}
+		return
+	}
+
+	// Keep the notification queued until it runs out of attempts
+	status := models.NotificationQueued
+	if attempts >= w.config.MaxAttempts {
+		status = models.NotificationFailed
+	}
+	log.Printf("Failed to send notification %d (attempt %d): %v", n.ID, attempts, err)
+	_, err = w.db.ExecContext(ctx, `
+		UPDATE notifications SET status = $2, attempts = $3, last_error = $4, next_attempt_at = $5
+		WHERE id = $1
+	`, n.ID, status, attempts, err.Error(), time.Now().Add(retryDelay))
+	if err != nil {
+		log.Printf("Failed to record notification %d: %v", n.ID, err)
+	}
+}
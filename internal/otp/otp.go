@@ -0,0 +1,77 @@
+package otp
+
+import (
+	"context"
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"time"
+
+	"go-application-task/internal/notification"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Notifier delivers a delivery code to the recipient of a parcel
+type Notifier interface {
+	Notify(ctx context.Context, phone, consignmentID, code string) error
+}
+
+// Generate returns a random numeric code of the given length
+func Generate(length int) (string, error) {
+	code := make([]byte, length)
+	for i := range code {
+		n, err := rand.Int(rand.Reader, big.NewInt(10))
+		if err != nil {
+			return "", fmt.Errorf("failed to generate OTP: %w", err)
+		}
+		code[i] = byte('0' + n.Int64())
+	}
+	return string(code), nil
+}
+
+// Hash returns the hash of code that is stored instead of the code itself
+func Hash(code string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(code), bcrypt.DefaultCost)
+	if err != nil {
+		return "", fmt.Errorf("failed to hash OTP: %w", err)
+	}
+	return string(hash), nil
+}
+
+// Matches reports whether code is the code hash was created from
+func Matches(hash, code string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(code)) == nil
+}
+
+// SMSNotifier texts the code to the recipient straight away.
+// It bypasses the notification queue so the code is never written to the database.
+type SMSNotifier struct {
+	SMS notification.SMSProvider
+}
+
+func (n SMSNotifier) Notify(ctx context.Context, phone, consignmentID, code string) error {
+	body := fmt.Sprintf("Your delivery code for parcel %s is %s. Share it with the rider only after you receive the parcel.", consignmentID, code)
+	_, err := n.SMS.Send(ctx, phone, body)
+	return err
+}
+
+// Limiter locks a code out for a while once too many wrong codes have been entered for it
+type Limiter struct {
+	MaxAttempts int
+	Lockout     time.Duration
+}
+
+// Locked reports whether a code locked until lockedUntil still is at now
+func (l Limiter) Locked(lockedUntil *time.Time, now time.Time) bool {
+	return lockedUntil != nil && lockedUntil.After(now)
+}
+
+// Fail counts a wrong code on top of failedAttempts earlier ones. It returns the attempts left before the code is locked,
+// or when that was the last attempt, until when it is locked. The count starts over once the lockout ends.
+func (l Limiter) Fail(failedAttempts int, now time.Time) (attemptsLeft int, lockedUntil *time.Time) {
+	if failed := failedAttempts + 1; failed < l.MaxAttempts {
+		return l.MaxAttempts - failed, nil
+	}
+	until := now.Add(l.Lockout)
+	return 0, &until
+}
@@ -0,0 +1,122 @@
+package otp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestGenerate(t *testing.T) {
+	for _, length := range []int{4, 6, 10} {
+		code, err := Generate(length)
+		if err != nil {
+			t.Fatalf("Generate(%d): %v", length, err)
+		}
+		if len(code) != length {
+			t.Errorf("Generate(%d) = %q, want %d digits", length, code, length)
+		}
+		for _, c := range code {
+			if c < '0' || c > '9' {
+				t.Errorf("Generate(%d) = %q, want only digits", length, code)
+				break
+			}
+		}
+	}
+
+	seen := map[string]bool{}
+	for i := 0; i < 20; i++ {
+		code, _ := Generate(10)
+		seen[code] = true
+	}
+	if len(seen) < 2 {
+		t.Errorf("Generate returned the same code 20 times")
+	}
+}
+
+func TestHashMatches(t *testing.T) {
+	hash, err := Hash("482913")
+	if err != nil {
+		t.Fatalf("Hash: %v", err)
+	}
+	if hash == "482913" {
+		t.Fatalf("Hash returned the code itself")
+	}
+
+	tests := []struct {
+		name string
+		code string
+		want bool
+	}{
+		{"same code", "482913", true},
+		{"wrong code", "482914", false},
+		{"empty code", "", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Matches(hash, tt.code); got != tt.want {
+				t.Errorf("Matches(%q) = %v, want %v", tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+var testLimiter = Limiter{MaxAttempts: 3, Lockout: 15 * time.Minute}
+
+func TestLimiterFail(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name           string
+		failedAttempts int
+
+		attemptsLeft int
+		lockedUntil  *time.Time
+	}{
+		{name: "first wrong code", failedAttempts: 0, attemptsLeft: 2},
+		{name: "second wrong code", failedAttempts: 1, attemptsLeft: 1},
+		{name: "last attempt locks the code", failedAttempts: 2, lockedUntil: timePtr(now.Add(15 * time.Minute))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			attemptsLeft, lockedUntil := testLimiter.Fail(tt.failedAttempts, now)
+			if attemptsLeft != tt.attemptsLeft {
+				t.Errorf("attempts left = %d, want %d", attemptsLeft, tt.attemptsLeft)
+			}
+			switch {
+			case tt.lockedUntil == nil && lockedUntil != nil:
+				t.Errorf("locked until %v, want not locked", *lockedUntil)
+			case tt.lockedUntil != nil && (lockedUntil == nil || !lockedUntil.Equal(*tt.lockedUntil)):
+				t.Errorf("locked until %v, want %v", lockedUntil, *tt.lockedUntil)
+			}
+		})
+	}
+}
+
+func TestLimiterLocked(t *testing.T) {
+	lockedAt := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	_, lockedUntil := testLimiter.Fail(testLimiter.MaxAttempts-1, lockedAt)
+
+	tests := []struct {
+		name        string
+		lockedUntil *time.Time
+		now         time.Time
+		want        bool
+	}{
+		{"never locked", nil, lockedAt, false},
+		{"right after locking", lockedUntil, lockedAt, true},
+		{"during lockout", lockedUntil, lockedAt.Add(14 * time.Minute), true},
+		{"lockout expired", lockedUntil, lockedAt.Add(15 * time.Minute), false},
+		{"long after lockout", lockedUntil, lockedAt.Add(24 * time.Hour), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := testLimiter.Locked(tt.lockedUntil, tt.now); got != tt.want {
+				t.Errorf("Locked() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
@@ -0,0 +1,129 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go-application-task/configs"
+	"go-application-task/internal/models"
+)
+
+// batchSize is how many events are published per poll
+const batchSize = 100
+
+// leaseDuration keeps a claimed event from being picked up again while it is being published
+const leaseDuration = 5 * time.Minute
+
+// maxRetryDelay caps the delay before a failed event is published again
+const maxRetryDelay = 5 * time.Minute
+
+// Dispatcher publishes outbox events to its sinks with at least once delivery
+type Dispatcher struct {
+	db     *sqlx.DB
+	sinks  []Sink
+	config configs.OutboxConfig
+}
+
+// NewDispatcher creates a dispatcher publishing the outbox in db to sinks
+func NewDispatcher(db *sqlx.DB, config configs.OutboxConfig, sinks ...Sink) *Dispatcher {
+	return &Dispatcher{db: db, sinks: sinks, config: config}
+}
+
+// Run polls the outbox until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	log.Println("Outbox dispatcher started")
+	for {
+		for {
+			n, err := d.publishBatch(ctx)
+			if err != nil {
+				log.Printf("Outbox dispatch error: %v", err)
+			}
+			// Keep draining while full batches are being published
+			if err != nil || n < batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Outbox dispatcher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// publishBatch publishes the oldest unpublished event of each consignment and returns how many were picked up.
+// Only the head event of every aggregate is eligible, so a later event is never published before an earlier one.
+// Events are claimed with a lease and published outside any transaction, so a slow sink holds no locks.
+func (d *Dispatcher) publishBatch(ctx context.Context) (int, error) {
+	var events []models.OutboxEvent
+	err := d.db.SelectContext(ctx, &events, `
+		WITH due AS (
+			SELECT o.id FROM outbox o
+			WHERE o.published_at IS NULL AND o.next_attempt_at <= NOW()
+			AND NOT EXISTS (
+				SELECT 1 FROM outbox p
+				WHERE p.aggregate_id = o.aggregate_id AND p.published_at IS NULL AND p.id < o.id
+			)
+			ORDER BY o.id
+			LIMIT $1
+			FOR UPDATE SKIP LOCKED
+		), claimed AS (
+			UPDATE outbox o
+			SET next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+			FROM due
+			WHERE o.id = due.id
+			RETURNING o.*
+		)
+		SELECT * FROM claimed ORDER BY id
+	`, batchSize, int(leaseDuration.Seconds()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim outbox events: %w", err)
+	}
+
+	for _, event := range events {
+		if err := d.publish(ctx, event); err != nil {
+			attempts := event.Attempts + 1
+			log.Printf("Failed to publish outbox event %d (attempt %d): %v", event.ID, attempts, err)
+			_, err = d.db.ExecContext(ctx, `
+				UPDATE outbox SET attempts = $2, last_error = $3, next_attempt_at = $4 WHERE id = $1
+			`, event.ID, attempts, err.Error(), time.Now().Add(retryDelay(attempts)))
+		} else {
+			_, err = d.db.ExecContext(ctx, `UPDATE outbox SET published_at = NOW() WHERE id = $1`, event.ID)
+		}
+		if err != nil {
+			// The lease runs out and the event is published again, which at least once delivery allows
+			log.Printf("Failed to update outbox event %d: %v", event.ID, err)
+		}
+	}
+	return len(events), nil
+}
+
+// publish sends the event to every sink, stopping at the first failure
+func (d *Dispatcher) publish(ctx context.Context, event models.OutboxEvent) error {
+	for _, sink := range d.sinks {
+		if err := sink.Publish(ctx, event); err != nil {
+			return fmt.Errorf("%s sink: %w", sink.Name(), err)
+		}
+	}
+	return nil
+}
+
+// retryDelay doubles the delay with every failed attempt, starting at one second
+func retryDelay(attempts int) time.Duration {
+	delay := time.Second
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxRetryDelay {
+			return maxRetryDelay
+		}
+	}
+	return delay
+}
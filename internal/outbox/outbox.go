@@ -0,0 +1,27 @@
+package outbox
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Write stores an event in the outbox. Pass the transaction performing the mutation that caused the event,
+// the event is then published if and only if the transaction commits.
+// Events with the same aggregateID are published in the order they were written.
+func Write(exec sqlx.Execer, aggregateID, eventType string, userID int, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s event: %w", eventType, err)
+	}
+
+	_, err = exec.Exec(`
+		INSERT INTO outbox (aggregate_id, event_type, user_id, payload)
+		VALUES ($1, $2, $3, $4)
+	`, aggregateID, eventType, userID, string(payload))
+	if err != nil {
+		return fmt.Errorf("failed to write %s event to outbox: %w", eventType, err)
+	}
+	return nil
+}
@@ -0,0 +1,203 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"go-application-task/internal/models"
+)
+
+// memorySink keeps every event it is given and fails with err when set
+type memorySink struct {
+	name   string
+	err    error
+	events []models.OutboxEvent
+}
+
+func (s *memorySink) Name() string { return s.name }
+
+func (s *memorySink) Publish(ctx context.Context, event models.OutboxEvent) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.events = append(s.events, event)
+	return nil
+}
+
+func event(id int64, eventType string) models.OutboxEvent {
+	return models.OutboxEvent{ID: id, AggregateID: "C1", EventType: eventType, Payload: models.JSONB(`{}`)}
+}
+
+func TestBusFanOut(t *testing.T) {
+	var created, statusChanged, everything []int64
+	record := func(ids *[]int64) Handler {
+		return func(ctx context.Context, event models.OutboxEvent) error {
+			*ids = append(*ids, event.ID)
+			return nil
+		}
+	}
+
+	bus := NewBus()
+	bus.Subscribe(record(&created), models.EventOrderCreated)
+	bus.Subscribe(record(&statusChanged), models.EventOrderStatusChanged, models.EventOrderCancelled)
+	bus.Subscribe(record(&everything))
+
+	events := []models.OutboxEvent{
+		event(1, models.EventOrderCreated),
+		event(2, models.EventOrderStatusChanged),
+		event(3, models.EventOrderCancelled),
+		event(4, "order.unknown"),
+	}
+	for _, e := range events {
+		if err := bus.Publish(context.Background(), e); err != nil {
+			t.Fatalf("Publish(%d): %v", e.ID, err)
+		}
+	}
+
+	tests := []struct {
+		name string
+		got  []int64
+		want []int64
+	}{
+		{"single event type", created, []int64{1}},
+		{"several event types", statusChanged, []int64{2, 3}},
+		{"every event", everything, []int64{1, 2, 3, 4}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if !reflect.DeepEqual(tt.got, tt.want) {
+				t.Errorf("received %v, want %v", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBusStopsAtFirstError(t *testing.T) {
+	errHandler := errors.New("handler failed")
+	calls := 0
+	bus := NewBus()
+	bus.Subscribe(func(ctx context.Context, event models.OutboxEvent) error {
+		calls++
+		return errHandler
+	}, models.EventOrderCreated)
+	bus.Subscribe(func(ctx context.Context, event models.OutboxEvent) error {
+		calls++
+		return nil
+	})
+
+	if err := bus.Publish(context.Background(), event(1, models.EventOrderCreated)); !errors.Is(err, errHandler) {
+		t.Errorf("Publish error = %v, want %v", err, errHandler)
+	}
+	if calls != 1 {
+		t.Errorf("handlers called = %d, want 1", calls)
+	}
+}
+
+func TestDispatcherPublish(t *testing.T) {
+	errSink := errors.New("sink down")
+	tests := []struct {
+		name  string
+		sinks []*memorySink
+
+		err       bool
+		published []int // events each sink received
+	}{
+		{
+			name:      "every sink receives the event",
+			sinks:     []*memorySink{{name: "first"}, {name: "second"}},
+			published: []int{1, 1},
+		},
+		{
+			name:      "a failing sink stops later ones",
+			sinks:     []*memorySink{{name: "first"}, {name: "second", err: errSink}, {name: "third"}},
+			err:       true,
+			published: []int{1, 0, 0},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var sinks []Sink
+			for _, s := range tt.sinks {
+				sinks = append(sinks, s)
+			}
+			d := &Dispatcher{sinks: sinks}
+
+			err := d.publish(context.Background(), event(1, models.EventOrderCreated))
+			if (err != nil) != tt.err {
+				t.Fatalf("publish error = %v, want error %v", err, tt.err)
+			}
+			if err != nil && (!errors.Is(err, errSink) || err.Error() != "second sink: sink down") {
+				t.Errorf("publish error = %q, want it to name the failing sink", err)
+			}
+			for i, s := range tt.sinks {
+				if len(s.events) != tt.published[i] {
+					t.Errorf("%s sink received %d events, want %d", s.name, len(s.events), tt.published[i])
+				}
+			}
+		})
+	}
+}
+
+func TestRetryDelay(t *testing.T) {
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{5, 16 * time.Second},
+		{9, 256 * time.Second},
+		{10, maxRetryDelay},
+		{50, maxRetryDelay},
+	}
+
+	for _, tt := range tests {
+		if got := retryDelay(tt.attempts); got != tt.want {
+			t.Errorf("retryDelay(%d) = %v, want %v", tt.attempts, got, tt.want)
+		}
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	tests := []struct {
+		name   string
+		status int
+		err    bool
+	}{
+		{"accepted", http.StatusNoContent, false},
+		{"rejected", http.StatusBadGateway, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var received struct {
+				ID        int64           `json:"id"`
+				EventType string          `json:"event_type"`
+				Payload   json.RawMessage `json:"payload"`
+			}
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+					t.Errorf("decode body: %v", err)
+				}
+				w.WriteHeader(tt.status)
+			}))
+			defer server.Close()
+
+			sink := HTTPSink{URL: server.URL, Client: server.Client()}
+			err := sink.Publish(context.Background(), event(7, models.EventOrderCreated))
+			if (err != nil) != tt.err {
+				t.Errorf("Publish error = %v, want error %v", err, tt.err)
+			}
+			if received.ID != 7 || received.EventType != models.EventOrderCreated || string(received.Payload) != `{}` {
+				t.Errorf("sink posted event %d %s, want 7 %s", received.ID, received.EventType, models.EventOrderCreated)
+			}
+		})
+	}
+}
@@ -0,0 +1,108 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"sync"
+
+	"go-application-task/internal/models"
+)
+
+// Sink receives published outbox events. Delivery is at least once, so sinks must tolerate duplicates.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, event models.OutboxEvent) error
+}
+
+// Handler reacts to an event published on the in-process Bus
+type Handler func(ctx context.Context, event models.OutboxEvent) error
+
+// Bus is a sink dispatching events to in-process subscribers
+type Bus struct {
+	mu          sync.RWMutex
+	subscribers map[string][]Handler
+	all         []Handler
+}
+
+// NewBus creates an empty in-process event bus
+func NewBus() *Bus {
+	return &Bus{subscribers: make(map[string][]Handler)}
+}
+
+// Subscribe registers handler for the given event types, or for every event when none are given
+func (b *Bus) Subscribe(handler Handler, eventTypes ...string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if len(eventTypes) == 0 {
+		b.all = append(b.all, handler)
+		return
+	}
+	for _, eventType := range eventTypes {
+		b.subscribers[eventType] = append(b.subscribers[eventType], handler)
+	}
+}
+
+func (b *Bus) Name() string { return "bus" }
+
+// Publish calls every matching subscriber and fails on the first subscriber error
+func (b *Bus) Publish(ctx context.Context, event models.OutboxEvent) error {
+	b.mu.RLock()
+	handlers := append(append([]Handler{}, b.subscribers[event.EventType]...), b.all...)
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		if err := handler(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// LogSink writes every event to the application log
+type LogSink struct{}
+
+func (LogSink) Name() string { return "log" }
+
+func (LogSink) Publish(ctx context.Context, event models.OutboxEvent) error {
+	log.Printf("Outbox event %d %s for %s: %s", event.ID, event.EventType, event.AggregateID, event.Payload)
+	return nil
+}
+
+// HTTPSink posts every event as JSON to a fixed URL, any non-2xx response is treated as a failure
+type HTTPSink struct {
+	URL    string
+	Client *http.Client
+}
+
+func (s HTTPSink) Name() string { return "http" }
+
+func (s HTTPSink) Publish(ctx context.Context, event models.OutboxEvent) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("sink responded with status %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,112 @@
+package pricing
+
+import (
+	"go-application-task/configs"
+	"go-application-task/internal/models"
+	"math"
+)
+
+// Delivery fees covering the base weight, and the share of the amount to collect charged for cash on delivery
+const (
+	InsideCityFee  = 60
+	OutsideCityFee = 100
+	CODRate        = 0.01
+)
+
+// Parcel is what a delivery is priced on
+type Parcel struct {
+	InsideCity      bool
+	Weight          float64
+	Length          float64
+	Width           float64
+	Height          float64
+	AmountToCollect float64
+}
+
+// SurchargeLine is what a single surcharge added to the fee
+type SurchargeLine struct {
+	SurchargeID int     `json:"surcharge_id"`
+	Name        string  `json:"name"`
+	Kind        string  `json:"kind"`
+	Amount      float64 `json:"amount"`
+}
+
+// Quote is the price of delivering a parcel broken down into its charges, and the weights it was worked out from.
+// The charges are VAT inclusive; NetFee and VAT split TotalFee into the amount before VAT and the VAT in it.
+type Quote struct {
+	ActualWeight     float64         `json:"actual_weight"`
+	VolumetricWeight float64         `json:"volumetric_weight"`
+	ChargeableWeight float64         `json:"chargeable_weight"`
+	BaseDeliveryFee  float64         `json:"base_delivery_fee"`
+	Discount         float64         `json:"discount"`
+	DeliveryFee      float64         `json:"delivery_fee"`
+	Surcharges       []SurchargeLine `json:"surcharges"`
+	Surcharge        float64         `json:"surcharge"`
+	CODFee           float64         `json:"cod_fee"`
+	NetFee           float64         `json:"net_fee"`
+	VATPercent       float64         `json:"vat_percent"`
+	VAT              float64         `json:"vat"`
+	TotalFee         float64         `json:"total_fee"`
+}
+
+// VolumetricWeight returns the weight in kg a parcel of the given size in cm is charged as
+func VolumetricWeight(length, width, height, divisor float64) float64 {
+	return round(length*width*height/divisor, 3)
+}
+
+// Calculate prices a parcel on the greater of its actual and volumetric weight.
+// Every started kg above the base weight adds the extra kg fee, and each surcharge in effect is added on top.
+func Calculate(p Parcel, cfg configs.PricingConfig, surcharges []models.Surcharge) Quote {
+	q := Quote{
+		ActualWeight:     p.Weight,
+		VolumetricWeight: VolumetricWeight(p.Length, p.Width, p.Height, cfg.VolumetricDivisor),
+		Surcharges:       []SurchargeLine{},
+		VATPercent:       cfg.VATPercent,
+	}
+	q.ChargeableWeight = math.Max(q.ActualWeight, q.VolumetricWeight)
+
+	q.DeliveryFee = OutsideCityFee
+	if p.InsideCity {
+		q.DeliveryFee = InsideCityFee
+	}
+	if extra := q.ChargeableWeight - cfg.BaseWeight; extra > 0 {
+		q.DeliveryFee += math.Ceil(extra) * cfg.ExtraKgFee
+	}
+
+	q.BaseDeliveryFee = q.DeliveryFee
+
+	// Surcharges are worked out on the delivery fee before any discount
+	for _, surcharge := range surcharges {
+		amount := surcharge.Value
+		if surcharge.Calculation == models.SurchargeCalculationPercent {
+			amount = q.BaseDeliveryFee * surcharge.Value / 100
+		}
+		line := SurchargeLine{SurchargeID: surcharge.ID, Name: surcharge.Name, Kind: surcharge.Kind, Amount: round(amount, 2)}
+		q.Surcharges = append(q.Surcharges, line)
+		q.Surcharge += line.Amount
+	}
+	q.Surcharge = round(q.Surcharge, 2)
+
+	q.CODFee = round(CODRate*p.AmountToCollect, 2)
+	q.total()
+	return q
+}
+
+// ApplyDiscount takes a promotional discount off the delivery fee, which never goes below zero
+func (q *Quote) ApplyDiscount(discount float64) {
+	q.Discount = round(math.Min(discount, q.BaseDeliveryFee), 2)
+	q.DeliveryFee = round(q.BaseDeliveryFee-q.Discount, 2)
+	q.total()
+}
+
+// total adds up the charges and splits out the VAT included in them
+func (q *Quote) total() {
+	q.TotalFee = round(q.DeliveryFee+q.Surcharge+q.CODFee, 2)
+	q.VAT = round(q.TotalFee*q.VATPercent/(100+q.VATPercent), 2)
+	q.NetFee = round(q.TotalFee-q.VAT, 2)
+}
+
+func round(v float64, places int) float64 {
+	p := math.Pow(10, float64(places))
+	return math.Round(v*p) / p
+}
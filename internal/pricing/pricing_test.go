@@ -0,0 +1,157 @@
+package pricing
+
+import (
+	"testing"
+
+	"go-application-task/configs"
+	"go-application-task/internal/models"
+)
+
+var testConfig = configs.PricingConfig{
+	VolumetricDivisor: 5000,
+	BaseWeight:        1,
+	ExtraKgFee:        15,
+	VATPercent:        15,
+}
+
+var (
+	fuelSurcharge = models.Surcharge{ID: 1, Name: "Fuel", Kind: models.SurchargeKindFuel, Calculation: models.SurchargeCalculationPercent, Value: 10}
+	peakSurcharge = models.Surcharge{ID: 2, Name: "Eid rush", Kind: models.SurchargeKindPeak, Calculation: models.SurchargeCalculationFlat, Value: 5}
+)
+
+func TestVolumetricWeight(t *testing.T) {
+	if got := VolumetricWeight(30, 20, 20, 5000); got != 2.4 {
+		t.Errorf("VolumetricWeight(30, 20, 20, 5000) = %v, want 2.4", got)
+	}
+	if got := VolumetricWeight(0, 0, 0, 5000); got != 0 {
+		t.Errorf("VolumetricWeight without dimensions = %v, want 0", got)
+	}
+}
+
+func TestCalculate(t *testing.T) {
+	tests := []struct {
+		name       string
+		parcel     Parcel
+		surcharges []models.Surcharge
+
+		chargeableWeight float64
+		deliveryFee      float64
+		surcharge        float64
+		codFee           float64
+		vat              float64
+		totalFee         float64
+	}{
+		{
+			name:             "inside city within base weight",
+			parcel:           Parcel{InsideCity: true, Weight: 0.5},
+			chargeableWeight: 0.5, deliveryFee: 60, vat: 7.83, totalFee: 60,
+		},
+		{
+			name:             "outside city within base weight",
+			parcel:           Parcel{Weight: 1},
+			chargeableWeight: 1, deliveryFee: 100, vat: 13.04, totalFee: 100,
+		},
+		{
+			name:             "whole extra kg",
+			parcel:           Parcel{InsideCity: true, Weight: 2},
+			chargeableWeight: 2, deliveryFee: 75, vat: 9.78, totalFee: 75,
+		},
+		{
+			name:             "started kg is charged in full",
+			parcel:           Parcel{Weight: 2.3},
+			chargeableWeight: 2.3, deliveryFee: 130, vat: 16.96, totalFee: 130,
+		},
+		{
+			name:             "volumetric weight above actual weight",
+			parcel:           Parcel{InsideCity: true, Weight: 1, Length: 30, Width: 20, Height: 20},
+			chargeableWeight: 2.4, deliveryFee: 90, vat: 11.74, totalFee: 90,
+		},
+		{
+			name:             "actual weight above volumetric weight",
+			parcel:           Parcel{InsideCity: true, Weight: 3, Length: 10, Width: 10, Height: 10},
+			chargeableWeight: 3, deliveryFee: 90, vat: 11.74, totalFee: 90,
+		},
+		{
+			name:             "cash on delivery",
+			parcel:           Parcel{InsideCity: true, Weight: 1, AmountToCollect: 1250},
+			chargeableWeight: 1, deliveryFee: 60, codFee: 12.5, vat: 9.46, totalFee: 72.5,
+		},
+		{
+			name:             "percent and flat surcharges",
+			parcel:           Parcel{InsideCity: true, Weight: 1, AmountToCollect: 1000},
+			surcharges:       []models.Surcharge{fuelSurcharge, peakSurcharge},
+			chargeableWeight: 1, deliveryFee: 60, surcharge: 11, codFee: 10, vat: 10.57, totalFee: 81,
+		},
+		{
+			name:             "percent surcharge on the weight based fee",
+			parcel:           Parcel{Weight: 2.3},
+			surcharges:       []models.Surcharge{fuelSurcharge},
+			chargeableWeight: 2.3, deliveryFee: 130, surcharge: 13, vat: 18.65, totalFee: 143,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := Calculate(tt.parcel, testConfig, tt.surcharges)
+			if q.ChargeableWeight != tt.chargeableWeight {
+				t.Errorf("ChargeableWeight = %v, want %v", q.ChargeableWeight, tt.chargeableWeight)
+			}
+			if q.DeliveryFee != tt.deliveryFee || q.BaseDeliveryFee != tt.deliveryFee {
+				t.Errorf("DeliveryFee = %v, BaseDeliveryFee = %v, want %v", q.DeliveryFee, q.BaseDeliveryFee, tt.deliveryFee)
+			}
+			if q.Surcharge != tt.surcharge {
+				t.Errorf("Surcharge = %v, want %v", q.Surcharge, tt.surcharge)
+			}
+			if len(q.Surcharges) != len(tt.surcharges) {
+				t.Errorf("len(Surcharges) = %d, want %d", len(q.Surcharges), len(tt.surcharges))
+			}
+			if q.CODFee != tt.codFee {
+				t.Errorf("CODFee = %v, want %v", q.CODFee, tt.codFee)
+			}
+			if q.TotalFee != tt.totalFee {
+				t.Errorf("TotalFee = %v, want %v", q.TotalFee, tt.totalFee)
+			}
+			if q.VAT != tt.vat {
+				t.Errorf("VAT = %v, want %v", q.VAT, tt.vat)
+			}
+			if q.NetFee != round(tt.totalFee-tt.vat, 2) {
+				t.Errorf("NetFee = %v, want %v", q.NetFee, round(tt.totalFee-tt.vat, 2))
+			}
+		})
+	}
+}
+
+func TestApplyDiscount(t *testing.T) {
+	tests := []struct {
+		name        string
+		discount    float64
+		applied     float64
+		deliveryFee float64
+		totalFee    float64
+	}{
+		{name: "part of the fee", discount: 20, applied: 20, deliveryFee: 40, totalFee: 46},
+		{name: "whole fee", discount: 60, applied: 60, deliveryFee: 0, totalFee: 6},
+		{name: "larger than the fee", discount: 100, applied: 60, deliveryFee: 0, totalFee: 6},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := Calculate(Parcel{InsideCity: true, Weight: 1}, testConfig, []models.Surcharge{fuelSurcharge})
+			q.ApplyDiscount(tt.discount)
+			if q.Discount != tt.applied {
+				t.Errorf("Discount = %v, want %v", q.Discount, tt.applied)
+			}
+			if q.DeliveryFee != tt.deliveryFee {
+				t.Errorf("DeliveryFee = %v, want %v", q.DeliveryFee, tt.deliveryFee)
+			}
+			// Surcharges stay worked out on the fee before the discount
+			if q.Surcharge != 6 {
+				t.Errorf("Surcharge = %v, want 6", q.Surcharge)
+			}
+			if q.TotalFee != tt.totalFee {
+				t.Errorf("TotalFee = %v, want %v", q.TotalFee, tt.totalFee)
+			}
+			if round(q.NetFee+q.VAT, 2) != q.TotalFee {
+				t.Errorf("NetFee %v + VAT %v != TotalFee %v", q.NetFee, q.VAT, q.TotalFee)
+			}
+		})
+	}
+}
@@ -0,0 +1,149 @@
+package promotion
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"go-application-task/internal/models"
+)
+
+// ErrNotFound is returned by Best when no promotion applies to an order
+var ErrNotFound = errors.New("no applicable promotion")
+
+// ErrUnavailable is returned by Redeem when a promotion stopped applying to an order after it was priced,
+// usually because its usage cap was reached meanwhile
+var ErrUnavailable = errors.New("promotion no longer available")
+
+// Order is what decides whether a promotion applies
+type Order struct {
+	UserID int
+	Zone   int
+	Code   string // empty to consider only automatic promotions
+}
+
+// NormalizeCode returns a promo code the way it is stored
+func NormalizeCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Discount returns how much a promotion takes off a delivery fee, never more than the fee itself
+func Discount(p models.Promotion, fee float64) float64 {
+	discount := p.DiscountValue
+	if p.DiscountType == models.DiscountTypePercent {
+		discount = fee * p.DiscountValue / 100
+	}
+	return math.Round(math.Min(discount, fee)*100) / 100
+}
+
+// Tx is the part of *sqlx.Tx redemptions are written through
+type Tx interface {
+	Get(dest interface{}, query string, args ...interface{}) error
+	Select(dest interface{}, query string, args ...interface{}) error
+	Exec(query string, args ...interface{}) (sql.Result, error)
+}
+
+// candidate is a promotion along with how many times the merchant ordering has redeemed it
+type candidate struct {
+	models.Promotion
+	MerchantRedemptions int `db:"merchant_redemptions"`
+}
+
+// usesLeft reports whether a promotion is under both its overall and per-merchant usage caps
+func usesLeft(p models.Promotion, merchantRedemptions int) bool {
+	if p.UsageLimit != nil && p.TimesUsed >= *p.UsageLimit {
+		return false
+	}
+	return p.PerMerchantLimit == nil || merchantRedemptions < *p.PerMerchantLimit
+}
+
+// eligibleQuery selects the active promotions in their validity window that apply to a merchant's order, and how often
+// the merchant redeemed each. Usage caps are left to usesLeft.
+// With a code only the promotion with that code is considered, otherwise only automatic ones.
+const eligibleQuery = `
+	SELECT p.*, (SELECT COUNT(*) FROM promotion_redemptions r WHERE r.promotion_id = p.id AND r.user_id = $1) AS merchant_redemptions
+	FROM promotions p
+	WHERE p.active
+	  AND (p.starts_at IS NULL OR p.starts_at <= NOW())
+	  AND (p.ends_at IS NULL OR p.ends_at > NOW())
+	  AND (p.user_id IS NULL OR p.user_id = $1)
+	  AND (cardinality(p.zones) = 0 OR $2 = ANY(p.zones))
+	  AND (($3 = '' AND p.code IS NULL) OR p.code = $3)`
+
+// Best returns the promotion giving the largest discount on an order's delivery fee, and the discount
+func Best(db sqlx.Queryer, order Order, fee float64) (models.Promotion, float64, error) {
+	var candidates []candidate
+	err := sqlx.Select(db, &candidates, eligibleQuery+` ORDER BY p.id`, order.UserID, order.Zone, NormalizeCode(order.Code))
+	if err != nil {
+		return models.Promotion{}, 0, fmt.Errorf("failed to fetch promotions: %w", err)
+	}
+
+	var best models.Promotion
+	var bestDiscount float64
+	for _, c := range candidates {
+		if !usesLeft(c.Promotion, c.MerchantRedemptions) {
+			continue
+		}
+		if discount := Discount(c.Promotion, fee); discount > bestDiscount {
+			best, bestDiscount = c.Promotion, discount
+		}
+	}
+	if bestDiscount == 0 {
+		return models.Promotion{}, 0, ErrNotFound
+	}
+	return best, bestDiscount, nil
+}
+
+// Redeem counts an order towards a promotion's usage caps. It locks the promotion so concurrent orders
+// cannot exceed a cap, and returns ErrUnavailable when the promotion no longer applies to the order.
+func Redeem(tx Tx, promotionID int, order Order, consignmentID string, discount float64) error {
+	var p models.Promotion
+	err := tx.Get(&p, `SELECT * FROM promotions WHERE id = $1 FOR UPDATE`, promotionID)
+	if err != nil {
+		return fmt.Errorf("failed to lock promotion %d: %w", promotionID, err)
+	}
+
+	var c candidate
+	err = tx.Get(&c, eligibleQuery+` AND p.id = $4`, order.UserID, order.Zone, NormalizeCode(order.Code), promotionID)
+	if err == sql.ErrNoRows {
+		return ErrUnavailable
+	}
+	if err != nil {
+		return fmt.Errorf("failed to check promotion %d: %w", promotionID, err)
+	}
+	if !usesLeft(c.Promotion, c.MerchantRedemptions) {
+		return ErrUnavailable
+	}
+
+	_, err = tx.Exec(`UPDATE promotions SET times_used = times_used + 1 WHERE id = $1`, promotionID)
+	if err == nil {
+		_, err = tx.Exec(`
+			INSERT INTO promotion_redemptions (promotion_id, user_id, consignment_id, discount)
+			VALUES ($1, $2, $3, $4)
+		`, promotionID, order.UserID, consignmentID, discount)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to redeem promotion %d: %w", promotionID, err)
+	}
+	return nil
+}
+
+// Release gives back the use an order made of its promotion, if any, so a cancelled order does not count
+// towards the promotion's usage caps. The order keeps the discount it was priced with.
+func Release(tx Tx, consignmentID string) error {
+	var promotionIDs []int
+	err := tx.Select(&promotionIDs, `DELETE FROM promotion_redemptions WHERE consignment_id = $1 RETURNING promotion_id`, consignmentID)
+	for _, promotionID := range promotionIDs {
+		if err != nil {
+			break
+		}
+		_, err = tx.Exec(`UPDATE promotions SET times_used = times_used - 1 WHERE id = $1 AND times_used > 0`, promotionID)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to release promotion of %s: %w", consignmentID, err)
+	}
+	return nil
+}
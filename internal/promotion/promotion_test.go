@@ -0,0 +1,178 @@
+package promotion
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"testing"
+
+	"go-application-task/internal/models"
+)
+
+func limit(n int) *int {
+	return &n
+}
+
+func TestDiscount(t *testing.T) {
+	tests := []struct {
+		name      string
+		promotion models.Promotion
+		fee       float64
+		want      float64
+	}{
+		{"percent", models.Promotion{DiscountType: models.DiscountTypePercent, DiscountValue: 20}, 60, 12},
+		{"percent rounds to cents", models.Promotion{DiscountType: models.DiscountTypePercent, DiscountValue: 33}, 65, 21.45},
+		{"flat", models.Promotion{DiscountType: models.DiscountTypeFlat, DiscountValue: 15}, 60, 15},
+		{"flat capped at the fee", models.Promotion{DiscountType: models.DiscountTypeFlat, DiscountValue: 100}, 60, 60},
+		{"percent capped at the fee", models.Promotion{DiscountType: models.DiscountTypePercent, DiscountValue: 150}, 60, 60},
+		{"free delivery", models.Promotion{DiscountType: models.DiscountTypePercent, DiscountValue: 100}, 60, 60},
+		{"no fee", models.Promotion{DiscountType: models.DiscountTypeFlat, DiscountValue: 15}, 0, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Discount(tt.promotion, tt.fee); got != tt.want {
+				t.Errorf("Discount() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUsesLeft(t *testing.T) {
+	tests := []struct {
+		name                string
+		promotion           models.Promotion
+		merchantRedemptions int
+		want                bool
+	}{
+		{"uncapped", models.Promotion{TimesUsed: 1000}, 50, true},
+		{"under usage limit", models.Promotion{UsageLimit: limit(10), TimesUsed: 9}, 0, true},
+		{"usage limit reached", models.Promotion{UsageLimit: limit(10), TimesUsed: 10}, 0, false},
+		{"under per-merchant limit", models.Promotion{PerMerchantLimit: limit(2)}, 1, true},
+		{"per-merchant limit reached", models.Promotion{PerMerchantLimit: limit(2)}, 2, false},
+		{"per-merchant limit reached with usage left", models.Promotion{UsageLimit: limit(10), PerMerchantLimit: limit(1), TimesUsed: 3}, 1, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := usesLeft(tt.promotion, tt.merchantRedemptions); got != tt.want {
+				t.Errorf("usesLeft() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeCode(t *testing.T) {
+	if got := NormalizeCode("  eid25 "); got != "EID25" {
+		t.Errorf("NormalizeCode() = %q, want %q", got, "EID25")
+	}
+}
+
+type redemption struct {
+	userID        int
+	consignmentID string
+}
+
+// fakeTx holds a single promotion and its redemptions in memory
+type fakeTx struct {
+	promotion   models.Promotion
+	redemptions []redemption
+}
+
+func (f *fakeTx) Get(dest interface{}, query string, args ...interface{}) error {
+	switch dest := dest.(type) {
+	case *models.Promotion:
+		*dest = f.promotion
+	case *candidate:
+		userID := args[0].(int)
+		count := 0
+		for _, r := range f.redemptions {
+			if r.userID == userID {
+				count++
+			}
+		}
+		*dest = candidate{Promotion: f.promotion, MerchantRedemptions: count}
+	}
+	return nil
+}
+
+func (f *fakeTx) Select(dest interface{}, query string, args ...interface{}) error {
+	consignmentID := args[0].(string)
+	var kept []redemption
+	for _, r := range f.redemptions {
+		if r.consignmentID == consignmentID {
+			*dest.(*[]int) = append(*dest.(*[]int), f.promotion.ID)
+		} else {
+			kept = append(kept, r)
+		}
+	}
+	f.redemptions = kept
+	return nil
+}
+
+func (f *fakeTx) Exec(query string, args ...interface{}) (sql.Result, error) {
+	switch {
+	case strings.Contains(query, "times_used + 1"):
+		f.promotion.TimesUsed++
+	case strings.Contains(query, "times_used - 1"):
+		if f.promotion.TimesUsed > 0 {
+			f.promotion.TimesUsed--
+		}
+	case strings.Contains(query, "INSERT INTO promotion_redemptions"):
+		f.redemptions = append(f.redemptions, redemption{userID: args[1].(int), consignmentID: args[2].(string)})
+	}
+	return nil, nil
+}
+
+func TestRedeemPerMerchantLimit(t *testing.T) {
+	tx := &fakeTx{promotion: models.Promotion{ID: 1, UsageLimit: limit(3), PerMerchantLimit: limit(2)}}
+	steps := []struct {
+		userID        int
+		consignmentID string
+		err           error
+	}{
+		{1, "C1", nil},
+		{1, "C2", nil},
+		{1, "C3", ErrUnavailable},
+		{2, "C4", nil},
+		{3, "C5", ErrUnavailable},
+	}
+
+	for _, s := range steps {
+		err := Redeem(tx, 1, Order{UserID: s.userID}, s.consignmentID, 10)
+		if !errors.Is(err, s.err) {
+			t.Fatalf("Redeem(merchant %d, %s) error = %v, want %v", s.userID, s.consignmentID, err, s.err)
+		}
+	}
+	if tx.promotion.TimesUsed != 3 {
+		t.Errorf("times used = %d, want 3", tx.promotion.TimesUsed)
+	}
+}
+
+func TestReleaseGivesBackAUse(t *testing.T) {
+	tx := &fakeTx{promotion: models.Promotion{ID: 1, PerMerchantLimit: limit(1)}}
+	if err := Redeem(tx, 1, Order{UserID: 1}, "C1", 10); err != nil {
+		t.Fatalf("Redeem: %v", err)
+	}
+	if err := Redeem(tx, 1, Order{UserID: 1}, "C2", 10); !errors.Is(err, ErrUnavailable) {
+		t.Fatalf("second Redeem error = %v, want %v", err, ErrUnavailable)
+	}
+
+	if err := Release(tx, "C1"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if tx.promotion.TimesUsed != 0 {
+		t.Errorf("times used after release = %d, want 0", tx.promotion.TimesUsed)
+	}
+	if err := Redeem(tx, 1, Order{UserID: 1}, "C2", 10); err != nil {
+		t.Errorf("Redeem after release: %v", err)
+	}
+
+	// Releasing an order that redeemed nothing leaves the count alone
+	if err := Release(tx, "C9"); err != nil {
+		t.Fatalf("Release: %v", err)
+	}
+	if tx.promotion.TimesUsed != 1 {
+		t.Errorf("times used = %d, want 1", tx.promotion.TimesUsed)
+	}
+}
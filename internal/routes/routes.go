@@ -4,17 +4,25 @@ import (
 	"github.com/gorilla/mux"
 	"go-application-task/internal/handlers"
 	"go-application-task/internal/middleware"
+	"go-application-task/internal/otp"
+	"go-application-task/internal/stream"
 	"go-application-task/pkg/db"
+	"go-application-task/pkg/storage"
 )
 
-func SetupRoutes() *mux.Router {
+func SetupRoutes(broker *stream.Broker, blobs storage.BlobStore, otpNotifier otp.Notifier) *mux.Router {
 	router := mux.NewRouter()
 
 	router.HandleFunc("/login", handlers.LoginHandler).Methods("POST")
 	router.HandleFunc("/refresh", handlers.RefreshTokenHandler).Methods("POST")
 
-	createOrderRoute := router.HandleFunc("/create_order", handlers.CreateOrderHandler).Methods("POST")
-	createOrderRoute.Handler(middleware.JWTMiddleware(createOrderRoute.GetHandler()))
+	router.HandleFunc("/track/{consignment_id}", handlers.TrackOrderHandler(db.ReadDB)).Methods("GET")
+
+	createOrderRoute := router.HandleFunc("/create_order", handlers.CreateOrderHandler(otpNotifier)).Methods("POST")
+	createOrderRoute.Handler(middleware.JWTMiddleware(handlers.CreateOrderHandler(otpNotifier)))
+
+	quoteRoute := router.HandleFunc("/quote", handlers.QuoteHandler(db.ReadDB)).Methods("POST")
+	quoteRoute.Handler(middleware.JWTMiddleware(handlers.QuoteHandler(db.ReadDB)))
 
 	getOrderRoute := router.HandleFunc("/orders", handlers.ListOrdersHandler(db.ReadDB)).Methods("GET")
 	getOrderRoute.Handler(middleware.JWTMiddleware(handlers.ListOrdersHandler(db.ReadDB)))
@@ -22,5 +30,236 @@ func SetupRoutes() *mux.Router {
 	cancelOrderRoute := router.HandleFunc("/cancel-order", handlers.CancelOrderHandler(db.WriteDB)).Methods("POST")
 	cancelOrderRoute.Handler(middleware.JWTMiddleware(handlers.CancelOrderHandler(db.WriteDB)))
 
+	deleteOrderRoute := router.HandleFunc("/orders/{consignment_id}", handlers.CancelOrderHandler(db.WriteDB)).Methods("DELETE")
+	deleteOrderRoute.Handler(middleware.JWTMiddleware(handlers.CancelOrderHandler(db.WriteDB)))
+
+	bulkCancelRoute := router.HandleFunc("/orders/cancel", handlers.BulkCancelOrdersHandler(db.WriteDB)).Methods("POST")
+	bulkCancelRoute.Handler(middleware.JWTMiddleware(handlers.BulkCancelOrdersHandler(db.WriteDB)))
+
+	orderStreamRoute := router.HandleFunc("/orders/stream", handlers.OrderStreamHandler(db.ReadDB, broker)).Methods("GET")
+	orderStreamRoute.Handler(middleware.JWTMiddleware(handlers.OrderStreamHandler(db.ReadDB, broker)))
+
+	exportOrdersRoute := router.HandleFunc("/orders/export", handlers.ExportOrdersHandler(db.ReadDB)).Methods("GET")
+	exportOrdersRoute.Handler(middleware.JWTMiddleware(handlers.ExportOrdersHandler(db.ReadDB)))
+
+	getOrderDetailRoute := router.HandleFunc("/orders/{consignment_id}", handlers.GetOrderHandler(db.ReadDB)).Methods("GET")
+	getOrderDetailRoute.Handler(middleware.JWTMiddleware(handlers.GetOrderHandler(db.ReadDB)))
+
+	podFileRoute := router.HandleFunc("/orders/{consignment_id}/pod/{kind:photo|signature}", handlers.ProofOfDeliveryFileHandler(db.ReadDB, blobs)).Methods("GET")
+	podFileRoute.Handler(middleware.JWTMiddleware(handlers.ProofOfDeliveryFileHandler(db.ReadDB, blobs)))
+
+	orderLabelRoute := router.HandleFunc("/orders/{consignment_id}/label", handlers.OrderLabelHandler(db.ReadDB)).Methods("GET")
+	orderLabelRoute.Handler(middleware.JWTMiddleware(handlers.OrderLabelHandler(db.ReadDB)))
+
+	bulkLabelsRoute := router.HandleFunc("/orders/labels", handlers.BulkOrderLabelsHandler(db.ReadDB)).Methods("POST")
+	bulkLabelsRoute.Handler(middleware.JWTMiddleware(handlers.BulkOrderLabelsHandler(db.ReadDB)))
+
+	createWebhookRoute := router.HandleFunc("/webhooks", handlers.CreateWebhookHandler(db.WriteDB)).Methods("POST")
+	createWebhookRoute.Handler(middleware.JWTMiddleware(handlers.CreateWebhookHandler(db.WriteDB)))
+
+	listWebhooksRoute := router.HandleFunc("/webhooks", handlers.ListWebhooksHandler(db.ReadDB)).Methods("GET")
+	listWebhooksRoute.Handler(middleware.JWTMiddleware(handlers.ListWebhooksHandler(db.ReadDB)))
+
+	deleteWebhookRoute := router.HandleFunc("/webhooks/{id:[0-9]+}", handlers.DeleteWebhookHandler(db.WriteDB)).Methods("DELETE")
+	deleteWebhookRoute.Handler(middleware.JWTMiddleware(handlers.DeleteWebhookHandler(db.WriteDB)))
+
+	webhookDeliveriesRoute := router.HandleFunc("/webhooks/{id:[0-9]+}/deliveries", handlers.ListWebhookDeliveriesHandler(db.ReadDB)).Methods("GET")
+	webhookDeliveriesRoute.Handler(middleware.JWTMiddleware(handlers.ListWebhookDeliveriesHandler(db.ReadDB)))
+
+	webhookDeliveryRoute := router.HandleFunc("/webhooks/deliveries/{id:[0-9]+}", handlers.GetWebhookDeliveryHandler(db.ReadDB)).Methods("GET")
+	webhookDeliveryRoute.Handler(middleware.JWTMiddleware(handlers.GetWebhookDeliveryHandler(db.ReadDB)))
+
+	redeliverWebhookRoute := router.HandleFunc("/webhooks/deliveries/{id:[0-9]+}/redeliver", handlers.RedeliverWebhookHandler(db.WriteDB)).Methods("POST")
+	redeliverWebhookRoute.Handler(middleware.JWTMiddleware(handlers.RedeliverWebhookHandler(db.WriteDB)))
+
+	getNotificationSettingsRoute := router.HandleFunc("/stores/{store_id}/notification-settings", handlers.GetNotificationSettingsHandler(db.ReadDB)).Methods("GET")
+	getNotificationSettingsRoute.Handler(middleware.JWTMiddleware(handlers.GetNotificationSettingsHandler(db.ReadDB)))
+
+	updateNotificationSettingsRoute := router.HandleFunc("/stores/{store_id}/notification-settings", handlers.UpdateNotificationSettingsHandler(db.WriteDB)).Methods("PUT")
+	updateNotificationSettingsRoute.Handler(middleware.JWTMiddleware(handlers.UpdateNotificationSettingsHandler(db.WriteDB)))
+
+	getDeliverySettingsRoute := router.HandleFunc("/stores/{store_id}/delivery-settings", handlers.GetDeliverySettingsHandler(db.ReadDB)).Methods("GET")
+	getDeliverySettingsRoute.Handler(middleware.JWTMiddleware(handlers.GetDeliverySettingsHandler(db.ReadDB)))
+
+	updateDeliverySettingsRoute := router.HandleFunc("/stores/{store_id}/delivery-settings", handlers.UpdateDeliverySettingsHandler(db.WriteDB)).Methods("PUT")
+	updateDeliverySettingsRoute.Handler(middleware.JWTMiddleware(handlers.UpdateDeliverySettingsHandler(db.WriteDB)))
+
+	resendDeliveryOTPRoute := router.HandleFunc("/orders/{consignment_id}/otp/resend", handlers.ResendDeliveryOTPHandler(db.WriteDB, otpNotifier)).Methods("POST")
+	resendDeliveryOTPRoute.Handler(middleware.JWTMiddleware(handlers.ResendDeliveryOTPHandler(db.WriteDB, otpNotifier)))
+
+	orderNotificationsRoute := router.HandleFunc("/orders/{consignment_id}/notifications", handlers.ListOrderNotificationsHandler(db.ReadDB)).Methods("GET")
+	orderNotificationsRoute.Handler(middleware.JWTMiddleware(handlers.ListOrderNotificationsHandler(db.ReadDB)))
+
+	initiateReturnRoute := router.HandleFunc("/orders/{consignment_id}/return", handlers.InitiateReturnHandler(db.WriteDB)).Methods("POST")
+	initiateReturnRoute.Handler(middleware.JWTMiddleware(handlers.InitiateReturnHandler(db.WriteDB)))
+
+	partialDeliveryRoute := router.HandleFunc("/orders/{consignment_id}/partial-delivery", handlers.PartialDeliveryHandler(db.WriteDB)).Methods("POST")
+	partialDeliveryRoute.Handler(middleware.JWTMiddleware(handlers.PartialDeliveryHandler(db.WriteDB)))
+
+	createPickupRoute := router.HandleFunc("/pickups", handlers.CreatePickupHandler(db.WriteDB)).Methods("POST")
+	createPickupRoute.Handler(middleware.JWTMiddleware(handlers.CreatePickupHandler(db.WriteDB)))
+
+	listPickupsRoute := router.HandleFunc("/pickups", handlers.ListPickupsHandler(db.ReadDB)).Methods("GET")
+	listPickupsRoute.Handler(middleware.JWTMiddleware(handlers.ListPickupsHandler(db.ReadDB)))
+
+	confirmPickupRoute := router.HandleFunc("/pickups/{pickup_id}/confirm", handlers.ConfirmPickupHandler(db.WriteDB)).Methods("POST")
+	confirmPickupRoute.Handler(middleware.JWTMiddleware(handlers.ConfirmPickupHandler(db.WriteDB)))
+
+	reschedulePickupRoute := router.HandleFunc("/pickups/{pickup_id}/reschedule", handlers.ReschedulePickupHandler(db.WriteDB)).Methods("POST")
+	reschedulePickupRoute.Handler(middleware.JWTMiddleware(handlers.ReschedulePickupHandler(db.WriteDB)))
+
+	createRiderRoute := router.HandleFunc("/riders", handlers.CreateRiderHandler(db.WriteDB)).Methods("POST")
+	createRiderRoute.Handler(middleware.JWTMiddleware(handlers.CreateRiderHandler(db.WriteDB)))
+
+	listRidersRoute := router.HandleFunc("/riders", handlers.ListRidersHandler(db.ReadDB)).Methods("GET")
+	listRidersRoute.Handler(middleware.JWTMiddleware(handlers.ListRidersHandler(db.ReadDB)))
+
+	updateRiderRoute := router.HandleFunc("/riders/{id:[0-9]+}", handlers.UpdateRiderHandler(db.WriteDB)).Methods("PATCH")
+	updateRiderRoute.Handler(middleware.JWTMiddleware(handlers.UpdateRiderHandler(db.WriteDB)))
+
+	assignOrderRoute := router.HandleFunc("/orders/{consignment_id}/assign", handlers.AssignOrderHandler(db.WriteDB)).Methods("POST")
+	assignOrderRoute.Handler(middleware.JWTMiddleware(handlers.AssignOrderHandler(db.WriteDB)))
+
+	riderParcelsRoute := router.HandleFunc("/rider/parcels", handlers.RiderParcelsHandler(db.ReadDB)).Methods("GET")
+	riderParcelsRoute.Handler(middleware.JWTMiddleware(handlers.RiderParcelsHandler(db.ReadDB)))
+
+	riderStatusRoute := router.HandleFunc("/rider/parcels/{consignment_id}/status", handlers.RiderUpdateStatusHandler(db.WriteDB)).Methods("POST")
+	riderStatusRoute.Handler(middleware.JWTMiddleware(handlers.RiderUpdateStatusHandler(db.WriteDB)))
+
+	podRoute := router.HandleFunc("/rider/parcels/{consignment_id}/pod", handlers.ProofOfDeliveryHandler(db.WriteDB, blobs)).Methods("POST")
+	podRoute.Handler(middleware.JWTMiddleware(handlers.ProofOfDeliveryHandler(db.WriteDB, blobs)))
+
+	createHubRoute := router.HandleFunc("/hubs", handlers.CreateHubHandler(db.WriteDB)).Methods("POST")
+	createHubRoute.Handler(middleware.JWTMiddleware(handlers.CreateHubHandler(db.WriteDB)))
+
+	listHubsRoute := router.HandleFunc("/hubs", handlers.ListHubsHandler(db.ReadDB)).Methods("GET")
+	listHubsRoute.Handler(middleware.JWTMiddleware(handlers.ListHubsHandler(db.ReadDB)))
+
+	hubParcelsRoute := router.HandleFunc("/hubs/{id:[0-9]+}/parcels", handlers.HubParcelsHandler(db.ReadDB)).Methods("GET")
+	hubParcelsRoute.Handler(middleware.JWTMiddleware(handlers.HubParcelsHandler(db.ReadDB)))
+
+	hubInboundRoute := router.HandleFunc("/hubs/{id:[0-9]+}/inbound", handlers.HubInboundScanHandler(db.WriteDB)).Methods("POST")
+	hubInboundRoute.Handler(middleware.JWTMiddleware(handlers.HubInboundScanHandler(db.WriteDB)))
+
+	cashDepositRoute := router.HandleFunc("/hubs/{id:[0-9]+}/cash-deposits", handlers.CreateCashDepositHandler(db.WriteDB)).Methods("POST")
+	cashDepositRoute.Handler(middleware.JWTMiddleware(handlers.CreateCashDepositHandler(db.WriteDB)))
+
+	cashReconciliationRoute := router.HandleFunc("/hubs/{id:[0-9]+}/cash-reconciliation", handlers.CashReconciliationHandler(db.ReadDB)).Methods("GET")
+	cashReconciliationRoute.Handler(middleware.JWTMiddleware(handlers.CashReconciliationHandler(db.ReadDB)))
+
+	createTransferRoute := router.HandleFunc("/transfers", handlers.CreateTransferHandler(db.WriteDB)).Methods("POST")
+	createTransferRoute.Handler(middleware.JWTMiddleware(handlers.CreateTransferHandler(db.WriteDB)))
+
+	listTransfersRoute := router.HandleFunc("/transfers", handlers.ListTransfersHandler(db.ReadDB)).Methods("GET")
+	listTransfersRoute.Handler(middleware.JWTMiddleware(handlers.ListTransfersHandler(db.ReadDB)))
+
+	getTransferRoute := router.HandleFunc("/transfers/{id:[0-9]+}", handlers.GetTransferHandler(db.ReadDB)).Methods("GET")
+	getTransferRoute.Handler(middleware.JWTMiddleware(handlers.GetTransferHandler(db.ReadDB)))
+
+	dispatchTransferRoute := router.HandleFunc("/transfers/{id:[0-9]+}/dispatch", handlers.DispatchTransferHandler(db.WriteDB)).Methods("POST")
+	dispatchTransferRoute.Handler(middleware.JWTMiddleware(handlers.DispatchTransferHandler(db.WriteDB)))
+
+	receiveTransferRoute := router.HandleFunc("/transfers/{id:[0-9]+}/receive", handlers.ReceiveTransferHandler(db.WriteDB)).Methods("POST")
+	receiveTransferRoute.Handler(middleware.JWTMiddleware(handlers.ReceiveTransferHandler(db.WriteDB)))
+
+	listReturnsRoute := router.HandleFunc("/returns", handlers.ListReturnsHandler(db.ReadDB)).Methods("GET")
+	listReturnsRoute.Handler(middleware.JWTMiddleware(handlers.ListReturnsHandler(db.ReadDB)))
+
+	updateReturnStatusRoute := router.HandleFunc("/returns/{return_consignment_id}/status", handlers.UpdateReturnStatusHandler(db.WriteDB)).Methods("POST")
+	updateReturnStatusRoute.Handler(middleware.JWTMiddleware(handlers.UpdateReturnStatusHandler(db.WriteDB)))
+
+	balanceRoute := router.HandleFunc("/balance", handlers.BalanceHandler(db.ReadDB)).Methods("GET")
+	balanceRoute.Handler(middleware.JWTMiddleware(handlers.BalanceHandler(db.ReadDB)))
+
+	createPayoutsRoute := router.HandleFunc("/payouts", handlers.CreatePayoutsHandler(db.WriteDB)).Methods("POST")
+	createPayoutsRoute.Handler(middleware.JWTMiddleware(handlers.CreatePayoutsHandler(db.WriteDB)))
+
+	listPayoutsRoute := router.HandleFunc("/payouts", handlers.ListPayoutsHandler(db.ReadDB)).Methods("GET")
+	listPayoutsRoute.Handler(middleware.JWTMiddleware(handlers.ListPayoutsHandler(db.ReadDB)))
+
+	getPayoutRoute := router.HandleFunc("/payouts/{id:[0-9]+}", handlers.GetPayoutHandler(db.ReadDB)).Methods("GET")
+	getPayoutRoute.Handler(middleware.JWTMiddleware(handlers.GetPayoutHandler(db.ReadDB)))
+
+	resolvePayoutRoute := router.HandleFunc("/payouts/{id:[0-9]+}/resolve", handlers.ResolvePayoutHandler(db.WriteDB)).Methods("POST")
+	resolvePayoutRoute.Handler(middleware.JWTMiddleware(handlers.ResolvePayoutHandler(db.WriteDB)))
+
+	// Providers authenticate status updates with a signature instead of a token
+	router.HandleFunc("/payouts/callback", handlers.PayoutCallbackHandler(db.WriteDB)).Methods("POST")
+
+	getPayoutMethodRoute := router.HandleFunc("/payout-method", handlers.GetPayoutMethodHandler(db.ReadDB)).Methods("GET")
+	getPayoutMethodRoute.Handler(middleware.JWTMiddleware(handlers.GetPayoutMethodHandler(db.ReadDB)))
+
+	updatePayoutMethodRoute := router.HandleFunc("/payout-method", handlers.UpdatePayoutMethodHandler(db.WriteDB)).Methods("PUT")
+	updatePayoutMethodRoute.Handler(middleware.JWTMiddleware(handlers.UpdatePayoutMethodHandler(db.WriteDB)))
+
+	createPromotionRoute := router.HandleFunc("/promotions", handlers.CreatePromotionHandler(db.WriteDB)).Methods("POST")
+	createPromotionRoute.Handler(middleware.JWTMiddleware(handlers.CreatePromotionHandler(db.WriteDB)))
+
+	listPromotionsRoute := router.HandleFunc("/promotions", handlers.ListPromotionsHandler(db.ReadDB)).Methods("GET")
+	listPromotionsRoute.Handler(middleware.JWTMiddleware(handlers.ListPromotionsHandler(db.ReadDB)))
+
+	updatePromotionRoute := router.HandleFunc("/promotions/{id:[0-9]+}", handlers.UpdatePromotionHandler(db.WriteDB)).Methods("PUT")
+	updatePromotionRoute.Handler(middleware.JWTMiddleware(handlers.UpdatePromotionHandler(db.WriteDB)))
+
+	createSurchargeRoute := router.HandleFunc("/surcharges", handlers.CreateSurchargeHandler(db.WriteDB)).Methods("POST")
+	createSurchargeRoute.Handler(middleware.JWTMiddleware(handlers.CreateSurchargeHandler(db.WriteDB)))
+
+	listSurchargesRoute := router.HandleFunc("/surcharges", handlers.ListSurchargesHandler(db.ReadDB)).Methods("GET")
+	listSurchargesRoute.Handler(middleware.JWTMiddleware(handlers.ListSurchargesHandler(db.ReadDB)))
+
+	updateSurchargeRoute := router.HandleFunc("/surcharges/{id:[0-9]+}", handlers.UpdateSurchargeHandler(db.WriteDB)).Methods("PUT")
+	updateSurchargeRoute.Handler(middleware.JWTMiddleware(handlers.UpdateSurchargeHandler(db.WriteDB)))
+
+	createClaimRoute := router.HandleFunc("/claims", handlers.CreateClaimHandler(db.WriteDB, blobs)).Methods("POST")
+	createClaimRoute.Handler(middleware.JWTMiddleware(handlers.CreateClaimHandler(db.WriteDB, blobs)))
+
+	listClaimsRoute := router.HandleFunc("/claims", handlers.ListClaimsHandler(db.ReadDB)).Methods("GET")
+	listClaimsRoute.Handler(middleware.JWTMiddleware(handlers.ListClaimsHandler(db.ReadDB)))
+
+	getClaimRoute := router.HandleFunc("/claims/{id:[0-9]+}", handlers.GetClaimHandler(db.ReadDB)).Methods("GET")
+	getClaimRoute.Handler(middleware.JWTMiddleware(handlers.GetClaimHandler(db.ReadDB)))
+
+	claimEvidenceRoute := router.HandleFunc("/claims/{id:[0-9]+}/evidence/{evidence_id:[0-9]+}", handlers.ClaimEvidenceFileHandler(db.ReadDB, blobs)).Methods("GET")
+	claimEvidenceRoute.Handler(middleware.JWTMiddleware(handlers.ClaimEvidenceFileHandler(db.ReadDB, blobs)))
+
+	claimCommentRoute := router.HandleFunc("/claims/{id:[0-9]+}/comments", handlers.AddClaimCommentHandler(db.WriteDB)).Methods("POST")
+	claimCommentRoute.Handler(middleware.JWTMiddleware(handlers.AddClaimCommentHandler(db.WriteDB)))
+
+	claimStatusRoute := router.HandleFunc("/claims/{id:[0-9]+}/status", handlers.UpdateClaimStatusHandler(db.WriteDB)).Methods("PUT")
+	claimStatusRoute.Handler(middleware.JWTMiddleware(handlers.UpdateClaimStatusHandler(db.WriteDB)))
+
+	createOrderCommentRoute := router.HandleFunc("/orders/{consignment_id}/comments", handlers.CreateOrderCommentHandler(db.WriteDB, blobs)).Methods("POST")
+	createOrderCommentRoute.Handler(middleware.JWTMiddleware(handlers.CreateOrderCommentHandler(db.WriteDB, blobs)))
+
+	listOrderCommentsRoute := router.HandleFunc("/orders/{consignment_id}/comments", handlers.ListOrderCommentsHandler(db.ReadDB)).Methods("GET")
+	listOrderCommentsRoute.Handler(middleware.JWTMiddleware(handlers.ListOrderCommentsHandler(db.ReadDB)))
+
+	commentAttachmentRoute := router.HandleFunc("/orders/{consignment_id}/comments/{comment_id:[0-9]+}/attachments/{attachment_id:[0-9]+}", handlers.CommentAttachmentFileHandler(db.ReadDB, blobs)).Methods("GET")
+	commentAttachmentRoute.Handler(middleware.JWTMiddleware(handlers.CommentAttachmentFileHandler(db.ReadDB, blobs)))
+
+	listCancellationReasonsRoute := router.HandleFunc("/cancellation-reasons", handlers.ListCancellationReasonsHandler(db.ReadDB)).Methods("GET")
+	listCancellationReasonsRoute.Handler(middleware.JWTMiddleware(handlers.ListCancellationReasonsHandler(db.ReadDB)))
+
+	createCancellationReasonRoute := router.HandleFunc("/cancellation-reasons", handlers.CreateCancellationReasonHandler(db.WriteDB)).Methods("POST")
+	createCancellationReasonRoute.Handler(middleware.JWTMiddleware(handlers.CreateCancellationReasonHandler(db.WriteDB)))
+
+	updateCancellationReasonRoute := router.HandleFunc("/cancellation-reasons/{id:[0-9]+}", handlers.UpdateCancellationReasonHandler(db.WriteDB)).Methods("PUT")
+	updateCancellationReasonRoute.Handler(middleware.JWTMiddleware(handlers.UpdateCancellationReasonHandler(db.WriteDB)))
+
+	createInvoiceRoute := router.HandleFunc("/invoices", handlers.CreateInvoiceHandler(db.WriteDB)).Methods("POST")
+	createInvoiceRoute.Handler(middleware.JWTMiddleware(handlers.CreateInvoiceHandler(db.WriteDB)))
+
+	listInvoicesRoute := router.HandleFunc("/invoices", handlers.ListInvoicesHandler(db.ReadDB)).Methods("GET")
+	listInvoicesRoute.Handler(middleware.JWTMiddleware(handlers.ListInvoicesHandler(db.ReadDB)))
+
+	getInvoiceRoute := router.HandleFunc("/invoices/{id:[0-9]+}", handlers.GetInvoiceHandler(db.ReadDB)).Methods("GET")
+	getInvoiceRoute.Handler(middleware.JWTMiddleware(handlers.GetInvoiceHandler(db.ReadDB)))
+
+	invoicePDFRoute := router.HandleFunc("/invoices/{id:[0-9]+}.pdf", handlers.InvoicePDFHandler(db.ReadDB)).Methods("GET")
+	invoicePDFRoute.Handler(middleware.JWTMiddleware(handlers.InvoicePDFHandler(db.ReadDB)))
+
+	creditNoteRoute := router.HandleFunc("/invoices/{id:[0-9]+}/credit-notes", handlers.CreateCreditNoteHandler(db.WriteDB)).Methods("POST")
+	creditNoteRoute.Handler(middleware.JWTMiddleware(handlers.CreateCreditNoteHandler(db.WriteDB)))
+
 	return router
 }
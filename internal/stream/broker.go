@@ -0,0 +1,137 @@
+package stream
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+
+	"go-application-task/internal/models"
+	"go-application-task/internal/outbox"
+)
+
+// subscriberBuffer is how many events a subscriber may fall behind before it is dropped.
+// A dropped client reconnects with Last-Event-ID and catches up from the event log.
+const subscriberBuffer = 64
+
+// Event is an order event delivered to stream subscribers.
+// Seq is assigned by the broker and increases in the order events are published.
+type Event struct {
+	Seq    uint64
+	UserID int
+	Type   string
+	Data   json.RawMessage
+}
+
+// Subscription receives the events of a single user until it is closed
+type Subscription struct {
+	broker *Broker
+	userID int
+	events chan Event
+}
+
+// Events returns the channel events are delivered on, it is closed when the subscription ends
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close unsubscribes, it is safe to call more than once
+func (s *Subscription) Close() {
+	s.broker.unsubscribe(s)
+}
+
+// Broker fans order events out to stream subscribers and keeps a bounded log of recent events for resuming
+type Broker struct {
+	mu          sync.Mutex
+	seq         uint64
+	log         []Event
+	logSize     int
+	subscribers map[int]map[*Subscription]struct{}
+}
+
+// NewBroker creates a broker remembering the last logSize events
+func NewBroker(logSize int) *Broker {
+	return &Broker{
+		logSize:     logSize,
+		subscribers: make(map[int]map[*Subscription]struct{}),
+	}
+}
+
+// Handler returns an outbox handler that publishes order events to the broker
+func (b *Broker) Handler() outbox.Handler {
+	return func(ctx context.Context, event models.OutboxEvent) error {
+		if event.UserID != nil {
+			b.Publish(*event.UserID, event.EventType, json.RawMessage(event.Payload))
+		}
+		return nil
+	}
+}
+
+// Publish appends an event to the log and delivers it to the user's subscribers
+func (b *Broker) Publish(userID int, eventType string, data json.RawMessage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	event := Event{Seq: b.seq, UserID: userID, Type: eventType, Data: append(json.RawMessage{}, data...)}
+	b.log = append(b.log, event)
+	if len(b.log) > b.logSize {
+		b.log = b.log[len(b.log)-b.logSize:]
+	}
+
+	for sub := range b.subscribers[userID] {
+		select {
+		case sub.events <- event:
+		default:
+			// The subscriber is too slow, drop it so it reconnects and resumes from the log
+			b.remove(sub)
+		}
+	}
+}
+
+// Subscribe registers a subscriber for userID. When lastSeq is non-zero the user's events after it are returned
+// as backlog; complete is false when some of those events have already left the log.
+func (b *Broker) Subscribe(userID int, lastSeq uint64) (sub *Subscription, backlog []Event, complete bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub = &Subscription{broker: b, userID: userID, events: make(chan Event, subscriberBuffer)}
+	if b.subscribers[userID] == nil {
+		b.subscribers[userID] = make(map[*Subscription]struct{})
+	}
+	b.subscribers[userID][sub] = struct{}{}
+
+	complete = true
+	if lastSeq == 0 {
+		return sub, nil, complete
+	}
+	if lastSeq > b.seq || (len(b.log) > 0 && b.log[0].Seq > lastSeq+1) {
+		// The client saw events the log no longer holds, or events from before a restart
+		complete = false
+	}
+	for _, event := range b.log {
+		if event.Seq > lastSeq && event.UserID == userID {
+			backlog = append(backlog, event)
+		}
+	}
+	return sub, backlog, complete
+}
+
+// unsubscribe removes a subscription if it is still registered
+func (b *Broker) unsubscribe(sub *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.remove(sub)
+}
+
+// remove deletes a subscription and closes its channel, b.mu must be held
+func (b *Broker) remove(sub *Subscription) {
+	subs := b.subscribers[sub.userID]
+	if _, ok := subs[sub]; !ok {
+		return
+	}
+	delete(subs, sub)
+	if len(subs) == 0 {
+		delete(b.subscribers, sub.userID)
+	}
+	close(sub.events)
+}
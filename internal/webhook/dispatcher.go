@@ -0,0 +1,193 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go-application-task/configs"
+	"go-application-task/internal/models"
+)
+
+// batchSize is how many due deliveries are claimed per poll
+const batchSize = 50
+
+// leaseDuration keeps a claimed delivery from being picked up again while it is being sent
+const leaseDuration = 2 * time.Minute
+
+// maxBackoff caps the delay between two attempts of the same delivery
+const maxBackoff = 6 * time.Hour
+
+// dueDelivery is a claimed delivery together with the endpoint it is sent to
+type dueDelivery struct {
+	models.WebhookDelivery
+	URL    string `db:"url"`
+	Secret string `db:"secret"`
+}
+
+// Dispatcher sends queued webhook deliveries in the background
+type Dispatcher struct {
+	db     *sqlx.DB
+	client *http.Client
+	config configs.WebhookConfig
+}
+
+// NewDispatcher creates a dispatcher reading deliveries from db
+func NewDispatcher(db *sqlx.DB, config configs.WebhookConfig) *Dispatcher {
+	return &Dispatcher{
+		db:     db,
+		client: &http.Client{Timeout: config.Timeout},
+		config: config,
+	}
+}
+
+// Run polls for due deliveries until ctx is cancelled
+func (d *Dispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(d.config.PollInterval)
+	defer ticker.Stop()
+
+	log.Println("Webhook dispatcher started")
+	for {
+		for {
+			n, err := d.dispatchDue(ctx)
+			if err != nil {
+				log.Printf("Webhook dispatch error: %v", err)
+			}
+			// Keep draining while full batches are being claimed
+			if err != nil || n < batchSize {
+				break
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			log.Println("Webhook dispatcher stopped")
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// dispatchDue claims a batch of due deliveries, sends them and returns how many were claimed
+func (d *Dispatcher) dispatchDue(ctx context.Context) (int, error) {
+	var deliveries []dueDelivery
+	err := d.db.SelectContext(ctx, &deliveries, `
+		WITH due AS (
+			SELECT d.id FROM webhook_deliveries d
+			JOIN webhook_endpoints e ON e.id = d.endpoint_id
+			WHERE d.status IN ('pending', 'failed') AND d.next_attempt_at <= NOW() AND e.active
+			ORDER BY d.next_attempt_at, d.id
+			LIMIT $1
+			FOR UPDATE OF d SKIP LOCKED
+		)
+		UPDATE webhook_deliveries wd
+		SET next_attempt_at = NOW() + $2 * INTERVAL '1 second'
+		FROM due, webhook_endpoints e
+		WHERE wd.id = due.id AND e.id = wd.endpoint_id AND e.active
+		RETURNING wd.*, e.url, e.secret
+	`, batchSize, int(leaseDuration.Seconds()))
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	for _, delivery := range deliveries {
+		d.deliver(ctx, delivery)
+	}
+	return len(deliveries), nil
+}
+
+// deliver sends a single delivery and records the outcome
+func (d *Dispatcher) deliver(ctx context.Context, delivery dueDelivery) {
+	attempt := delivery.Attempts + 1
+	start := time.Now()
+	responseCode, sendErr := d.send(ctx, delivery)
+	duration := time.Since(start)
+
+	var responseCodeValue, errValue interface{}
+	if responseCode != 0 {
+		responseCodeValue = responseCode
+	}
+	if sendErr != nil {
+		errValue = sendErr.Error()
+	}
+
+	status := models.DeliverySucceeded
+	var nextAttempt interface{}
+	if sendErr != nil {
+		if attempt >= d.config.MaxAttempts {
+			status = models.DeliveryDead
+			log.Printf("Webhook delivery %d dead-lettered after %d attempts: %v", delivery.ID, attempt, sendErr)
+		} else {
+			status = models.DeliveryFailed
+			nextAttempt = time.Now().Add(d.backoff(attempt))
+		}
+	}
+
+	tx, err := d.db.Beginx()
+	if err != nil {
+		log.Printf("Failed to record webhook delivery %d: %v", delivery.ID, err)
+		return
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO webhook_delivery_attempts (delivery_id, attempt, response_code, error, duration_ms)
+		VALUES ($1, $2, $3, $4, $5)
+	`, delivery.ID, attempt, responseCodeValue, errValue, duration.Milliseconds())
+	if err == nil {
+		_, err = tx.Exec(`
+			UPDATE webhook_deliveries
+			SET status = $2, attempts = $3, next_attempt_at = $4, last_response_code = $5, last_error = $6, updated_at = NOW()
+			WHERE id = $1
+		`, delivery.ID, status, attempt, nextAttempt, responseCodeValue, errValue)
+	}
+	if err == nil {
+		err = tx.Commit()
+	}
+	if err != nil {
+		log.Printf("Failed to record webhook delivery %d: %v", delivery.ID, err)
+	}
+}
+
+// send posts the signed payload and returns the response code, any non-2xx response is an error
+func (d *Dispatcher) send(ctx context.Context, delivery dueDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, delivery.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, fmt.Errorf("invalid request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-application-task-webhooks/1.0")
+	req.Header.Set(HeaderEvent, delivery.Event)
+	req.Header.Set(HeaderDeliveryID, strconv.Itoa(delivery.ID))
+	req.Header.Set(HeaderSignature, Sign(delivery.Secret, delivery.Payload))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("endpoint responded with status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// backoff returns the delay before the next attempt, doubling with every failed attempt
+func (d *Dispatcher) backoff(attempt int) time.Duration {
+	delay := d.config.BaseBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= maxBackoff {
+			return maxBackoff
+		}
+	}
+	return delay
+}
@@ -0,0 +1,96 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"go-application-task/internal/models"
+	"go-application-task/internal/outbox"
+)
+
+// Headers sent with every webhook request
+const (
+	HeaderEvent      = "X-Webhook-Event"
+	HeaderDeliveryID = "X-Webhook-Delivery"
+	HeaderSignature  = "X-Webhook-Signature"
+)
+
+// Payload is the JSON body posted to webhook endpoints.
+// ID is the outbox event ID, merchants can use it to ignore an event they have already processed.
+type Payload struct {
+	ID        int64        `json:"id"`
+	Event     string       `json:"event"`
+	CreatedAt time.Time    `json:"created_at"`
+	Data      models.JSONB `json:"data"`
+}
+
+// Sign returns the hex encoded HMAC-SHA256 of payload using the endpoint secret,
+// prefixed with the algorithm the same way it is sent in the signature header
+func Sign(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature is a valid signature of payload for secret
+func Verify(secret string, payload []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, payload)), []byte(signature))
+}
+
+// GenerateSecret returns a new random signing secret for an endpoint
+func GenerateSecret() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return "whsec_" + hex.EncodeToString(b), nil
+}
+
+// IsEvent reports whether event is a known webhook event
+func IsEvent(event string) bool {
+	for _, e := range models.WebhookEvents {
+		if e == event {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscriber returns an outbox handler that queues a delivery of every webhook event
+// for each active endpoint of the event's merchant subscribed to it
+func Subscriber(db *sqlx.DB) outbox.Handler {
+	return func(ctx context.Context, event models.OutboxEvent) error {
+		if event.UserID == nil {
+			return nil
+		}
+
+		payload, err := json.Marshal(Payload{
+			ID:        event.ID,
+			Event:     event.EventType,
+			CreatedAt: event.CreatedAt.UTC(),
+			Data:      event.Payload,
+		})
+		if err != nil {
+			return fmt.Errorf("failed to encode webhook payload: %w", err)
+		}
+
+		// The event may be published more than once, the conflict clause keeps a single delivery per endpoint
+		_, err = db.ExecContext(ctx, `
+			INSERT INTO webhook_deliveries (endpoint_id, event_id, event, payload)
+			SELECT id, $2, $3, $4 FROM webhook_endpoints
+			WHERE user_id = $1 AND active AND $3 = ANY(events)
+			ON CONFLICT (endpoint_id, event_id) DO NOTHING
+		`, *event.UserID, event.ID, event.EventType, string(payload))
+		if err != nil {
+			return fmt.Errorf("failed to enqueue webhook %s: %w", event.EventType, err)
+		}
+		return nil
+	}
+}
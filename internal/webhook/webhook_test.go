@@ -0,0 +1,77 @@
+package webhook
+
+import (
+	"strings"
+	"testing"
+)
+
+const testPayload = `{"id":42,"event":"order.created","created_at":"2024-03-01T12:00:00Z","data":{"consignment_id":"DA240301ABC"}}`
+
+func TestSign(t *testing.T) {
+	tests := []struct {
+		name    string
+		secret  string
+		payload string
+		want    string
+	}{
+		{
+			name:    "RFC 4231 test case 2",
+			secret:  "Jefe",
+			payload: "what do ya want for nothing?",
+			want:    "sha256=5bdcc146bf60754e6a042426089575c75a003f089d2739839dec58b964ec3843",
+		},
+		{
+			name:    "webhook payload",
+			secret:  "whsec_test",
+			payload: testPayload,
+			want:    "sha256=a23e90ad45519827b4b9b758281184d104b20f96bf6732375a23388f920d0c3e",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Sign(tt.secret, []byte(tt.payload)); got != tt.want {
+				t.Errorf("Sign() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	signature := "sha256=a23e90ad45519827b4b9b758281184d104b20f96bf6732375a23388f920d0c3e"
+	tests := []struct {
+		name      string
+		secret    string
+		payload   string
+		signature string
+		want      bool
+	}{
+		{"valid", "whsec_test", testPayload, signature, true},
+		{"wrong secret", "whsec_other", testPayload, signature, false},
+		{"tampered payload", "whsec_test", strings.Replace(testPayload, "42", "43", 1), signature, false},
+		{"missing algorithm prefix", "whsec_test", testPayload, strings.TrimPrefix(signature, "sha256="), false},
+		{"empty signature", "whsec_test", testPayload, "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Verify(tt.secret, []byte(tt.payload), tt.signature); got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	first, err := GenerateSecret()
+	if err != nil {
+		t.Fatalf("GenerateSecret: %v", err)
+	}
+	second, _ := GenerateSecret()
+	if !strings.HasPrefix(first, "whsec_") || len(first) != len("whsec_")+64 {
+		t.Errorf("GenerateSecret() = %q, want whsec_ and 64 hex characters", first)
+	}
+	if first == second {
+		t.Errorf("GenerateSecret returned the same secret twice")
+	}
+}
@@ -0,0 +1,15 @@
+-- Every order status change is recorded so exports and reports can show when each status was reached
+CREATE TABLE IF NOT EXISTS order_status_history (
+       id SERIAL PRIMARY KEY,
+       consignment_id VARCHAR(255) NOT NULL REFERENCES orders(consignment_id) ON DELETE CASCADE,
+       status VARCHAR(50) NOT NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_status_history_consignment_id ON order_status_history (consignment_id, status);
+
+-- Backfill the initial status of orders created before the history table existed
+INSERT INTO order_status_history (consignment_id, status, created_at)
+SELECT o.consignment_id, 'pending', o.created_at
+FROM orders o
+WHERE NOT EXISTS (SELECT 1 FROM order_status_history h WHERE h.consignment_id = o.consignment_id);
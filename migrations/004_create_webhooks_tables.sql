@@ -0,0 +1,47 @@
+-- Merchant webhook endpoints and their subscribed events
+CREATE TABLE IF NOT EXISTS webhook_endpoints (
+       id SERIAL PRIMARY KEY,
+       user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+       url VARCHAR(2048) NOT NULL,
+       secret VARCHAR(255) NOT NULL,
+       events TEXT[] NOT NULL,
+       active BOOLEAN NOT NULL DEFAULT TRUE,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_endpoints_user_id ON webhook_endpoints (user_id);
+
+-- One row per event per endpoint, retried with exponential backoff until it succeeds or is dead-lettered
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+       id SERIAL PRIMARY KEY,
+       endpoint_id INT NOT NULL REFERENCES webhook_endpoints(id) ON DELETE CASCADE,
+       event VARCHAR(100) NOT NULL,
+       payload JSONB NOT NULL,
+       status VARCHAR(20) NOT NULL DEFAULT 'pending',
+       attempts INT NOT NULL DEFAULT 0,
+       next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       last_response_code INT,
+       last_error TEXT,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries (status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_endpoint_id ON webhook_deliveries (endpoint_id);
+
+-- Log of every HTTP attempt made for a delivery
+CREATE TABLE IF NOT EXISTS webhook_delivery_attempts (
+       id SERIAL PRIMARY KEY,
+       delivery_id INT NOT NULL REFERENCES webhook_deliveries(id) ON DELETE CASCADE,
+       attempt INT NOT NULL,
+       response_code INT,
+       error TEXT,
+       duration_ms INT NOT NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_attempts_delivery_id ON webhook_delivery_attempts (delivery_id);
+
+-- Deliveries left queued for endpoints deleted before deletion cancelled them
+UPDATE webhook_deliveries SET status = 'cancelled', next_attempt_at = NULL, updated_at = NOW()
+WHERE status IN ('pending', 'failed') AND endpoint_id IN (SELECT id FROM webhook_endpoints WHERE NOT active);
@@ -0,0 +1,19 @@
+-- Domain events written in the same transaction as the order mutation that caused them
+CREATE TABLE IF NOT EXISTS outbox (
+       id BIGSERIAL PRIMARY KEY,
+       aggregate_id VARCHAR(255) NOT NULL,
+       event_type VARCHAR(100) NOT NULL,
+       user_id INT REFERENCES users(id) ON DELETE CASCADE,
+       payload JSONB NOT NULL,
+       attempts INT NOT NULL DEFAULT 0,
+       last_error TEXT,
+       next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       published_at TIMESTAMP,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_unpublished ON outbox (aggregate_id, id) WHERE published_at IS NULL;
+
+-- Webhook deliveries remember the outbox event they came from so a republished event is not queued twice
+ALTER TABLE webhook_deliveries ADD COLUMN IF NOT EXISTS event_id BIGINT;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_webhook_deliveries_endpoint_event ON webhook_deliveries (endpoint_id, event_id);
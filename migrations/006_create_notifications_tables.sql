@@ -0,0 +1,32 @@
+-- Per store opt-in for recipient notifications
+CREATE TABLE IF NOT EXISTS store_notification_settings (
+       user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+       store_id INT NOT NULL,
+       sms_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+       language VARCHAR(2) NOT NULL DEFAULT 'en',
+       updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       PRIMARY KEY (user_id, store_id)
+);
+
+-- Every message sent to a recipient, with its delivery status
+CREATE TABLE IF NOT EXISTS notifications (
+       id SERIAL PRIMARY KEY,
+       event_id BIGINT NOT NULL,
+       consignment_id VARCHAR(255) NOT NULL REFERENCES orders(consignment_id) ON DELETE CASCADE,
+       channel VARCHAR(20) NOT NULL,
+       recipient VARCHAR(255) NOT NULL,
+       template VARCHAR(50) NOT NULL,
+       language VARCHAR(2) NOT NULL,
+       body TEXT NOT NULL,
+       status VARCHAR(20) NOT NULL DEFAULT 'queued',
+       attempts INT NOT NULL DEFAULT 0,
+       next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       provider_message_id VARCHAR(255),
+       last_error TEXT,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       sent_at TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_notifications_event_channel ON notifications (event_id, channel);
+CREATE INDEX IF NOT EXISTS idx_notifications_consignment_id ON notifications (consignment_id);
+CREATE INDEX IF NOT EXISTS idx_notifications_due ON notifications (status, next_attempt_at);
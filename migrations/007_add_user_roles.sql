@@ -0,0 +1,2 @@
+-- Users are merchants unless given an operations role
+ALTER TABLE users ADD COLUMN IF NOT EXISTS role VARCHAR(20) NOT NULL DEFAULT 'merchant';
@@ -0,0 +1,3 @@
+-- Statuses of orders refused by the recipient and sent back to the store
+ALTER TYPE order_status_enum ADD VALUE IF NOT EXISTS 'return_initiated';
+ALTER TYPE order_status_enum ADD VALUE IF NOT EXISTS 'returned';
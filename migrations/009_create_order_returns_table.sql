@@ -0,0 +1,15 @@
+-- Returns of refused parcels, each travelling back to the store under its own consignment ID
+CREATE TABLE IF NOT EXISTS order_returns (
+       id SERIAL PRIMARY KEY,
+       consignment_id VARCHAR(255) UNIQUE NOT NULL REFERENCES orders(consignment_id) ON DELETE CASCADE,
+       return_consignment_id VARCHAR(255) UNIQUE NOT NULL,
+       reason VARCHAR(255) NOT NULL,
+       status VARCHAR(20) NOT NULL DEFAULT 'initiated',
+       return_charge FLOAT NOT NULL DEFAULT 0,
+       initiated_by INT REFERENCES users(id) ON DELETE SET NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       returned_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_returns_status ON order_returns (status);
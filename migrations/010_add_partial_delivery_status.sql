@@ -0,0 +1,2 @@
+-- Orders where the recipient accepted only some of the items
+ALTER TYPE order_status_enum ADD VALUE IF NOT EXISTS 'partially_delivered';
@@ -0,0 +1,29 @@
+-- Items packed in a parcel
+CREATE TABLE IF NOT EXISTS order_items (
+       id SERIAL PRIMARY KEY,
+       consignment_id VARCHAR(255) NOT NULL REFERENCES orders(consignment_id) ON DELETE CASCADE,
+       sku VARCHAR(100) DEFAULT '',
+       name VARCHAR(255) NOT NULL,
+       quantity INT NOT NULL,
+       unit_price FLOAT NOT NULL DEFAULT 0,
+       weight FLOAT NOT NULL DEFAULT 0,
+       returned_quantity INT NOT NULL DEFAULT 0
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_items_consignment_id ON order_items (consignment_id);
+
+-- Exchange orders point at the consignment whose item they replace,
+-- and the amount actually collected is kept when it differs from amount_to_collect
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS order_type VARCHAR(20) NOT NULL DEFAULT 'regular';
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS parent_consignment_id VARCHAR(255) REFERENCES orders(consignment_id);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS collected_amount FLOAT;
+
+-- Partial deliveries recorded against an order
+CREATE TABLE IF NOT EXISTS partial_deliveries (
+       id SERIAL PRIMARY KEY,
+       consignment_id VARCHAR(255) UNIQUE NOT NULL REFERENCES orders(consignment_id) ON DELETE CASCADE,
+       collected_amount FLOAT NOT NULL,
+       reason VARCHAR(255) NOT NULL,
+       recorded_by INT REFERENCES users(id) ON DELETE SET NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,2 @@
+-- Declared value of the goods, the sum of the order items' prices
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS declared_value FLOAT;
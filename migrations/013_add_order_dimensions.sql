@@ -0,0 +1,11 @@
+-- Parcel dimensions in cm and the weight the delivery fee was charged on
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS length FLOAT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS width FLOAT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS height FLOAT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS volumetric_weight FLOAT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS chargeable_weight FLOAT;
+
+UPDATE orders SET chargeable_weight = item_weight WHERE chargeable_weight IS NULL;
+
+ALTER TABLE orders ALTER COLUMN chargeable_weight SET DEFAULT 0;
+ALTER TABLE orders ALTER COLUMN chargeable_weight SET NOT NULL;
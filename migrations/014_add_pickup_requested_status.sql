@@ -0,0 +1,2 @@
+-- Status of orders waiting to be picked up from the store
+ALTER TYPE order_status_enum ADD VALUE IF NOT EXISTS 'pickup_requested';
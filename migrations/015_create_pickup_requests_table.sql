@@ -0,0 +1,25 @@
+-- Requests to collect parcels from a store within a time window
+CREATE TABLE IF NOT EXISTS pickup_requests (
+       id SERIAL PRIMARY KEY,
+       pickup_id VARCHAR(20) UNIQUE NOT NULL,
+       user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+       store_id INT NOT NULL,
+       zone_id INT NOT NULL,
+       pickup_date DATE NOT NULL,
+       window_start VARCHAR(5) NOT NULL,
+       window_end VARCHAR(5) NOT NULL,
+       status VARCHAR(20) NOT NULL DEFAULT 'requested',
+       note TEXT,
+       confirmed_by INT REFERENCES users(id) ON DELETE SET NULL,
+       confirmed_at TIMESTAMP,
+       reschedule_reason TEXT,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_pickup_requests_zone_date ON pickup_requests (zone_id, pickup_date);
+CREATE INDEX IF NOT EXISTS idx_pickup_requests_user_id ON pickup_requests (user_id);
+
+-- The pickup an order is collected in
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS pickup_id VARCHAR(20) REFERENCES pickup_requests(pickup_id);
+CREATE INDEX IF NOT EXISTS idx_orders_pickup_id ON orders (pickup_id);
@@ -0,0 +1,4 @@
+-- Statuses of orders on their way to the recipient
+ALTER TYPE order_status_enum ADD VALUE IF NOT EXISTS 'picked';
+ALTER TYPE order_status_enum ADD VALUE IF NOT EXISTS 'out_for_delivery';
+ALTER TYPE order_status_enum ADD VALUE IF NOT EXISTS 'delivery_failed';
@@ -0,0 +1,41 @@
+-- Riders are users with the rider role who pick up and deliver parcels
+CREATE TABLE IF NOT EXISTS riders (
+       id SERIAL PRIMARY KEY,
+       user_id INT UNIQUE NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+       name VARCHAR(255) NOT NULL,
+       phone VARCHAR(20) NOT NULL,
+       home_hub VARCHAR(50) NOT NULL,
+       active BOOLEAN NOT NULL DEFAULT TRUE,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_riders_home_hub ON riders (home_hub);
+
+-- The rider currently responsible for an order
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS rider_id INT REFERENCES riders(id);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS assigned_at TIMESTAMP;
+CREATE INDEX IF NOT EXISTS idx_orders_rider_id ON orders (rider_id);
+
+-- Every assignment and reassignment of an order
+CREATE TABLE IF NOT EXISTS rider_assignments (
+       id SERIAL PRIMARY KEY,
+       consignment_id VARCHAR(255) NOT NULL REFERENCES orders(consignment_id) ON DELETE CASCADE,
+       rider_id INT NOT NULL REFERENCES riders(id),
+       previous_rider_id INT REFERENCES riders(id),
+       assigned_by INT REFERENCES users(id) ON DELETE SET NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_rider_assignments_consignment_id ON rider_assignments (consignment_id);
+
+-- Failed delivery attempts reported by riders
+CREATE TABLE IF NOT EXISTS delivery_attempts (
+       id SERIAL PRIMARY KEY,
+       consignment_id VARCHAR(255) NOT NULL REFERENCES orders(consignment_id) ON DELETE CASCADE,
+       rider_id INT NOT NULL REFERENCES riders(id),
+       reason VARCHAR(255) NOT NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_delivery_attempts_consignment_id ON delivery_attempts (consignment_id);
@@ -0,0 +1,63 @@
+-- Sorting centers and warehouses parcels pass through, each serving a zone
+CREATE TABLE IF NOT EXISTS hubs (
+       id SERIAL PRIMARY KEY,
+       code VARCHAR(50) UNIQUE NOT NULL,
+       name VARCHAR(255) NOT NULL,
+       hub_type VARCHAR(20) NOT NULL DEFAULT 'sorting_center',
+       zone_id INT NOT NULL,
+       address TEXT,
+       active BOOLEAN NOT NULL DEFAULT TRUE,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_hubs_zone_id ON hubs (zone_id);
+
+-- The hub currently holding a parcel. transfer_status is 1 at merchant, 2 at hub, 3 in transit between hubs,
+-- 4 out with a rider and 5 delivered.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS current_hub_id INT REFERENCES hubs(id);
+CREATE INDEX IF NOT EXISTS idx_orders_current_hub_id ON orders (current_hub_id);
+CREATE INDEX IF NOT EXISTS idx_orders_transfer_status ON orders (transfer_status);
+
+-- Parcels that left their last hub before order status changes released them from it
+UPDATE orders SET current_hub_id = NULL,
+       transfer_status = CASE order_status WHEN 'out_for_delivery' THEN 4 WHEN 'completed' THEN 5 WHEN 'partially_delivered' THEN 5 ELSE 1 END
+WHERE current_hub_id IS NOT NULL AND order_status IN ('out_for_delivery', 'completed', 'partially_delivered', 'returned');
+
+-- Manifests of parcels moved between hubs
+CREATE TABLE IF NOT EXISTS transfer_manifests (
+       id SERIAL PRIMARY KEY,
+       origin_hub_id INT NOT NULL REFERENCES hubs(id),
+       destination_hub_id INT NOT NULL REFERENCES hubs(id),
+       status VARCHAR(20) NOT NULL DEFAULT 'created',
+       created_by INT REFERENCES users(id) ON DELETE SET NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       dispatched_at TIMESTAMP,
+       received_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfer_manifests_origin ON transfer_manifests (origin_hub_id, status);
+CREATE INDEX IF NOT EXISTS idx_transfer_manifests_destination ON transfer_manifests (destination_hub_id, status);
+
+CREATE TABLE IF NOT EXISTS transfer_manifest_items (
+       id SERIAL PRIMARY KEY,
+       manifest_id INT NOT NULL REFERENCES transfer_manifests(id) ON DELETE CASCADE,
+       consignment_id VARCHAR(255) NOT NULL REFERENCES orders(consignment_id) ON DELETE CASCADE,
+       dispatched_at TIMESTAMP,
+       received_at TIMESTAMP,
+       UNIQUE (manifest_id, consignment_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_transfer_manifest_items_consignment_id ON transfer_manifest_items (consignment_id);
+
+-- Every scan of a parcel at a hub
+CREATE TABLE IF NOT EXISTS hub_scans (
+       id SERIAL PRIMARY KEY,
+       consignment_id VARCHAR(255) NOT NULL REFERENCES orders(consignment_id) ON DELETE CASCADE,
+       hub_id INT NOT NULL REFERENCES hubs(id),
+       scan_type VARCHAR(20) NOT NULL,
+       manifest_id INT REFERENCES transfer_manifests(id),
+       scanned_by INT REFERENCES users(id) ON DELETE SET NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_hub_scans_consignment_id ON hub_scans (consignment_id);
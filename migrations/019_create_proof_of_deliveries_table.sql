@@ -0,0 +1,14 @@
+-- Evidence captured by the rider when a parcel is handed over
+CREATE TABLE IF NOT EXISTS proof_of_deliveries (
+       id SERIAL PRIMARY KEY,
+       consignment_id VARCHAR(255) UNIQUE NOT NULL REFERENCES orders(consignment_id) ON DELETE CASCADE,
+       rider_id INT NOT NULL REFERENCES riders(id),
+       photo_key VARCHAR(255),
+       signature_key VARCHAR(255),
+       latitude DOUBLE PRECISION NOT NULL,
+       longitude DOUBLE PRECISION NOT NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Delivery codes are only kept hashed in delivery_otps
+ALTER TABLE proof_of_deliveries DROP COLUMN IF EXISTS otp;
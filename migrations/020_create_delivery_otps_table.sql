@@ -0,0 +1,30 @@
+-- Per store opt-in for delivery OTP on every parcel, regardless of its value
+CREATE TABLE IF NOT EXISTS store_delivery_settings (
+       user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+       store_id INT NOT NULL,
+       otp_enabled BOOLEAN NOT NULL DEFAULT FALSE,
+       updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       PRIMARY KEY (user_id, store_id)
+);
+
+-- One-time codes the recipient gives the rider on delivery. Only a hash of the code is kept.
+CREATE TABLE IF NOT EXISTS delivery_otps (
+       consignment_id VARCHAR(255) PRIMARY KEY REFERENCES orders(consignment_id) ON DELETE CASCADE,
+       code_hash VARCHAR(255) NOT NULL,
+       failed_attempts INT NOT NULL DEFAULT 0,
+       locked_until TIMESTAMP,
+       verified_at TIMESTAMP,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Every code entered by a rider
+CREATE TABLE IF NOT EXISTS delivery_otp_attempts (
+       id SERIAL PRIMARY KEY,
+       consignment_id VARCHAR(255) NOT NULL REFERENCES orders(consignment_id) ON DELETE CASCADE,
+       rider_id INT REFERENCES riders(id),
+       success BOOLEAN NOT NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_delivery_otp_attempts_consignment_id ON delivery_otp_attempts (consignment_id);
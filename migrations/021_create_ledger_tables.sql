@@ -0,0 +1,39 @@
+-- Batches of settled ledger transactions paid out to a merchant
+CREATE TABLE IF NOT EXISTS payouts (
+       id SERIAL PRIMARY KEY,
+       user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+       amount NUMERIC(12, 2) NOT NULL,
+       status VARCHAR(20) NOT NULL DEFAULT 'pending',
+       period_end TIMESTAMP NOT NULL,
+       created_by INT REFERENCES users(id) ON DELETE SET NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       paid_at TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_payouts_user_id ON payouts (user_id, id);
+
+-- Balanced groups of ledger entries, each recording one money movement for a merchant
+CREATE TABLE IF NOT EXISTS ledger_transactions (
+       id BIGSERIAL PRIMARY KEY,
+       entry_type VARCHAR(30) NOT NULL,
+       user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+       consignment_id VARCHAR(255) REFERENCES orders(consignment_id) ON DELETE CASCADE,
+       payout_id INT REFERENCES payouts(id),
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- An order is charged each fee at most once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_ledger_transactions_consignment_entry ON ledger_transactions (consignment_id, entry_type) WHERE consignment_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_ledger_transactions_unbatched ON ledger_transactions (user_id, created_at) WHERE payout_id IS NULL;
+CREATE INDEX IF NOT EXISTS idx_ledger_transactions_payout_id ON ledger_transactions (payout_id);
+
+-- Double-entry legs; the debits and credits of a transaction always add up to the same amount
+CREATE TABLE IF NOT EXISTS ledger_entries (
+       id BIGSERIAL PRIMARY KEY,
+       transaction_id BIGINT NOT NULL REFERENCES ledger_transactions(id) ON DELETE CASCADE,
+       account VARCHAR(30) NOT NULL,
+       debit NUMERIC(12, 2) NOT NULL DEFAULT 0 CHECK (debit >= 0),
+       credit NUMERIC(12, 2) NOT NULL DEFAULT 0 CHECK (credit >= 0)
+);
+
+CREATE INDEX IF NOT EXISTS idx_ledger_entries_transaction_id ON ledger_entries (transaction_id);
@@ -0,0 +1,14 @@
+-- Cash handed over by riders to hub cashiers, reconciled against the COD they collected that day
+CREATE TABLE IF NOT EXISTS rider_cash_deposits (
+       id SERIAL PRIMARY KEY,
+       rider_id INT NOT NULL REFERENCES riders(id),
+       hub_id INT NOT NULL REFERENCES hubs(id),
+       business_date DATE NOT NULL,
+       amount NUMERIC(12, 2) NOT NULL CHECK (amount > 0),
+       note VARCHAR(255),
+       recorded_by INT REFERENCES users(id) ON DELETE SET NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_rider_cash_deposits_hub_date ON rider_cash_deposits (hub_id, business_date);
+CREATE INDEX IF NOT EXISTS idx_rider_cash_deposits_rider_date ON rider_cash_deposits (rider_id, business_date);
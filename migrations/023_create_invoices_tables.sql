@@ -0,0 +1,60 @@
+-- Last number issued per document prefix and year, so invoice numbers run without gaps
+CREATE TABLE IF NOT EXISTS invoice_sequences (
+       prefix VARCHAR(10) NOT NULL,
+       year INT NOT NULL,
+       last_number INT NOT NULL,
+       PRIMARY KEY (prefix, year)
+);
+
+-- Invoices for the fees settled in a payout, and credit notes correcting them
+CREATE TABLE IF NOT EXISTS invoices (
+       id SERIAL PRIMARY KEY,
+       number VARCHAR(30) UNIQUE NOT NULL,
+       kind VARCHAR(20) NOT NULL,
+       user_id INT NOT NULL REFERENCES users(id),
+       payout_id INT REFERENCES payouts(id),
+       original_invoice_id INT REFERENCES invoices(id),
+       period_start TIMESTAMP NOT NULL,
+       period_end TIMESTAMP NOT NULL,
+       vat_percent NUMERIC(5, 2) NOT NULL,
+       subtotal NUMERIC(12, 2) NOT NULL,
+       vat NUMERIC(12, 2) NOT NULL,
+       total NUMERIC(12, 2) NOT NULL,
+       reason VARCHAR(255),
+       issued_by INT REFERENCES users(id),
+       issued_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- A payout is invoiced once
+CREATE UNIQUE INDEX IF NOT EXISTS idx_invoices_payout_id ON invoices (payout_id) WHERE kind = 'invoice';
+CREATE INDEX IF NOT EXISTS idx_invoices_user_id ON invoices (user_id, id);
+CREATE INDEX IF NOT EXISTS idx_invoices_original_invoice_id ON invoices (original_invoice_id);
+
+CREATE TABLE IF NOT EXISTS invoice_lines (
+       id SERIAL PRIMARY KEY,
+       invoice_id INT NOT NULL REFERENCES invoices(id),
+       consignment_id VARCHAR(255) NOT NULL,
+       delivery_fee NUMERIC(12, 2) NOT NULL,
+       cod_fee NUMERIC(12, 2) NOT NULL,
+       return_charge NUMERIC(12, 2) NOT NULL,
+       net NUMERIC(12, 2) NOT NULL,
+       vat NUMERIC(12, 2) NOT NULL,
+       total NUMERIC(12, 2) NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_invoice_lines_invoice_id ON invoice_lines (invoice_id);
+
+-- Issued documents are immutable; mistakes are corrected with a credit note
+CREATE OR REPLACE FUNCTION reject_invoice_change() RETURNS trigger AS $$
+BEGIN
+       RAISE EXCEPTION 'issued invoices cannot be changed, issue a credit note instead';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS invoices_immutable ON invoices;
+CREATE TRIGGER invoices_immutable BEFORE UPDATE OR DELETE ON invoices
+       FOR EACH ROW EXECUTE FUNCTION reject_invoice_change();
+
+DROP TRIGGER IF EXISTS invoice_lines_immutable ON invoice_lines;
+CREATE TRIGGER invoice_lines_immutable BEFORE UPDATE OR DELETE ON invoice_lines
+       FOR EACH ROW EXECUTE FUNCTION reject_invoice_change();
@@ -0,0 +1,39 @@
+-- Where each merchant's payouts are sent; the account number is encrypted by the application
+CREATE TABLE IF NOT EXISTS payout_methods (
+       user_id INT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+       method VARCHAR(20) NOT NULL,
+       account_name VARCHAR(255) NOT NULL,
+       account_number_encrypted TEXT NOT NULL,
+       account_number_last4 VARCHAR(4) NOT NULL,
+       bank_name VARCHAR(255),
+       branch_name VARCHAR(255),
+       routing_number VARCHAR(20),
+       wallet_provider VARCHAR(50),
+       updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Payouts are sent through a provider and retried until they succeed or run out of attempts
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS method VARCHAR(20);
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS provider_reference VARCHAR(255);
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS attempts INT NOT NULL DEFAULT 0;
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS last_error TEXT;
+ALTER TABLE payouts ADD COLUMN IF NOT EXISTS updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP;
+
+-- Payouts marked paid by hand before providers existed
+UPDATE payouts SET status = 'succeeded' WHERE status = 'paid';
+
+CREATE INDEX IF NOT EXISTS idx_payouts_due ON payouts (next_attempt_at) WHERE status = 'pending';
+
+-- Every attempt to send a payout and every status update received from the provider
+CREATE TABLE IF NOT EXISTS payout_attempts (
+       id SERIAL PRIMARY KEY,
+       payout_id INT NOT NULL REFERENCES payouts(id) ON DELETE CASCADE,
+       attempt INT NOT NULL,
+       status VARCHAR(20) NOT NULL,
+       provider_reference VARCHAR(255),
+       error TEXT,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_payout_attempts_payout_id ON payout_attempts (payout_id);
@@ -0,0 +1,39 @@
+-- Discounts on the delivery fee. A promotion without a code applies automatically to every eligible order,
+-- one with a user_id only to that merchant. Empty zones means every zone.
+CREATE TABLE IF NOT EXISTS promotions (
+       id SERIAL PRIMARY KEY,
+       code VARCHAR(50) UNIQUE,
+       name VARCHAR(255) NOT NULL,
+       user_id INT REFERENCES users(id) ON DELETE CASCADE,
+       discount_type VARCHAR(20) NOT NULL,
+       discount_value FLOAT NOT NULL,
+       zones INT[] NOT NULL DEFAULT '{}',
+       starts_at TIMESTAMP,
+       ends_at TIMESTAMP,
+       usage_limit INT,
+       per_merchant_limit INT,
+       times_used INT NOT NULL DEFAULT 0,
+       active BOOLEAN NOT NULL DEFAULT TRUE,
+       created_by INT REFERENCES users(id) ON DELETE SET NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_promotions_user_id ON promotions (user_id);
+
+-- One row per order a promotion was applied to, counting towards its usage caps
+CREATE TABLE IF NOT EXISTS promotion_redemptions (
+       id SERIAL PRIMARY KEY,
+       promotion_id INT NOT NULL REFERENCES promotions(id) ON DELETE CASCADE,
+       user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+       consignment_id VARCHAR(255) UNIQUE NOT NULL REFERENCES orders(consignment_id) ON DELETE CASCADE,
+       discount FLOAT NOT NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_promotion_redemptions_promotion_user ON promotion_redemptions (promotion_id, user_id);
+
+-- The promotion an order's delivery fee was discounted by; delivery_fee is what is charged after the discount
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS promotion_id INT REFERENCES promotions(id) ON DELETE SET NULL;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS promo_code VARCHAR(50);
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS delivery_fee_discount FLOAT NOT NULL DEFAULT 0;
@@ -0,0 +1,24 @@
+-- Fuel and peak surcharges on the delivery fee, applied to orders created while they are in effect
+CREATE TABLE IF NOT EXISTS surcharges (
+       id SERIAL PRIMARY KEY,
+       name VARCHAR(255) NOT NULL,
+       kind VARCHAR(20) NOT NULL,
+       calculation VARCHAR(20) NOT NULL,
+       value FLOAT NOT NULL,
+       effective_from TIMESTAMP NOT NULL,
+       effective_to TIMESTAMP,
+       created_by INT REFERENCES users(id) ON DELETE SET NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_surcharges_effective ON surcharges (effective_from, effective_to);
+
+-- What an order was charged, fee_breakdown holds every component of the fee as quoted at creation.
+-- vat is the VAT included in delivery_fee, surcharge and cod_fee.
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS surcharge FLOAT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS vat FLOAT NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS fee_breakdown JSONB;
+
+-- Surcharges are invoiced on their own column; adding a column does not touch issued rows
+ALTER TABLE invoice_lines ADD COLUMN IF NOT EXISTS surcharge NUMERIC(12, 2) NOT NULL DEFAULT 0;
@@ -0,0 +1,40 @@
+-- Merchant claims for lost or damaged parcels. A consignment has at most one claim that was not rejected.
+CREATE TABLE IF NOT EXISTS claims (
+       id SERIAL PRIMARY KEY,
+       consignment_id VARCHAR(255) NOT NULL REFERENCES orders(consignment_id) ON DELETE CASCADE,
+       user_id INT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+       claim_type VARCHAR(20) NOT NULL,
+       description TEXT NOT NULL,
+       status VARCHAR(20) NOT NULL DEFAULT 'open',
+       requested_amount FLOAT,
+       compensation_amount FLOAT,
+       decided_by INT REFERENCES users(id) ON DELETE SET NULL,
+       decided_at TIMESTAMP,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_claims_consignment_id ON claims (consignment_id) WHERE status <> 'rejected';
+CREATE INDEX IF NOT EXISTS idx_claims_user_id ON claims (user_id, id);
+CREATE INDEX IF NOT EXISTS idx_claims_status ON claims (status);
+
+-- Photos the merchant submitted with a claim, kept in blob storage
+CREATE TABLE IF NOT EXISTS claim_evidence (
+       id SERIAL PRIMARY KEY,
+       claim_id INT NOT NULL REFERENCES claims(id) ON DELETE CASCADE,
+       file_key VARCHAR(255) NOT NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_claim_evidence_claim_id ON claim_evidence (claim_id);
+
+-- Notes ops leave while investigating a claim, visible to the merchant
+CREATE TABLE IF NOT EXISTS claim_comments (
+       id SERIAL PRIMARY KEY,
+       claim_id INT NOT NULL REFERENCES claims(id) ON DELETE CASCADE,
+       user_id INT REFERENCES users(id) ON DELETE SET NULL,
+       body TEXT NOT NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_claim_comments_claim_id ON claim_comments (claim_id);
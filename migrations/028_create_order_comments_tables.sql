@@ -0,0 +1,24 @@
+-- Notes between merchants and ops about an order. Internal comments are only shown to ops.
+CREATE TABLE IF NOT EXISTS order_comments (
+       id SERIAL PRIMARY KEY,
+       consignment_id VARCHAR(255) NOT NULL REFERENCES orders(consignment_id) ON DELETE CASCADE,
+       user_id INT REFERENCES users(id) ON DELETE SET NULL,
+       author_role VARCHAR(20) NOT NULL,
+       visibility VARCHAR(20) NOT NULL DEFAULT 'merchant',
+       body TEXT NOT NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_comments_consignment_id ON order_comments (consignment_id, id);
+
+-- Files attached to a comment, kept in blob storage
+CREATE TABLE IF NOT EXISTS order_comment_attachments (
+       id SERIAL PRIMARY KEY,
+       comment_id INT NOT NULL REFERENCES order_comments(id) ON DELETE CASCADE,
+       file_key VARCHAR(255) NOT NULL,
+       file_name VARCHAR(255) NOT NULL,
+       size BIGINT NOT NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_comment_attachments_comment_id ON order_comment_attachments (comment_id);
@@ -0,0 +1,29 @@
+-- Reasons an order may be cancelled for, managed by ops. Inactive reasons are kept for past cancellations.
+CREATE TABLE IF NOT EXISTS cancellation_reasons (
+       id SERIAL PRIMARY KEY,
+       code VARCHAR(50) UNIQUE NOT NULL,
+       label VARCHAR(255) NOT NULL,
+       active BOOLEAN NOT NULL DEFAULT TRUE,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+       updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+INSERT INTO cancellation_reasons (code, label) VALUES
+       ('customer_request', 'Customer asked to cancel'),
+       ('duplicate_order', 'Duplicate order'),
+       ('out_of_stock', 'Item out of stock'),
+       ('wrong_details', 'Wrong recipient or parcel details'),
+       ('other', 'Other')
+ON CONFLICT (code) DO NOTHING;
+
+-- Why and by whom an order was cancelled
+CREATE TABLE IF NOT EXISTS order_cancellations (
+       id SERIAL PRIMARY KEY,
+       consignment_id VARCHAR(255) UNIQUE NOT NULL REFERENCES orders(consignment_id) ON DELETE CASCADE,
+       reason_code VARCHAR(50) NOT NULL REFERENCES cancellation_reasons(code),
+       note TEXT,
+       cancelled_by INT REFERENCES users(id) ON DELETE SET NULL,
+       created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_order_cancellations_reason_code ON order_cancellations (reason_code);
@@ -45,40 +45,41 @@ func InitDB() error {
 		log.Fatalf("Failed to apply migrations: %v", err)
 	}
 
-	// Call the seed function to ensure the default user is created
-	seedDefaultUser()
+	// Call the seed function to ensure the default users are created
+	seedUser("01901901901@mailinator.com", "321dsa", "merchant")
+	// The ops account is privileged, so it is only seeded in development when its credentials are configured
+	if seed := configs.GetSeedOpsUser(); seed.Email != "" && seed.Password != "" {
+		seedUser(seed.Email, seed.Password, "ops")
+	}
 	return nil
 }
 
-// seedDefaultUser ensures the default user is present in the database
-func seedDefaultUser() {
-	email := "01901901901@mailinator.com"
-	password := "321dsa"
-
+// seedUser ensures a default user with the given role is present in the database
+func seedUser(email, password, role string) {
 	// checking if the user already exists
 	var exists bool
 	query := "SELECT EXISTS(SELECT 1 FROM users WHERE email=$1)"
 	err := WriteDB.QueryRow(query, email).Scan(&exists)
 	if err != nil {
-		log.Fatalf("Failed to check if default user exists: %v", err)
+		log.Fatalf("Failed to check if default %s user exists: %v", role, err)
 	}
 
 	if !exists {
 		// Hash
 		hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 		if err != nil {
-			log.Fatalf("Failed to hash default user password: %v", err)
+			log.Fatalf("Failed to hash default %s user password: %v", role, err)
 		}
 
 		// Insert the default user
-		insertQuery := "INSERT INTO users (email, password) VALUES ($1, $2)"
-		_, err = WriteDB.Exec(insertQuery, email, string(hashedPassword))
+		insertQuery := "INSERT INTO users (email, password, role) VALUES ($1, $2, $3)"
+		_, err = WriteDB.Exec(insertQuery, email, string(hashedPassword), role)
 		if err != nil {
-			log.Fatalf("Failed to create default user: %v", err)
+			log.Fatalf("Failed to create default %s user: %v", role, err)
 		}
 
-		log.Println("Default user created successfully")
+		log.Printf("Default %s user created successfully", role)
 	} else {
-		log.Println("Default user already exists")
+		log.Printf("Default %s user already exists", role)
 	}
 }
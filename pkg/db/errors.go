@@ -0,0 +1,20 @@
+package db
+
+import (
+	"errors"
+
+	"github.com/lib/pq"
+)
+
+// uniqueViolation is the PostgreSQL error code raised when a unique constraint is violated
+const uniqueViolation = "23505"
+
+// IsUniqueViolation reports whether err was caused by a unique constraint violation.
+// When constraint is non-empty only a violation of that constraint matches.
+func IsUniqueViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) || pqErr.Code != uniqueViolation {
+		return false
+	}
+	return constraint == "" || pqErr.Constraint == constraint
+}
@@ -3,23 +3,18 @@ package db
 import (
 	"fmt"
 	"github.com/jmoiron/sqlx"
-	"io"
 	"log"
 	"os"
 	"path/filepath"
 )
 
 // ApplyMigrations applies all SQL migrations from the migrations folder to the provided DB connection.
+// Migrations are applied in file name order, so later migrations can rely on earlier ones.
 func ApplyMigrations(dbConn *sqlx.DB) error {
 	migrationsDir := "./migrations"
-	dir, err := os.Open(migrationsDir)
-	if err != nil {
-		return fmt.Errorf("failed to open migrations directory: %w", err)
-	}
-	defer dir.Close()
 
-	// Read all entries in the migrations directory
-	files, err := dir.Readdir(0)
+	// Read all entries in the migrations directory, sorted by file name
+	files, err := os.ReadDir(migrationsDir)
 	if err != nil {
 		return fmt.Errorf("failed to read migrations directory: %w", err)
 	}
@@ -30,14 +25,8 @@ func ApplyMigrations(dbConn *sqlx.DB) error {
 			continue
 		}
 
-		migrationFile := fmt.Sprintf("%s/%s", migrationsDir, file.Name())
-		f, err := os.Open(migrationFile)
-		if err != nil {
-			return fmt.Errorf("failed to open migration file %s: %w", migrationFile, err)
-		}
-		defer f.Close()
-
-		migrationSQL, err := io.ReadAll(f)
+		migrationFile := filepath.Join(migrationsDir, file.Name())
+		migrationSQL, err := os.ReadFile(migrationFile)
 		if err != nil {
 			return fmt.Errorf("failed to read migration file %s: %w", migrationFile, err)
 		}
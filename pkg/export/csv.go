@@ -0,0 +1,44 @@
+package export
+
+import (
+	"encoding/csv"
+	"io"
+)
+
+// csvFlushEvery is how many rows are buffered before they are flushed to the client
+const csvFlushEvery = 500
+
+type csvWriter struct {
+	w    *csv.Writer
+	rows int
+}
+
+func newCSVWriter(w io.Writer) Writer {
+	return &csvWriter{w: csv.NewWriter(w)}
+}
+
+func (c *csvWriter) WriteHeader(columns []string) error {
+	return c.w.Write(columns)
+}
+
+func (c *csvWriter) WriteRow(values []interface{}) error {
+	record := make([]string, len(values))
+	for i, v := range values {
+		record[i] = formatValue(v)
+	}
+	if err := c.w.Write(record); err != nil {
+		return err
+	}
+
+	c.rows++
+	if c.rows%csvFlushEvery == 0 {
+		c.w.Flush()
+		return c.w.Error()
+	}
+	return nil
+}
+
+func (c *csvWriter) Close() error {
+	c.w.Flush()
+	return c.w.Error()
+}
@@ -0,0 +1,98 @@
+package export
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+)
+
+// Writer streams tabular data row by row to an underlying io.Writer
+type Writer interface {
+	// WriteHeader writes the column names, it must be called once before any row
+	WriteHeader(columns []string) error
+	// WriteRow writes a single row, values are formatted according to their type
+	WriteRow(values []interface{}) error
+	// Close flushes any buffered data and finishes the document
+	Close() error
+}
+
+// Format describes an export file format
+type Format struct {
+	Name        string
+	ContentType string
+	Extension   string
+	newWriter   func(w io.Writer) Writer
+}
+
+// NewWriter returns a Writer of this format writing to w
+func (f Format) NewWriter(w io.Writer) Writer {
+	return f.newWriter(w)
+}
+
+// Supported export formats
+var (
+	FormatCSV = Format{
+		Name:        "csv",
+		ContentType: "text/csv",
+		Extension:   "csv",
+		newWriter:   newCSVWriter,
+	}
+	FormatXLSX = Format{
+		Name:        "xlsx",
+		ContentType: "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet",
+		Extension:   "xlsx",
+		newWriter:   newXLSXWriter,
+	}
+)
+
+// GetFormat returns the format with the given name, defaulting to CSV when name is empty
+func GetFormat(name string) (Format, error) {
+	switch name {
+	case "", FormatCSV.Name:
+		return FormatCSV, nil
+	case FormatXLSX.Name:
+		return FormatXLSX, nil
+	default:
+		return Format{}, fmt.Errorf("unknown export format %q", name)
+	}
+}
+
+// formatValue converts a cell value to its text representation
+func formatValue(v interface{}) string {
+	switch val := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return val
+	case *string:
+		if val == nil {
+			return ""
+		}
+		return *val
+	case time.Time:
+		if val.IsZero() {
+			return ""
+		}
+		return val.Format(time.RFC3339)
+	case *time.Time:
+		if val == nil || val.IsZero() {
+			return ""
+		}
+		return val.Format(time.RFC3339)
+	case *int:
+		if val == nil {
+			return ""
+		}
+		return strconv.Itoa(*val)
+	case float64:
+		return fmt.Sprintf("%.2f", val)
+	case *float64:
+		if val == nil {
+			return ""
+		}
+		return fmt.Sprintf("%.2f", *val)
+	default:
+		return fmt.Sprint(val)
+	}
+}
@@ -0,0 +1,188 @@
+package invoice
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/go-pdf/fpdf"
+)
+
+// Page layout in mm
+const (
+	pageMargin = 15.0
+	fontSize   = 9.0
+)
+
+// Party is the issuer or customer block printed on the document
+type Party struct {
+	Name            string
+	Address         string
+	VATRegistration string
+}
+
+// Line is what a single consignment was charged
+type Line struct {
+	ConsignmentID string
+	DeliveryFee   float64
+	Surcharge     float64
+	CODFee        float64
+	ReturnCharge  float64
+	Net           float64
+	VAT           float64
+	Total         float64
+}
+
+// Document holds everything printed on an invoice or credit note
+type Document struct {
+	Title       string // "INVOICE" or "CREDIT NOTE"
+	Number      string
+	Reference   string // the invoice a credit note corrects
+	Reason      string
+	IssuedAt    time.Time
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	Issuer      Party
+	Customer    Party
+	VATPercent  float64
+	Lines       []Line
+	Subtotal    float64
+	VAT         float64
+	Total       float64
+}
+
+// columns of the line table with their widths in mm, adding up to the A4 content width
+var columns = []struct {
+	title string
+	width float64
+}{
+	{"Consignment", 40}, {"Delivery fee", 21}, {"Surcharge", 19}, {"COD fee", 17}, {"Return charge", 23}, {"Net", 20}, {"VAT", 18}, {"Total", 22},
+}
+
+// Render writes the document as an A4 PDF to w
+func Render(w io.Writer, doc Document) error {
+	pdf := fpdf.New("P", "mm", "A4", "")
+	pdf.SetMargins(pageMargin, pageMargin, pageMargin)
+	pdf.SetAutoPageBreak(true, pageMargin)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+	pdf.SetFooterFunc(func() {
+		pdf.SetY(-pageMargin + 5)
+		pdf.SetFont("Helvetica", "", fontSize*0.8)
+		pdf.CellFormat(0, 4, tr(fmt.Sprintf("%s %s - page %d", doc.Title, doc.Number, pdf.PageNo())), "", 0, "C", false, 0, "")
+	})
+	pdf.AddPage()
+	contentWidth, _ := pdf.GetPageSize()
+	contentWidth -= 2 * pageMargin
+
+	// Issuer on the left, document title and number on the right
+	y := pdf.GetY()
+	partyBlock(pdf, tr, pageMargin, y, contentWidth/2, "", doc.Issuer)
+	pdf.SetXY(pageMargin+contentWidth/2, y)
+	pdf.SetFont("Helvetica", "B", fontSize*2)
+	pdf.CellFormat(contentWidth/2, 10, tr(doc.Title), "", 2, "R", false, 0, "")
+	pdf.SetFont("Helvetica", "", fontSize)
+	details := []string{
+		"No. " + doc.Number,
+		"Issued " + doc.IssuedAt.Format("02 Jan 2006"),
+		"Period " + doc.PeriodStart.Format("02 Jan 2006") + " - " + doc.PeriodEnd.Format("02 Jan 2006"),
+	}
+	if doc.Reference != "" {
+		details = append(details, "Corrects "+doc.Reference)
+	}
+	for _, detail := range details {
+		pdf.CellFormat(contentWidth/2, 5, tr(detail), "", 2, "R", false, 0, "")
+	}
+
+	y = pdf.GetY() + 6
+	y = partyBlock(pdf, tr, pageMargin, y, contentWidth, "BILL TO", doc.Customer)
+	if doc.Reason != "" {
+		pdf.SetXY(pageMargin, y)
+		pdf.MultiCell(contentWidth, 5, tr("Reason: "+doc.Reason), "", "L", false)
+		y = pdf.GetY() + 2
+	}
+
+	// Line table, repeating the header on every page
+	pdf.SetXY(pageMargin, y+2)
+	header := func() {
+		pdf.SetFont("Helvetica", "B", fontSize)
+		pdf.SetFillColor(230, 230, 230)
+		for i, column := range columns {
+			align := "R"
+			if i == 0 {
+				align = "L"
+			}
+			pdf.CellFormat(column.width, 7, column.title, "1", 0, align, true, 0, "")
+		}
+		pdf.Ln(-1)
+		pdf.SetFont("Helvetica", "", fontSize)
+	}
+	header()
+	_, pageHeight := pdf.GetPageSize()
+	for _, line := range doc.Lines {
+		if pdf.GetY()+6 > pageHeight-pageMargin {
+			pdf.AddPage()
+			header()
+		}
+		cells := []string{
+			line.ConsignmentID,
+			amount(line.DeliveryFee), amount(line.Surcharge), amount(line.CODFee), amount(line.ReturnCharge),
+			amount(line.Net), amount(line.VAT), amount(line.Total),
+		}
+		for i, cell := range cells {
+			align := "R"
+			if i == 0 {
+				align = "L"
+			}
+			pdf.CellFormat(columns[i].width, 6, tr(cell), "1", 0, align, false, 0, "")
+		}
+		pdf.Ln(-1)
+	}
+
+	// Totals under the amount columns
+	pdf.Ln(3)
+	labelWidth := contentWidth - columns[len(columns)-1].width
+	totals := []struct {
+		label string
+		value float64
+	}{
+		{"Subtotal", doc.Subtotal},
+		{fmt.Sprintf("VAT (%.2f%%)", doc.VATPercent), doc.VAT},
+		{"Total", doc.Total},
+	}
+	for i, total := range totals {
+		style := ""
+		if i == len(totals)-1 {
+			style = "B"
+		}
+		pdf.SetFont("Helvetica", style, fontSize)
+		pdf.CellFormat(labelWidth, 6, tr(total.label), "", 0, "R", false, 0, "")
+		pdf.CellFormat(columns[len(columns)-1].width, 6, tr(amount(total.value)), "T", 1, "R", false, 0, "")
+	}
+
+	return pdf.Output(w)
+}
+
+// amount formats money the way it is printed on the document
+func amount(value float64) string {
+	return fmt.Sprintf("Tk %.2f", value)
+}
+
+// partyBlock draws an optionally titled name/address block and returns the y position below it
+func partyBlock(pdf *fpdf.Fpdf, tr func(string) string, x, y, width float64, title string, p Party) float64 {
+	pdf.SetXY(x, y)
+	if title != "" {
+		pdf.SetFont("Helvetica", "B", fontSize*0.8)
+		pdf.CellFormat(width, 4, title, "", 2, "L", false, 0, "")
+	}
+	pdf.SetFont("Helvetica", "B", fontSize*1.2)
+	pdf.CellFormat(width, 6, tr(p.Name), "", 2, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", fontSize)
+	if p.Address != "" {
+		pdf.MultiCell(width, 4.5, tr(p.Address), "", "L", false)
+		pdf.SetX(x)
+	}
+	if p.VATRegistration != "" {
+		pdf.CellFormat(width, 5, tr("VAT reg. "+p.VATRegistration), "", 2, "L", false, 0, "")
+	}
+	return pdf.GetY() + 2
+}
@@ -0,0 +1,193 @@
+package label
+
+import (
+	"bytes"
+	"fmt"
+	"image/png"
+	"io"
+
+	"github.com/boombuler/barcode"
+	"github.com/boombuler/barcode/code128"
+	"github.com/boombuler/barcode/qr"
+	"github.com/go-pdf/fpdf"
+)
+
+// Template describes the page size and layout of a shipping label
+type Template struct {
+	Name     string
+	Width    float64 // page width in mm
+	Height   float64 // page height in mm
+	Margin   float64 // page margin in mm
+	FontSize float64 // base font size in pt
+}
+
+// Supported label templates
+var (
+	TemplateA6      = Template{Name: "a6", Width: 105, Height: 148, Margin: 5, FontSize: 9}
+	TemplateThermal = Template{Name: "thermal", Width: 101.6, Height: 152.4, Margin: 4, FontSize: 10}
+)
+
+// GetTemplate returns the template with the given name, defaulting to A6 when name is empty
+func GetTemplate(name string) (Template, error) {
+	switch name {
+	case "", TemplateA6.Name:
+		return TemplateA6, nil
+	case TemplateThermal.Name, "4x6":
+		return TemplateThermal, nil
+	default:
+		return Template{}, fmt.Errorf("unknown label template %q", name)
+	}
+}
+
+// Party is a sender or recipient block printed on the label
+type Party struct {
+	Name    string
+	Phone   string
+	Address string
+}
+
+// Label holds everything printed on a single shipping label
+type Label struct {
+	ConsignmentID   string
+	MerchantOrderID string
+	Sender          Party
+	Recipient       Party
+	CODAmount       float64
+	RoutingCode     string
+	TrackingURL     string
+	ItemWeight      float64
+	Instruction     string
+}
+
+// Render writes a PDF with one page per label to w
+func Render(w io.Writer, tpl Template, labels []Label) error {
+	if len(labels) == 0 {
+		return fmt.Errorf("no labels to render")
+	}
+
+	pdf := fpdf.NewCustom(&fpdf.InitType{
+		UnitStr: "mm",
+		Size:    fpdf.SizeType{Wd: tpl.Width, Ht: tpl.Height},
+	})
+	pdf.SetMargins(tpl.Margin, tpl.Margin, tpl.Margin)
+	pdf.SetAutoPageBreak(false, tpl.Margin)
+	tr := pdf.UnicodeTranslatorFromDescriptor("")
+
+	for _, l := range labels {
+		if err := renderPage(pdf, tr, tpl, l); err != nil {
+			return fmt.Errorf("failed to render label for %s: %w", l.ConsignmentID, err)
+		}
+	}
+
+	return pdf.Output(w)
+}
+
+// renderPage draws a single label on a new page
+func renderPage(pdf *fpdf.Fpdf, tr func(string) string, tpl Template, l Label) error {
+	pdf.AddPage()
+	contentWidth := tpl.Width - 2*tpl.Margin
+	x := tpl.Margin
+	y := tpl.Margin
+
+	// Header with routing code, large enough to sort by at a glance
+	pdf.SetFont("Helvetica", "B", tpl.FontSize*2.4)
+	pdf.SetXY(x, y)
+	pdf.CellFormat(contentWidth, 12, tr(l.RoutingCode), "1", 1, "C", false, 0, "")
+	y += 14
+
+	// Code128 barcode of the consignment ID
+	barcodeName, err := registerCode(pdf, "code128-"+l.ConsignmentID, func() (barcode.Barcode, error) {
+		return code128.Encode(l.ConsignmentID)
+	}, 3, 120)
+	if err != nil {
+		return err
+	}
+	pdf.ImageOptions(barcodeName, x, y, contentWidth, 18, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+	y += 19
+	pdf.SetFont("Courier", "B", tpl.FontSize*1.4)
+	pdf.SetXY(x, y)
+	pdf.CellFormat(contentWidth, 6, tr(l.ConsignmentID), "", 1, "C", false, 0, "")
+	y += 8
+
+	// Recipient block
+	y = partyBlock(pdf, tr, tpl, x, y, contentWidth, "TO", l.Recipient)
+
+	// Sender block
+	y = partyBlock(pdf, tr, tpl, x, y, contentWidth, "FROM", l.Sender)
+
+	// COD amount and parcel details beside the tracking QR code
+	qrSize := 32.0
+	qrName, err := registerCode(pdf, "qr-"+l.ConsignmentID, func() (barcode.Barcode, error) {
+		return qr.Encode(l.TrackingURL, qr.M, qr.Auto)
+	}, 8, 0)
+	if err != nil {
+		return err
+	}
+	pdf.ImageOptions(qrName, x+contentWidth-qrSize, y, qrSize, qrSize, false, fpdf.ImageOptions{ImageType: "PNG"}, 0, "")
+
+	detailWidth := contentWidth - qrSize - 2
+	pdf.SetXY(x, y)
+	pdf.SetFont("Helvetica", "", tpl.FontSize)
+	pdf.CellFormat(detailWidth, 5, "COD AMOUNT", "", 1, "L", false, 0, "")
+	pdf.SetX(x)
+	pdf.SetFont("Helvetica", "B", tpl.FontSize*2)
+	pdf.CellFormat(detailWidth, 10, tr(fmt.Sprintf("Tk %.2f", l.CODAmount)), "", 1, "L", false, 0, "")
+	pdf.SetX(x)
+	pdf.SetFont("Helvetica", "", tpl.FontSize)
+	pdf.CellFormat(detailWidth, 5, tr(fmt.Sprintf("Weight: %.2f kg", l.ItemWeight)), "", 1, "L", false, 0, "")
+	if l.MerchantOrderID != "" {
+		pdf.SetX(x)
+		pdf.CellFormat(detailWidth, 5, tr("Order: "+l.MerchantOrderID), "", 1, "L", false, 0, "")
+	}
+	if l.Instruction != "" {
+		pdf.SetX(x)
+		pdf.MultiCell(detailWidth, 4, tr("Note: "+l.Instruction), "", "L", false)
+	}
+
+	return pdf.Error()
+}
+
+// partyBlock draws a titled name/phone/address block and returns the y position below it
+func partyBlock(pdf *fpdf.Fpdf, tr func(string) string, tpl Template, x, y, width float64, title string, p Party) float64 {
+	pdf.SetXY(x, y)
+	pdf.SetFont("Helvetica", "B", tpl.FontSize*0.8)
+	pdf.CellFormat(width, 4, title, "T", 1, "L", false, 0, "")
+	pdf.SetX(x)
+	pdf.SetFont("Helvetica", "B", tpl.FontSize*1.2)
+	pdf.CellFormat(width, 6, tr(p.Name), "", 1, "L", false, 0, "")
+	pdf.SetFont("Helvetica", "", tpl.FontSize)
+	if p.Phone != "" {
+		pdf.SetX(x)
+		pdf.CellFormat(width, 5, tr(p.Phone), "", 1, "L", false, 0, "")
+	}
+	if p.Address != "" {
+		pdf.SetX(x)
+		pdf.MultiCell(width, 4.5, tr(p.Address), "", "L", false)
+	}
+	return pdf.GetY() + 2
+}
+
+// registerCode encodes a barcode, scales every module to moduleSize pixels and registers it as a PNG image.
+// Scaling by a whole number of pixels keeps bar widths even so scanners can read the printed code.
+// A zero height keeps the barcode's own aspect ratio.
+func registerCode(pdf *fpdf.Fpdf, name string, encode func() (barcode.Barcode, error), moduleSize, height int) (string, error) {
+	code, err := encode()
+	if err != nil {
+		return "", fmt.Errorf("failed to encode %s: %w", name, err)
+	}
+	width := code.Bounds().Dx() * moduleSize
+	if height == 0 {
+		height = code.Bounds().Dy() * moduleSize
+	}
+	scaled, err := barcode.Scale(code, width, height)
+	if err != nil {
+		return "", fmt.Errorf("failed to scale %s: %w", name, err)
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, scaled); err != nil {
+		return "", fmt.Errorf("failed to encode %s as png: %w", name, err)
+	}
+	pdf.RegisterImageOptionsReader(name, fpdf.ImageOptions{ImageType: "PNG"}, &buf)
+	return name, pdf.Error()
+}
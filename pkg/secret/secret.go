@@ -0,0 +1,58 @@
+package secret
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+)
+
+// ErrNoKey is returned when no encryption key is configured
+var ErrNoKey = errors.New("no encryption key configured")
+
+// Seal encrypts plaintext with AES-256-GCM under key and returns the nonce and ciphertext base64 encoded
+func Seal(key []byte, plaintext string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return base64.StdEncoding.EncodeToString(gcm.Seal(nonce, nonce, []byte(plaintext), nil)), nil
+}
+
+// Open decrypts a value produced by Seal with the same key
+func Open(key []byte, sealed string) (string, error) {
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode sealed value: %w", err)
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("sealed value is too short")
+	}
+	plaintext, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to decrypt sealed value: %w", err)
+	}
+	return string(plaintext), nil
+}
+
+// newGCM returns an AES-GCM cipher for a 32 byte key
+func newGCM(key []byte) (cipher.AEAD, error) {
+	if len(key) == 0 {
+		return nil, ErrNoKey
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,31 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"go-application-task/configs"
+)
+
+// ErrNotFound is returned when no blob is stored under a key
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore stores uploaded files under slash separated keys
+type BlobStore interface {
+	// Put stores the contents of r under key, replacing any existing blob
+	Put(ctx context.Context, key string, r io.Reader) error
+	// Open returns the contents stored under key, or ErrNotFound
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+}
+
+// NewBlobStore returns the blob storage driver selected in config
+func NewBlobStore(config configs.StorageConfig) (BlobStore, error) {
+	switch config.Driver {
+	case "local":
+		return NewLocalStore(config.LocalPath)
+	default:
+		return nil, fmt.Errorf("unknown storage driver %q", config.Driver)
+	}
+}
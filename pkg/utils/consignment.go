@@ -1,33 +1,122 @@
 package utils
 
 import (
+	"crypto/rand"
 	"fmt"
-	"math/rand"
+	"math/big"
 	"strings"
 	"time"
 )
 
-// GenerateConsignmentID generates a unique consignment ID based on the provided format
+// consignmentCharset is the alphabet used for the random suffix and the check character
+const consignmentCharset = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+
+// ConsignmentIDLength is the length of a consignment ID: CID + YYMMDD + city code + 4 char suffix + check char
+const ConsignmentIDLength = 16
+
+// legacyConsignmentIDLength is the length of consignment IDs issued without a check character
+const legacyConsignmentIDLength = ConsignmentIDLength - 1
+
+// cityCodes maps recipient city IDs to the two letter code embedded in consignment IDs
+var cityCodes = map[int]string{
+	1: "DA", // Dhaka
+}
+
+// CityCode returns the two letter consignment code for a recipient city.
+// Cities without a registered code fall back to their zero-padded numeric ID.
+func CityCode(cityID int) string {
+	if code, ok := cityCodes[cityID]; ok {
+		return code
+	}
+	return fmt.Sprintf("%02d", cityID%100)
+}
+
+// RoutingCode returns the sorting code printed on parcel labels, e.g. DA-01-004 for city 1, zone 1, area 4
+func RoutingCode(cityID, zoneID, areaID int) string {
+	return fmt.Sprintf("%s-%02d-%03d", CityCode(cityID), zoneID, areaID)
+}
+
+// GenerateConsignmentID generates a consignment ID for the given city code.
+// The suffix is drawn from crypto/rand and the last character is a Luhn mod 36 check character,
+// so a mistyped or misread ID can be rejected before it reaches the database.
 func GenerateConsignmentID(cityCode string) (string, error) {
+	if len(cityCode) != 2 {
+		return "", fmt.Errorf("city code must be 2 characters, got %q", cityCode)
+	}
 	currentDate := time.Now().Format("060102") // "YYMMDD" format
 
 	// Generate a random alphanumeric identifier of 4 characters
-	identifier := generateRandomString(4)
+	identifier, err := generateRandomString(4)
+	if err != nil {
+		return "", err
+	}
+
+	body := fmt.Sprintf("CID%s%s%s", currentDate, strings.ToUpper(cityCode), identifier)
+	check, err := consignmentCheckChar(body)
+	if err != nil {
+		return "", err
+	}
+	return body + string(check), nil
+}
 
-	consignmentID := fmt.Sprintf("CID%s%s%s", currentDate, cityCode, identifier)
-	return consignmentID, nil
+// ValidateConsignmentID reports whether id is well formed and its check character matches.
+// IDs issued before check characters were added are one character shorter and are accepted without one.
+func ValidateConsignmentID(id string) bool {
+	if !strings.HasPrefix(id, "CID") {
+		return false
+	}
+	switch len(id) {
+	case legacyConsignmentIDLength:
+		for i := 3; i < len(id); i++ {
+			if strings.IndexByte(consignmentCharset, id[i]) < 0 {
+				return false
+			}
+		}
+		return true
+	case ConsignmentIDLength:
+		check, err := consignmentCheckChar(id[:len(id)-1])
+		if err != nil {
+			return false
+		}
+		return check == id[len(id)-1]
+	}
+	return false
+}
+
+// consignmentCheckChar computes the Luhn mod 36 check character for s
+func consignmentCheckChar(s string) (byte, error) {
+	n := len(consignmentCharset)
+	factor := 2
+	sum := 0
+	for i := len(s) - 1; i >= 0; i-- {
+		codePoint := strings.IndexByte(consignmentCharset, s[i])
+		if codePoint < 0 {
+			return 0, fmt.Errorf("invalid character %q in consignment ID", s[i])
+		}
+		addend := factor * codePoint
+		addend = addend/n + addend%n
+		sum += addend
+		if factor == 2 {
+			factor = 1
+		} else {
+			factor = 2
+		}
+	}
+	return consignmentCharset[(n-sum%n)%n], nil
 }
 
-// generateRandomString generates a random alphanumeric string of the given length
-func generateRandomString(length int) string {
-	const charset = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+// generateRandomString generates a cryptographically random alphanumeric string of the given length
+func generateRandomString(length int) (string, error) {
 	var sb strings.Builder
-	source := rand.NewSource(time.Now().UnixNano())
-	r := rand.New(source)
+	max := big.NewInt(int64(len(consignmentCharset)))
 
 	for i := 0; i < length; i++ {
-		sb.WriteByte(charset[r.Intn(len(charset))])
+		idx, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("failed to read random bytes: %w", err)
+		}
+		sb.WriteByte(consignmentCharset[idx.Int64()])
 	}
 
-	return sb.String()
+	return sb.String(), nil
 }
@@ -0,0 +1,70 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestGenerateConsignmentIDValidates(t *testing.T) {
+	for i := 0; i < 100; i++ {
+		id, err := GenerateConsignmentID("DA")
+		if err != nil {
+			t.Fatalf("GenerateConsignmentID: %v", err)
+		}
+		if len(id) != ConsignmentIDLength {
+			t.Fatalf("len(%q) = %d, want %d", id, len(id), ConsignmentIDLength)
+		}
+		if !ValidateConsignmentID(id) {
+			t.Fatalf("ValidateConsignmentID(%q) = false, want true", id)
+		}
+	}
+}
+
+func TestGenerateConsignmentIDRejectsBadCityCode(t *testing.T) {
+	if _, err := GenerateConsignmentID("DAK"); err == nil {
+		t.Fatal("GenerateConsignmentID(\"DAK\") returned no error")
+	}
+}
+
+func TestValidateConsignmentIDRejectsTypos(t *testing.T) {
+	id, err := GenerateConsignmentID("DA")
+	if err != nil {
+		t.Fatalf("GenerateConsignmentID: %v", err)
+	}
+	// Every single character substitution after the prefix must be caught by the check character
+	for i := 3; i < len(id); i++ {
+		for j := 0; j < len(consignmentCharset); j++ {
+			c := consignmentCharset[j]
+			if c == id[i] {
+				continue
+			}
+			typo := id[:i] + string(c) + id[i+1:]
+			if ValidateConsignmentID(typo) {
+				t.Errorf("ValidateConsignmentID(%q) = true for a typo of %q", typo, id)
+			}
+		}
+	}
+}
+
+func TestValidateConsignmentID(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want bool
+	}{
+		{"legacy", "CID240115DA7K2Q", true},
+		{"legacy lowercase", "CID240115DA7k2q", false},
+		{"legacy bad prefix", "XID240115DA7K2Q", false},
+		{"empty", "", false},
+		{"too short", "CID240115DA7K", false},
+		{"too long", "CID240115DA7K2Q" + strings.Repeat("A", 2), false},
+		{"bad check character", "CID240115DA7K2Q" + "!", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ValidateConsignmentID(tt.id); got != tt.want {
+				t.Errorf("ValidateConsignmentID(%q) = %v, want %v", tt.id, got, tt.want)
+			}
+		})
+	}
+}
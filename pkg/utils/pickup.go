@@ -0,0 +1,12 @@
+package utils
+
+import "time"
+
+// GeneratePickupID generates a pickup ID: PU + YYMMDD + 6 random characters
+func GeneratePickupID() (string, error) {
+	identifier, err := generateRandomString(6)
+	if err != nil {
+		return "", err
+	}
+	return "PU" + time.Now().Format("060102") + identifier, nil
+}